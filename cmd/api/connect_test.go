@@ -0,0 +1,76 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/config"
+)
+
+// The database comes up a second or so after the API starts: connectDB
+// keeps retrying and returns a working pool once it does.
+func TestConnectDBWaitsForLateDatabase(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_SOURCE")
+	if dsn == "" {
+		t.Skip("TEST_DB_SOURCE not set")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := u.Host
+	proxyAddr := closedPort(t)
+
+	// Start forwarding to the real database only after a delay; until then
+	// connections to proxyAddr are refused.
+	listening := make(chan net.Listener, 1)
+	defer func() {
+		if l := <-listening; l != nil {
+			l.Close()
+		}
+	}()
+	go func() {
+		time.Sleep(1200 * time.Millisecond)
+		l, err := net.Listen("tcp", proxyAddr)
+		listening <- l
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				db, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer db.Close()
+				go io.Copy(db, c)
+				io.Copy(c, db)
+			}()
+		}
+	}()
+
+	u.Host = proxyAddr
+	cfg := &config.Config{DBSource: u.String(), DBConnectAttempts: 10, DBConnectTimeout: 30 * time.Second}
+	pool, err := connectDB(cfg, nil)
+	if err != nil {
+		t.Fatalf("connectDB: %v", err)
+	}
+	defer pool.Close()
+	var one int
+	if err := pool.QueryRow(context.Background(), "SELECT 1").Scan(&one); err != nil || one != 1 {
+		t.Fatalf("query through the late pool: %d, %v", one, err)
+	}
+}
@@ -2,19 +2,39 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/punchamoorthee/ledgerops/internal/api"
+	"github.com/punchamoorthee/ledgerops/internal/async"
+	"github.com/punchamoorthee/ledgerops/internal/audit"
+	"github.com/punchamoorthee/ledgerops/internal/cache"
 	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/idgen"
+	"github.com/punchamoorthee/ledgerops/internal/retention"
 	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// Set at build time with -ldflags "-X main.version=..." (see Makefile).
+var (
+	version   = "unknown"
+	commit    = "unknown"
+	buildTime = "unknown"
 )
 
 func main() {
@@ -24,61 +44,288 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	registry := newRegistry(cfg)
+
 	// 2. Connect Database
-	dbPool, err := pgxpool.New(context.Background(), cfg.DBSource)
+	var tracer pgx.QueryTracer
+	if cfg.SlowQueryThreshold > 0 {
+		tracer = store.NewSlowQueryTracer(registry, cfg.MetricsPrefix, cfg.SlowQueryThreshold)
+	}
+	dbPool, err := connectDB(cfg, tracer)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v", err)
 	}
 	defer dbPool.Close()
-
-	if err := dbPool.Ping(context.Background()); err != nil {
-		log.Fatalf("Database ping failed: %v", err)
-	}
 	log.Println("Connected to Database")
 
 	// 3. Initialize Layers
-	ledgerStore := store.NewLedgerStore(dbPool)
-	handler := api.NewHandler(ledgerStore)
+	// Pre-transfer rules. Custom validators are wired in here. These static
+	// ones also re-check sweeps once the store has resolved the amount.
+	var validators []validation.TransferValidator
+	if cfg.MaxTransferAmount > 0 {
+		validators = append(validators, validation.MaxAmount{Limit: cfg.MaxTransferAmount})
+	}
+	if len(cfg.BlockedAccountIDs) > 0 {
+		validators = append(validators, validation.NewAccountBlocklist(cfg.BlockedAccountIDs))
+	}
+
+	storeOpts := []store.Option{store.WithSweepValidators(validators...)}
+	if cfg.NodeID >= 0 {
+		gen, err := idgen.NewSnowflake(cfg.NodeID)
+		if err != nil {
+			log.Fatalf("Invalid NODE_ID: %v", err)
+		}
+		storeOpts = append(storeOpts, store.WithIDGenerator(gen))
+		log.Printf("Using Snowflake transfer IDs (node %d)", cfg.NodeID)
+	}
+	if cfg.AccountCacheSize > 0 {
+		storeOpts = append(storeOpts, store.WithAccountCache(cache.NewLRU[int64, domain.Account](cfg.AccountCacheSize, cfg.AccountCacheTTL)))
+	}
+	if cfg.TransferIsolation == "serializable" {
+		storeOpts = append(storeOpts, store.WithIsolation(pgx.Serializable))
+	}
+	if cfg.IdempotencyMaxBodyBytes > 0 {
+		storeOpts = append(storeOpts, store.WithIdempotencyBodyLimit(cfg.IdempotencyMaxBodyBytes))
+	}
+	ledgerStore := store.NewLedgerStore(dbPool, storeOpts...)
+	if cfg.AccountIDFloor > 0 {
+		if err := ledgerStore.RaiseAccountIDFloor(context.Background(), cfg.AccountIDFloor); err != nil {
+			log.Fatalf("Failed to apply ACCOUNT_ID_FLOOR: %v", err)
+		}
+	}
+
+	// The pair blocklist is loaded from the store; it doesn't depend on the
+	// amount, so sweeps don't need it re-checked.
+	blocklist := validation.NewPairBlocklist(ledgerStore.ListBlockedPairs, cfg.BlocklistBidirectional)
+	if _, err := blocklist.Reload(context.Background()); err != nil {
+		log.Fatalf("Failed to load blocklist: %v", err)
+	}
+	validators = append(validators, blocklist)
+	hasher, ok := api.NewBodyFingerprinter(cfg.IdempotencyFingerprint)
+	if !ok {
+		log.Fatalf("Unknown IDEMPOTENCY_FINGERPRINT %q (want raw, canonical-json or parsed)", cfg.IdempotencyFingerprint)
+	}
+	handler := api.NewHandler(ledgerStore, cfg, registry, hasher, validators...)
+
+	// Background jobs stop, finishing in-flight work, when the server shuts down
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var bgJobs sync.WaitGroup
+	background := func(run func(context.Context)) {
+		bgJobs.Add(1)
+		go func() {
+			defer bgJobs.Done()
+			run(bgCtx)
+		}()
+	}
+
+	background(func(ctx context.Context) { blocklist.Run(ctx, cfg.BlocklistRefresh) })
+
+	if cfg.AsyncWorkers > 0 {
+		processor := async.NewProcessor(ledgerStore, registry, cfg.MetricsPrefix, cfg.AsyncWorkers, cfg.AsyncBatchSize, cfg.AsyncPollInterval)
+		background(processor.Run)
+	}
+
+	// Partitions must outlive the longest TTL a client can ask for.
+	keep := cfg.IdempotencyRetention
+	if keep > 0 && keep < cfg.IdempotencyMaxTTL {
+		keep = cfg.IdempotencyMaxTTL
+	}
+	partitions := retention.NewIdempotencyPartitions(ledgerStore, cfg.IdempotencyPartitionsAhead, keep)
+	background(func(ctx context.Context) { partitions.Run(ctx, cfg.IdempotencyPartitionInterval) })
+
+	reaper := retention.NewKeyReaper(ledgerStore)
+	background(func(ctx context.Context) { reaper.Run(ctx, cfg.IdempotencyReapInterval) })
+
+	driftMonitor := audit.NewDriftMonitor(ledgerStore, registry, cfg.MetricsPrefix, cfg.DriftCheckChunk)
+	if cfg.DriftCheckInterval > 0 {
+		background(func(ctx context.Context) { driftMonitor.Run(ctx, cfg.DriftCheckInterval) })
+	}
 
 	// 4. Setup Router
-	r := mux.NewRouter()
-	r.Use(loggingMiddleware)
+	r, admin := newRouters(cfg, handler, registry, blocklist, driftMonitor)
+
+	// 5. Start Servers
+	servers := []*http.Server{{Addr: cfg.ListenAddr(cfg.Port), Handler: handler.Recover(r)}}
+	if cfg.AdminPort != "" {
+		servers = append(servers, &http.Server{Addr: cfg.ListenAddr(cfg.AdminPort), Handler: handler.Recover(admin)})
+	}
+
+	for _, srv := range servers {
+		go func() {
+			log.Printf("Server starting on %s", srv.Addr)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Listen: %s\n", err)
+			}
+		}()
+	}
+
+	// 6. Graceful Shutdown
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	log.Println("Shutting down server...")
+	var stopping sync.WaitGroup
+	for _, srv := range servers {
+		stopping.Add(1)
+		go func() {
+			defer stopping.Done()
+			srv.Shutdown(ctx)
+		}()
+	}
+	stopping.Wait()
+
+	stopBackground()
+	bgJobs.Wait()
+}
+
+// newRegistry returns the registry everything we export goes to, so
+// /metrics shows only what this server registered. Go runtime and process
+// metrics are opt-in.
+func newRegistry(cfg *config.Config) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	if cfg.ProcessMetrics {
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	return registry
+}
+
+// newRouters builds the public router and the operator one. They are the
+// same router unless cfg.AdminPort splits the operator surface out.
+func newRouters(cfg *config.Config, handler *api.Handler, registry *prometheus.Registry, blocklist *validation.PairBlocklist, driftMonitor *audit.DriftMonitor) (r, admin *mux.Router) {
+	r = mux.NewRouter()
+	r.Use(loggingMiddleware, handler.TrackInflight)
+	if cfg.Compression {
+		r.Use(api.Compress(cfg.CompressionMinBytes))
+	}
+
+	// Operator surface: on the public router unless ADMIN_PORT splits it out
+	admin = r
+	if cfg.AdminPort != "" {
+		admin = mux.NewRouter()
+		admin.Use(loggingMiddleware, handler.TrackInflight)
+		admin.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+		admin.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		admin.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		admin.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		admin.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		admin.HandleFunc("/debug/info", handler.DebugInfo(api.BuildInfo{
+			Version:   version,
+			Commit:    commit,
+			BuildTime: buildTime,
+		})).Methods("GET")
+	}
 
 	// Observability
-	r.Handle("/metrics", promhttp.Handler())
+	admin.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry}))
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
-	// API V1
+	// API V1. Reads also answer HEAD for cheap existence checks: the handlers
+	// run as for GET and net/http discards the body, keeping status and headers.
 	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.Use(handler.Timeout(cfg.RequestTimeout))
+	if cfg.RequireJSONContentType {
+		v1.Use(handler.RequireJSON)
+	}
+	v1.Use(handler.APIVersion, handler.RouteLabels)
+	locking := func(h http.HandlerFunc) http.Handler {
+		return timeoutMiddleware(cfg.TransferTimeout)(h)
+	}
 	v1.HandleFunc("/accounts", handler.CreateAccount).Methods("POST")
-	v1.HandleFunc("/accounts/{id}", handler.GetAccount).Methods("GET")
-	v1.HandleFunc("/transfers", handler.CreateTransfer).Methods("POST")
+	v1.HandleFunc("/accounts", handler.ListAccounts).Methods("GET", "HEAD")
+	v1.HandleFunc("/accounts/{id}", handler.GetAccount).Methods("GET", "HEAD")
+	v1.HandleFunc("/accounts/{id}", handler.UpdateAccount).Methods("PATCH")
+	v1.HandleFunc("/accounts/{id}/statement", handler.GetStatement).Methods("GET", "HEAD")
+	v1.HandleFunc("/accounts/{id}/transfers", handler.GetAccountTransfers).Methods("GET", "HEAD")
+	v1.HandleFunc("/accounts/{id}/entries", handler.GetAccountEntries).Methods("GET", "HEAD")
+	v1.HandleFunc("/accounts/{id}/summary", handler.GetAccountSummary).Methods("GET", "HEAD")
+	v1.Handle("/transfers", locking(handler.CreateTransfer)).Methods("POST")
+	v1.HandleFunc("/transfers", handler.SearchTransfers).Methods("GET", "HEAD")
+	v1.Handle("/transfers/chain", locking(handler.CreateChain)).Methods("POST")
+	v1.HandleFunc("/transfers/batch-get", handler.BatchGetTransfers).Methods("POST")
+	v1.HandleFunc("/transfers/by-key/{key}", handler.GetTransferByKey).Methods("GET", "HEAD")
+	v1.HandleFunc("/transfers/{id}", handler.GetTransfer).Methods("GET", "HEAD")
+	v1.HandleFunc("/transfers/{id}/verify", handler.VerifyReceipt).Methods("GET", "HEAD")
+	v1.Handle("/escrow", locking(handler.CreateEscrow)).Methods("POST")
+	v1.Handle("/escrow/{id}/release", locking(handler.ReleaseEscrow)).Methods("POST")
+	v1.Handle("/escrow/{id}/refund", locking(handler.RefundEscrow)).Methods("POST")
 
-	// 5. Start Server
-	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
+	// Admin
+	adminV1 := admin.PathPrefix("/api/v1/admin").Subrouter()
+	adminV1.Use(handler.RouteLabels)
+	adminV1.HandleFunc("/blocklist/reload", handler.ReloadBlocklist(blocklist)).Methods("POST")
+	adminV1.HandleFunc("/accounts/{id}/verify", handler.VerifyAccount(driftMonitor)).Methods("POST")
+	adminV1.HandleFunc("/transfers/{id}/trace", handler.TraceTransfer).Methods("GET")
+	adminV1.HandleFunc("/reconcile/diff", handler.ReconcileDiff).Methods("GET")
+	adminV1.HandleFunc("/recompute-all", handler.RecomputeAll).Methods("POST")
+	adminV1.HandleFunc("/batch-adjust", handler.BatchAdjust).Methods("POST")
+	adminV1.HandleFunc("/opening-balances", handler.ImportOpeningBalances).Methods("POST")
+
+	// Unmatched paths and methods get the JSON error envelope too
+	for _, router := range []*mux.Router{r, admin} {
+		router.NotFoundHandler = handler.NotFound(router)
+		router.MethodNotAllowedHandler = handler.MethodNotAllowed(router)
 	}
+	return r, admin
+}
 
-	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Listen: %s\n", err)
+// connectDB retries connect+ping with exponential backoff so the API can start
+// before Postgres is ready (e.g. pod ordering in Kubernetes) instead of
+// crash-looping. To exercise it by hand: `docker compose stop db`, start the
+// API, watch it log attempts, then `docker compose start db` within
+// DB_CONNECT_TIMEOUT and it should come up.
+func connectDB(cfg *config.Config, tracer pgx.QueryTracer) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DBSource)
+	if err != nil {
+		return nil, err
+	}
+	poolCfg.ConnConfig.Tracer = tracer
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DBConnectTimeout)
+	defer cancel()
+
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err == nil {
+			if err = pool.Ping(ctx); err == nil {
+				return pool, nil
+			}
+			pool.Close()
 		}
-	}()
+		if attempt >= cfg.DBConnectAttempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, cfg.DBConnectAttempts, err, backoff)
 
-	// 6. Graceful Shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	log.Println("Shutting down server...")
-	srv.Shutdown(ctx)
+// timeoutMiddleware puts a deadline on the request context. Handlers pass it
+// down to the store, so a stuck transaction is canceled and rolled back
+// rather than holding account locks. Nested deadlines keep the shorter one.
+func timeoutMiddleware(d time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/config"
+)
+
+// closedPort returns a local address nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestConnectDBGivesUp(t *testing.T) {
+	cfg := &config.Config{
+		DBSource:          "postgres://u:p@" + closedPort(t) + "/db?sslmode=disable&connect_timeout=1",
+		DBConnectAttempts: 3,
+		DBConnectTimeout:  time.Minute,
+	}
+	start := time.Now()
+	_, err := connectDB(cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Fatalf("err = %v, want giving up after 3 attempts", err)
+	}
+	// Two backoffs, 500ms then 1s, between the three attempts.
+	if elapsed := time.Since(start); elapsed < 1500*time.Millisecond {
+		t.Errorf("gave up after %s, before backing off", elapsed)
+	}
+}
+
+func TestConnectDBTimesOut(t *testing.T) {
+	cfg := &config.Config{
+		DBSource:          "postgres://u:p@" + closedPort(t) + "/db?sslmode=disable&connect_timeout=1",
+		DBConnectAttempts: 100,
+		DBConnectTimeout:  700 * time.Millisecond,
+	}
+	_, err := connectDB(cfg, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/api"
+	"github.com/punchamoorthee/ledgerops/internal/audit"
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// testRouters builds the routers main would for env, with no database
+// behind the handler; only routing is exercised.
+func testRouters(t *testing.T, env map[string]string) (r, admin *mux.Router) {
+	t.Helper()
+	t.Setenv("DB_SOURCE", "postgres://unused")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewRegistry()
+	handler := api.NewHandler(nil, cfg, registry, api.RawSHA256{})
+	blocklist := validation.NewPairBlocklist(func(context.Context) ([]domain.BlockedPair, error) { return nil, nil }, false)
+	return newRouters(cfg, handler, registry, blocklist, audit.NewDriftMonitor(nil, registry, cfg.MetricsPrefix, 10))
+}
+
+// routed reports whether router has a route for the request, without
+// running its handler (most would need the database).
+func routed(router *mux.Router, method, target string) bool {
+	var match mux.RouteMatch
+	return router.Match(httptest.NewRequest(method, target, nil), &match) && match.MatchErr == nil
+}
+
+func status(router http.Handler, method, target string) int {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec.Code
+}
+
+var operatorRoutes = []struct{ method, target string }{
+	{"GET", "/metrics"},
+	{"GET", "/debug/info"},
+	{"GET", "/debug/pprof/"},
+	{"POST", "/api/v1/admin/blocklist/reload"},
+	{"POST", "/api/v1/admin/accounts/1/verify"},
+	{"GET", "/api/v1/admin/transfers/1/trace"},
+	{"GET", "/api/v1/admin/reconcile/diff"},
+	{"POST", "/api/v1/admin/recompute-all"},
+	{"POST", "/api/v1/admin/batch-adjust"},
+	{"POST", "/api/v1/admin/opening-balances"},
+}
+
+func TestAdminPortSplitsOperatorRoutes(t *testing.T) {
+	r, admin := testRouters(t, map[string]string{"ADMIN_PORT": "9091"})
+	if r == admin {
+		t.Fatal("ADMIN_PORT set but both listeners share one router")
+	}
+	for _, rt := range operatorRoutes {
+		if routed(r, rt.method, rt.target) {
+			t.Errorf("public port routes %s %s", rt.method, rt.target)
+		}
+		if !routed(admin, rt.method, rt.target) {
+			t.Errorf("admin port does not route %s %s", rt.method, rt.target)
+		}
+	}
+	if got := status(r, "GET", "/health"); got != http.StatusOK {
+		t.Errorf("public /health = %d", got)
+	}
+	if routed(admin, "GET", "/api/v1/accounts/1") {
+		t.Error("admin port routes the public API")
+	}
+}
+
+// Without ADMIN_PORT there is one listener serving everything, as before
+// the split existed, admin endpoints included; the debug endpoints stay off it.
+func TestSinglePortServesEverything(t *testing.T) {
+	r, admin := testRouters(t, nil)
+	if r != admin {
+		t.Fatal("ADMIN_PORT unset but a separate admin router was built")
+	}
+	if got := status(r, "GET", "/metrics"); got != http.StatusOK {
+		t.Errorf("/metrics = %d, want 200", got)
+	}
+	for _, rt := range operatorRoutes {
+		if strings.HasPrefix(rt.target, "/debug/") {
+			if routed(r, rt.method, rt.target) {
+				t.Errorf("%s is exposed on the only, public, port", rt.target)
+			}
+			continue
+		}
+		if !routed(r, rt.method, rt.target) {
+			t.Errorf("%s %s is not routed", rt.method, rt.target)
+		}
+	}
+}
+
+func TestUnmatchedRoutesAnswerJSON(t *testing.T) {
+	r, admin := testRouters(t, map[string]string{"ADMIN_PORT": "9091"})
+	cases := []struct {
+		name           string
+		router         *mux.Router
+		method, target string
+		status         int
+		code, allow    string
+	}{
+		{"unknown path", r, "GET", "/api/v1/nope", http.StatusNotFound, "NOT_FOUND", ""},
+		{"unknown top-level path", r, "GET", "/favicon.ico", http.StatusNotFound, "NOT_FOUND", ""},
+		{"wrong method", r, "DELETE", "/api/v1/accounts/1", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "GET, HEAD, PATCH"},
+		{"wrong method, earlier route", r, "PUT", "/api/v1/transfers", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "GET, HEAD, POST"},
+		{"wrong method, admin", admin, "GET", "/api/v1/admin/blocklist/reload", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "POST"},
+		{"wrong method, last admin route", admin, "GET", "/api/v1/admin/accounts/1/verify", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "POST"},
+		{"wrong method, top level", admin, "POST", "/debug/info", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "GET"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("%d %q is not JSON: %v", rec.Code, rec.Body, err)
+			}
+			if rec.Code != tc.status || body["code"] != tc.code {
+				t.Fatalf("got %d %v, want %d %s", rec.Code, body["code"], tc.status, tc.code)
+			}
+			if got := rec.Header().Get("Allow"); got != tc.allow {
+				t.Errorf("Allow = %q, want %q", got, tc.allow)
+			}
+		})
+	}
+}
+
+// /metrics serves this server's own registry: the handler's families are
+// there, runtime collectors only when PROCESS_METRICS asks for them, and
+// building the handler twice in one process does not panic on duplicate
+// registration.
+func TestMetricsScrape(t *testing.T) {
+	scrape := func(env map[string]string) string {
+		t.Helper()
+		t.Setenv("DB_SOURCE", "postgres://unused")
+		for k, v := range env {
+			t.Setenv(k, v)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		registry := newRegistry(cfg)
+		handler := api.NewHandler(nil, cfg, registry, api.RawSHA256{})
+		blocklist := validation.NewPairBlocklist(func(context.Context) ([]domain.BlockedPair, error) { return nil, nil }, false)
+		r, _ := newRouters(cfg, handler, registry, blocklist, audit.NewDriftMonitor(nil, registry, cfg.MetricsPrefix, 10))
+
+		status(r, "GET", "/api/v1/nowhere") // one request, so the labeled families have a sample
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("scrape: got %d %s", rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+
+	body := scrape(nil)
+	for _, family := range []string{"ledger_http_requests_total", "ledger_transfers_total", "ledger_panics_total", "ledger_invariant_violation_total"} {
+		if !strings.Contains(body, "# TYPE "+family+" ") {
+			t.Errorf("scrape lacks %s", family)
+		}
+	}
+	// promhttp reports its own scrape errors into the registry it serves.
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "# TYPE ") && !strings.HasPrefix(line, "# TYPE ledger_") && !strings.HasPrefix(line, "# TYPE promhttp_") {
+			t.Errorf("unexpected family outside the namespace: %s", line)
+		}
+	}
+
+	body = scrape(map[string]string{"PROCESS_METRICS": "true", "METRICS_PREFIX": "acme"})
+	for _, family := range []string{"go_goroutines", "acme_transfers_total"} {
+		if !strings.Contains(body, "# TYPE "+family+" ") {
+			t.Errorf("scrape with PROCESS_METRICS lacks %s", family)
+		}
+	}
+}
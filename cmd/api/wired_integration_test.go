@@ -0,0 +1,112 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/api"
+	"github.com/punchamoorthee/ledgerops/internal/audit"
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// TestWiredTransferPath drives the router main serves, on a real store,
+// through account creation, a transfer and the reads that report it.
+func TestWiredTransferPath(t *testing.T) {
+	s := store.NewTestStore(t)
+	t.Setenv("DB_SOURCE", "postgres://unused")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewRegistry()
+	handler := api.NewHandler(s, cfg, registry, api.RawSHA256{})
+	blocklist := validation.NewPairBlocklist(s.ListBlockedPairs, false)
+	r, _ := newRouters(cfg, handler, registry, blocklist, audit.NewDriftMonitor(s, registry, cfg.MetricsPrefix, 10))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	do := func(method, path, body string, header map[string]string, want int, out any) http.Header {
+		t.Helper()
+		req, err := http.NewRequestWithContext(context.Background(), method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != want {
+			t.Fatalf("%s %s: got %d %s, want %d", method, path, resp.StatusCode, raw, want)
+		}
+		if out != nil {
+			if err := json.Unmarshal(raw, out); err != nil {
+				t.Fatalf("%s %s: decode %s: %v", method, path, raw, err)
+			}
+		}
+		return resp.Header
+	}
+
+	var from, to struct{ ID int64 }
+	do("POST", "/api/v1/accounts", `{"initial_balance":1000}`, nil, http.StatusCreated, &from)
+	do("POST", "/api/v1/accounts", `{"initial_balance":0}`, nil, http.StatusCreated, &to)
+
+	var created domain.TransferResponse
+	body := fmt.Sprintf(`{"from_account_id":%d,"to_account_id":%d,"amount":250}`, from.ID, to.ID)
+	hdr := do("POST", "/api/v1/transfers", body, map[string]string{"Idempotency-Key": "wired"}, http.StatusCreated, &created)
+	if created.Transfer.Amount != 250 || created.Transfer.Status != "completed" {
+		t.Fatalf("created %+v", created.Transfer)
+	}
+
+	var fetched domain.TransferResponse
+	do("GET", "/api/v1"+hdr.Get("Location"), "", nil, http.StatusOK, &fetched)
+	if fetched.Transfer.ID != created.Transfer.ID {
+		t.Errorf("GET Location returned transfer %d, want %d", fetched.Transfer.ID, created.Transfer.ID)
+	}
+
+	for id, want := range map[int64]int64{from.ID: 750, to.ID: 250} {
+		var acc domain.Account
+		do("GET", fmt.Sprintf("/api/v1/accounts/%d", id), "", nil, http.StatusOK, &acc)
+		if acc.Balance != want {
+			t.Errorf("account %d balance %d, want %d", id, acc.Balance, want)
+		}
+	}
+
+	var entries domain.AccountEntries
+	do("GET", fmt.Sprintf("/api/v1/accounts/%d/entries", to.ID), "", nil, http.StatusOK, &entries)
+	if len(entries.Entries) != 1 || entries.Entries[0].Delta != 250 || entries.Entries[0].TransferID != created.Transfer.ID {
+		t.Errorf("entries %+v, want one +250 for transfer %d", entries.Entries, created.Transfer.ID)
+	}
+
+	var page domain.TransferPage
+	do("GET", fmt.Sprintf("/api/v1/accounts/%d/transfers", from.ID), "", nil, http.StatusOK, &page)
+	if len(page.Transfers) != 1 || page.Transfers[0].ID != created.Transfer.ID {
+		t.Errorf("account transfers %+v, want only %d", page.Transfers, created.Transfer.ID)
+	}
+
+	// A retry through the router replays rather than moving money again.
+	var replay domain.TransferResponse
+	hdr = do("POST", "/api/v1/transfers", body, map[string]string{"Idempotency-Key": "wired"}, http.StatusCreated, &replay)
+	if hdr.Get("Idempotency-Replayed") != "true" || replay.Transfer.ID != created.Transfer.ID {
+		t.Errorf("retry: replayed=%s transfer %d, want a replay of %d", hdr.Get("Idempotency-Replayed"), replay.Transfer.ID, created.Transfer.ID)
+	}
+	store.AssertBalance(t, s, from.ID, 750)
+	store.AssertInvariants(t, s)
+}
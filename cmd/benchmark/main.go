@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,9 +10,12 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // Config holds the benchmark settings
@@ -20,6 +24,9 @@ var (
 	concurrency int
 	duration    time.Duration
 	workload    string
+	trackPairs  bool
+	firstID     int64
+	verify      bool
 )
 
 // Metrics
@@ -31,30 +38,86 @@ var (
 	failOther     uint64
 )
 
+// pairOutcomes counts the results for one (from, to) pair. Each worker keeps
+// its own map so tracking adds no contention; they are merged at the end.
+type pairOutcomes struct {
+	Created   uint64 `json:"created"`
+	Replayed  uint64 `json:"replayed"`
+	Conflicts uint64 `json:"conflicts"`
+	Errors    uint64 `json:"errors"`
+}
+
+type pairKey struct{ from, to int64 }
+
+// topPairs is how many of the most contended pairs go into the results.
+const topPairs = 10
+
 func init() {
 	flag.StringVar(&targetURL, "url", "http://localhost:8080", "API Base URL")
 	flag.IntVar(&concurrency, "workers", 10, "Number of concurrent workers")
 	flag.DurationVar(&duration, "duration", 30*time.Second, "Test duration")
 	flag.StringVar(&workload, "workload", "uniform", "Workload type: uniform | hotspot")
+	flag.Int64Var(&firstID, "first-account", 1, "Lowest seeded account ID (raise it when ACCOUNT_ID_FLOOR is set)")
+	flag.BoolVar(&trackPairs, "track-pairs", false, "Record outcomes per account pair and report the most contended")
+	flag.BoolVar(&verify, "verify", false, "Check the ledger invariants in DB_SOURCE before and after the run; exit 1 if any broke")
 }
 
 func main() {
 	flag.Parse()
 	log.Printf("Starting Benchmark: %s | Workers: %d | Duration: %s", workload, concurrency, duration)
 
+	var conn *pgx.Conn
+	var before ledgerTotals
+	if verify {
+		ctx := context.Background()
+		var err error
+		if conn, err = connectLedger(ctx); err != nil {
+			log.Fatalf("Unable to connect to database: %v", err)
+		}
+		defer conn.Close(ctx)
+		if before, err = readTotals(ctx, conn); err != nil {
+			log.Fatalf("Reading ledger totals: %v", err)
+		}
+		if problems := checkTotals(before, before); len(problems) > 0 {
+			log.Fatalf("Ledger is inconsistent before the run: %v", problems)
+		}
+	}
+
 	start := time.Now()
 	var wg sync.WaitGroup
 	wg.Add(concurrency)
 
+	perWorker := make([]map[pairKey]*pairOutcomes, concurrency)
 	for i := 0; i < concurrency; i++ {
-		go worker(&wg, start)
+		if trackPairs {
+			perWorker[i] = make(map[pairKey]*pairOutcomes)
+		}
+		go worker(&wg, start, perWorker[i])
 	}
 
 	wg.Wait()
-	printResults(time.Since(start))
+	printResults(time.Since(start), mergePairs(perWorker))
+
+	if verify {
+		after, err := readTotals(context.Background(), conn)
+		if err != nil {
+			log.Fatalf("Reading ledger totals: %v", err)
+		}
+		problems := checkTotals(before, after)
+		for _, p := range problems {
+			log.Printf("INVARIANT BROKEN: %s", p)
+		}
+		if len(problems) > 0 {
+			conn.Close(context.Background())
+			os.Exit(1)
+		}
+		log.Printf("Invariants hold: total balance %d unchanged, entries sum to 0, no drifted accounts", after.balance)
+	}
 }
 
-func worker(wg *sync.WaitGroup, start time.Time) {
+// worker issues transfers until the duration elapses. pairs is nil unless
+// -track-pairs is set.
+func worker(wg *sync.WaitGroup, start time.Time, pairs map[pairKey]*pairOutcomes) {
 	defer wg.Done()
 	client := &http.Client{Timeout: 5 * time.Second}
 
@@ -78,9 +141,21 @@ func worker(wg *sync.WaitGroup, start time.Time) {
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Idempotency-Key", key)
 
+		var pair *pairOutcomes
+		if pairs != nil {
+			k := pairKey{from, to}
+			if pair = pairs[k]; pair == nil {
+				pair = &pairOutcomes{}
+				pairs[k] = pair
+			}
+		}
+
 		resp, err := client.Do(req)
 		if err != nil {
 			atomic.AddUint64(&failOther, 1)
+			if pair != nil {
+				pair.Errors++
+			}
 			continue
 		}
 
@@ -88,41 +163,97 @@ func worker(wg *sync.WaitGroup, start time.Time) {
 		switch resp.StatusCode {
 		case 201:
 			atomic.AddUint64(&success201, 1)
+			if pair != nil {
+				pair.Created++
+			}
 		case 200:
 			atomic.AddUint64(&success200, 1)
+			if pair != nil {
+				pair.Replayed++
+			}
 		case 409:
 			atomic.AddUint64(&fail409, 1)
+			if pair != nil {
+				pair.Conflicts++
+			}
 		default:
 			atomic.AddUint64(&failOther, 1)
+			if pair != nil {
+				pair.Errors++
+			}
 		}
 		resp.Body.Close()
 	}
 }
 
 func generateAccounts() (int64, int64) {
-	// Assumes 1000 accounts seeded (IDs 1-1000)
+	// Assumes 1000 accounts seeded (IDs firstID to firstID+999)
 	totalAccounts := 1000
 
 	if workload == "hotspot" {
-		// Hotspot: 90% of traffic goes to Account 1 & 2
+		// Hotspot: 90% of traffic goes to the first two accounts
 		if rand.Float32() < 0.90 {
 			if rand.Float32() < 0.5 {
-				return 1, 2
+				return firstID, firstID + 1
 			}
-			return 2, 1
+			return firstID + 1, firstID
 		}
 	}
 
 	// Uniform Random
-	a := rand.Intn(totalAccounts) + 1
-	b := rand.Intn(totalAccounts) + 1
+	a := rand.Intn(totalAccounts)
+	b := rand.Intn(totalAccounts)
 	for a == b {
-		b = rand.Intn(totalAccounts) + 1
+		b = rand.Intn(totalAccounts)
 	}
-	return int64(a), int64(b)
+	return firstID + int64(a), firstID + int64(b)
+}
+
+// pairResult is one entry of the results' top_contended_pairs.
+type pairResult struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	pairOutcomes
 }
 
-func printResults(d time.Duration) {
+// mergePairs combines the workers' maps and returns the pairs with the most
+// conflicts, or nil when tracking is off.
+func mergePairs(perWorker []map[pairKey]*pairOutcomes) []pairResult {
+	if !trackPairs {
+		return nil
+	}
+	merged := make(map[pairKey]*pairOutcomes)
+	for _, m := range perWorker {
+		for k, o := range m {
+			t := merged[k]
+			if t == nil {
+				t = &pairOutcomes{}
+				merged[k] = t
+			}
+			t.Created += o.Created
+			t.Replayed += o.Replayed
+			t.Conflicts += o.Conflicts
+			t.Errors += o.Errors
+		}
+	}
+
+	out := make([]pairResult, 0, len(merged))
+	for k, o := range merged {
+		out = append(out, pairResult{From: k.from, To: k.to, pairOutcomes: *o})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Conflicts != out[j].Conflicts {
+			return out[i].Conflicts > out[j].Conflicts
+		}
+		return out[i].Created+out[i].Replayed > out[j].Created+out[j].Replayed
+	})
+	if len(out) > topPairs {
+		out = out[:topPairs]
+	}
+	return out
+}
+
+func printResults(d time.Duration, pairs []pairResult) {
 	total := atomic.LoadUint64(&totalRequests)
 	s201 := atomic.LoadUint64(&success201)
 	s200 := atomic.LoadUint64(&success200)
@@ -143,6 +274,9 @@ func printResults(d time.Duration) {
 		"abort_rate_pct":  abortRate,
 		"errors":          fErr,
 	}
+	if trackPairs {
+		results["top_contended_pairs"] = pairs
+	}
 
 	// Print JSON for the python plotter to consume
 	enc := json.NewEncoder(os.Stdout)
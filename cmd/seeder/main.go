@@ -1,20 +1,44 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
-const (
-	TotalAccounts  = 1000
-	InitialBalance = 10000 // $100.00
+var (
+	totalAccounts  int
+	initialBalance int64
+	openingAPI     string
 )
 
+// openingChunk matches the API's cap on balances per import.
+const openingChunk = 1000
+
+func init() {
+	flag.IntVar(&totalAccounts, "accounts", 1000, "Number of accounts to seed")
+	flag.Int64Var(&initialBalance, "balance", 10000, "Initial balance per account in minor units ($100.00); 0 is allowed")
+	flag.StringVar(&openingAPI, "opening-api", "", "Admin base URL (e.g. http://localhost:8080); when set, balances are imported as ledger entries against the opening-balance equity account instead of written directly")
+}
+
 func main() {
+	flag.Parse()
+	if totalAccounts <= 0 {
+		log.Fatalf("-accounts must be positive, got %d", totalAccounts)
+	}
+	if initialBalance < 0 {
+		log.Fatalf("-balance must not be negative, got %d", initialBalance)
+	}
+
 	dbURL := os.Getenv("DB_SOURCE")
 	if dbURL == "" {
 		// Fallback for local development if env not set
@@ -37,22 +61,41 @@ func main() {
 	// 2. Check existing
 	var count int
 	conn.QueryRow(ctx, "SELECT COUNT(*) FROM accounts").Scan(&count)
-	if count >= TotalAccounts {
+	if count >= totalAccounts {
 		log.Printf("Database already has %d accounts. Skipping.", count)
 		return
 	}
 
+	// Same floor the API applies at startup, so seeded IDs don't start at 1.
+	if v := os.Getenv("ACCOUNT_ID_FLOOR"); v != "" {
+		floor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || floor < 0 {
+			log.Fatalf("ACCOUNT_ID_FLOOR must be a non-negative integer, got %q", v)
+		}
+		_, err = conn.Exec(ctx, "SELECT setval('accounts_id_seq', $1, false) FROM accounts_id_seq WHERE last_value < $1", floor)
+		if err != nil {
+			log.Fatalf("Failed to apply ACCOUNT_ID_FLOOR: %v", err)
+		}
+	}
+
+	var before int64
+	conn.QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM accounts").Scan(&before)
+
 	// 3. Bulk Insert using CopyFrom
-	log.Printf("Generating %d accounts...", TotalAccounts)
+	log.Printf("Generating %d accounts...", totalAccounts)
+	direct := initialBalance
+	if openingAPI != "" {
+		direct = 0
+	}
 	rows := [][]interface{}{}
-	for i := 0; i < TotalAccounts; i++ {
-		rows = append(rows, []interface{}{int64(InitialBalance), time.Now()})
+	for i := 0; i < totalAccounts; i++ {
+		rows = append(rows, []interface{}{direct, direct, time.Now()})
 	}
 
 	copyCount, err := conn.CopyFrom(
 		ctx,
 		pgx.Identifier{"accounts"},
-		[]string{"balance", "created_at"},
+		[]string{"balance", "initial_balance", "created_at"},
 		pgx.CopyFromRows(rows),
 	)
 
@@ -60,5 +103,56 @@ func main() {
 		log.Fatalf("Bulk insert failed: %v", err)
 	}
 
-	log.Printf("Successfully seeded %d accounts.", copyCount)
+	var first, last int64
+	conn.QueryRow(ctx, "SELECT MIN(id), MAX(id) FROM accounts").Scan(&first, &last)
+	log.Printf("Successfully seeded %d accounts (IDs %d-%d).", copyCount, first, last)
+
+	if openingAPI != "" && initialBalance > 0 {
+		if err := importOpeningBalances(ctx, conn, before); err != nil {
+			log.Fatalf("Opening balance import failed: %v", err)
+		}
+	}
+}
+
+// importOpeningBalances opens every account above after through the admin
+// opening-balances endpoint. Import IDs derive from each chunk's first
+// account, so re-running after a failure replays finished chunks.
+func importOpeningBalances(ctx context.Context, conn *pgx.Conn, after int64) error {
+	rows, err := conn.Query(ctx, "SELECT id FROM accounts WHERE id > $1 ORDER BY id", after)
+	if err != nil {
+		return err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+
+	type balance struct {
+		AccountID int64 `json:"account_id"`
+		Amount    int64 `json:"amount"`
+	}
+	for start := 0; start < len(ids); start += openingChunk {
+		chunk := ids[start:min(start+openingChunk, len(ids))]
+		req := struct {
+			ImportID string    `json:"import_id"`
+			Balances []balance `json:"balances"`
+		}{ImportID: fmt.Sprintf("seed-%d", chunk[0])}
+		for _, id := range chunk {
+			req.Balances = append(req.Balances, balance{id, initialBalance})
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		resp, err := http.Post(openingAPI+"/api/v1/admin/opening-balances", "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("import %s: status %d", req.ImportID, resp.StatusCode)
+		}
+	}
+	log.Printf("Imported opening balances for %d accounts.", len(ids))
+	return nil
 }
@@ -0,0 +1,10 @@
+// Package db embeds the schema migrations so tooling can apply them without
+// a checkout of this directory. Production still runs them with migrate.
+package db
+
+import "embed"
+
+// Migrations holds the migrate-style *.up.sql files, under migrations/.
+//
+//go:embed migrations/*.up.sql
+var Migrations embed.FS
@@ -0,0 +1,5 @@
+-- Back the handler validations with DB constraints so the guarantee
+-- holds regardless of entry point. (amount > 0 is already enforced
+-- by the column CHECK in 000001.)
+ALTER TABLE "transfers"
+  ADD CONSTRAINT "transfers_no_self_transfer" CHECK (from_account_id <> to_account_id);
@@ -0,0 +1,8 @@
+-- Account pairs that compliance has barred from transacting.
+-- Whether a row also blocks the reverse direction is decided by config.
+CREATE TABLE "blocked_pairs" (
+  "from_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "to_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("from_id", "to_id")
+);
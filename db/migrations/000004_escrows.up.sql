@@ -0,0 +1,15 @@
+-- Escrows (Funds parked in a system escrow account until release or refund)
+-- Every movement is an ordinary double-entry transfer; this table only
+-- tracks where the held funds are destined.
+CREATE TABLE "escrows" (
+  "id" bigserial PRIMARY KEY,
+  "buyer_account_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "seller_account_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "escrow_account_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "amount" bigint NOT NULL CHECK (amount > 0),
+  "state" text NOT NULL CHECK (state IN ('held', 'released', 'refunded')),
+  "hold_transfer_id" bigint NOT NULL REFERENCES "transfers" ("id"),
+  "settle_transfer_id" bigint NULL REFERENCES "transfers" ("id"),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
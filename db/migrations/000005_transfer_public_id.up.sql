@@ -0,0 +1,8 @@
+-- Unguessable public identifiers for transfers. The bigint id stays the
+-- internal key for joins; clients may reference either.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE "transfers"
+  ADD COLUMN "public_id" uuid NOT NULL DEFAULT gen_random_uuid();
+
+CREATE UNIQUE INDEX "transfers_public_id_idx" ON "transfers" ("public_id");
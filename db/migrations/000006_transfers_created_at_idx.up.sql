@@ -0,0 +1,2 @@
+-- Supports keyset pagination over transfers ordered by (created_at, id).
+CREATE INDEX "transfers_created_at_id_idx" ON "transfers" ("created_at", "id");
@@ -0,0 +1,3 @@
+-- Supports listing an account's transfers (either side) in keyset order.
+CREATE INDEX "transfers_from_account_idx" ON "transfers" ("from_account_id", "created_at", "id");
+CREATE INDEX "transfers_to_account_idx" ON "transfers" ("to_account_id", "created_at", "id");
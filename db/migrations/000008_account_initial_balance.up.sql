@@ -0,0 +1,10 @@
+-- Opening balances are written directly to accounts.balance rather than
+-- journaled, so remember them: balance must always equal
+-- initial_balance + SUM(ledger_entries.delta). Existing rows are assumed
+-- consistent and backfilled from their entries.
+ALTER TABLE "accounts" ADD COLUMN "initial_balance" bigint NOT NULL DEFAULT 0;
+
+UPDATE "accounts" a
+SET "initial_balance" = a.balance - COALESCE((SELECT SUM(delta) FROM ledger_entries e WHERE e.account_id = a.id), 0);
+
+CREATE INDEX "ledger_entries_account_idx" ON "ledger_entries" ("account_id", "id");
@@ -0,0 +1,4 @@
+-- Scope idempotency keys to the operation that reserved them, so a key
+-- minted for a transfer can't be replayed against a different endpoint.
+-- Keys stay globally unique; reuse across operations is rejected.
+ALTER TABLE "idempotency_keys" ADD COLUMN "operation" text NOT NULL DEFAULT 'transfer';
@@ -0,0 +1,12 @@
+-- Async transfers: accepted requests are persisted as 'pending' transfers
+-- (no ledger entries yet) and settled later by the worker pool. The
+-- transfers table doubles as the queue.
+ALTER TABLE "transfers" DROP CONSTRAINT "transfers_status_check";
+ALTER TABLE "transfers"
+  ADD CONSTRAINT "transfers_status_check" CHECK (status IN ('pending', 'completed', 'failed'));
+
+ALTER TABLE "transfers"
+  ADD COLUMN "failure_reason" text NULL,
+  ADD COLUMN "processed_at" timestamptz NULL;
+
+CREATE INDEX "transfers_pending_idx" ON "transfers" ("id") WHERE status = 'pending';
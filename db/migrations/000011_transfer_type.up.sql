@@ -0,0 +1,5 @@
+-- Transfer type for reporting. Existing rows are plain transfers.
+ALTER TABLE "transfers"
+  ADD COLUMN "type" text NOT NULL DEFAULT 'transfer',
+  ADD CONSTRAINT "transfers_type_check"
+    CHECK (type IN ('transfer', 'payment', 'refund', 'fee', 'adjustment'));
@@ -0,0 +1,4 @@
+-- Oversized idempotency responses are not stored. transfer_ids records the
+-- transfers the response described so a replay can rebuild it; it is NULL
+-- whenever response_body holds the full response.
+ALTER TABLE "idempotency_keys" ADD COLUMN "transfer_ids" bigint[] NULL;
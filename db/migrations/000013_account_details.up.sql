@@ -0,0 +1,6 @@
+-- Human-friendly account details. version counts edits to name/metadata
+-- and backs optimistic concurrency on PATCH; balance changes don't bump it.
+ALTER TABLE "accounts"
+  ADD COLUMN "name" text NULL,
+  ADD COLUMN "metadata" jsonb NULL CHECK (metadata IS NULL OR jsonb_typeof(metadata) = 'object'),
+  ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,56 @@
+-- Partition idempotency_keys by day so expired keys are dropped a partition
+-- at a time instead of row-by-row deletes. The API's maintenance job creates
+-- partitions ahead of time and drops those past IDEMPOTENCY_RETENTION.
+--
+-- A partitioned table's primary key must include the partition column, so
+-- uniqueness becomes (key, created_on). Lookups still go by key alone and
+-- find a key in any partition. The key alone is no longer unique, so two
+-- first requests with the same key straddling UTC midnight could both
+-- reserve it (one per day's partition); Reserve takes a per-key advisory
+-- lock before its lookup to close that gap.
+ALTER TABLE "idempotency_keys" RENAME TO "idempotency_keys_legacy";
+
+CREATE TABLE "idempotency_keys" (
+  "key" text NOT NULL,
+  "request_hash" text NOT NULL,
+  "status" text NOT NULL CHECK (status IN ('in_progress', 'completed', 'failed')),
+  "transfer_id" bigint NULL REFERENCES "transfers" ("id"),
+  "response_status" int NULL,
+  "response_body" jsonb NULL,
+  "operation" text NOT NULL DEFAULT 'transfer',
+  "transfer_ids" bigint[] NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_on" date NOT NULL DEFAULT ((now() AT TIME ZONE 'UTC')::date),
+  PRIMARY KEY ("key", "created_on")
+) PARTITION BY RANGE ("created_on");
+
+-- Catches rows if the maintenance job falls behind. It should stay empty:
+-- a partition can't be created for a range the default already holds rows in.
+CREATE TABLE "idempotency_keys_default" PARTITION OF "idempotency_keys" DEFAULT;
+
+-- Seed from the oldest legacy key (or a week back) to a week ahead, so every
+-- copied key lands in a named partition the job drops once it is past
+-- IDEMPOTENCY_RETENTION; the job takes over from there.
+DO $$
+DECLARE
+  today date := (now() AT TIME ZONE 'UTC')::date;
+  oldest date;
+  d date;
+BEGIN
+  SELECT LEAST(today - 7, min((created_at AT TIME ZONE 'UTC')::date)) INTO oldest FROM "idempotency_keys_legacy";
+  FOR d IN SELECT generate_series(oldest, today + 7, interval '1 day')::date LOOP
+    EXECUTE format(
+      'CREATE TABLE %I PARTITION OF idempotency_keys FOR VALUES FROM (%L) TO (%L)',
+      'idempotency_keys_p' || to_char(d, 'YYYYMMDD'), d, d + 1);
+  END LOOP;
+END $$;
+
+-- Every key is kept: retention is the maintenance job's call, made against
+-- the configured IDEMPOTENCY_RETENTION rather than a fixed window here.
+INSERT INTO "idempotency_keys"
+  (key, request_hash, status, transfer_id, response_status, response_body, operation, transfer_ids, created_at, created_on)
+SELECT key, request_hash, status, transfer_id, response_status, response_body, operation, transfer_ids,
+       created_at, (created_at AT TIME ZONE 'UTC')::date
+FROM "idempotency_keys_legacy";
+
+DROP TABLE "idempotency_keys_legacy";
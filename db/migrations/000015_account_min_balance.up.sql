@@ -0,0 +1,4 @@
+-- Per-account reserve: debits may not take balance below min_balance.
+-- Unlike an overdraft this never lets the balance go negative.
+ALTER TABLE "accounts"
+  ADD COLUMN "min_balance" bigint NOT NULL DEFAULT 0 CHECK (min_balance >= 0);
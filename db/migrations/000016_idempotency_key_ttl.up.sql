@@ -0,0 +1,6 @@
+-- Client-requested key lifetime (Idempotency-Key-TTL). NULL keeps the key
+-- until its partition is dropped at the default retention.
+ALTER TABLE "idempotency_keys" ADD COLUMN "expires_at" timestamptz NULL;
+
+CREATE INDEX "idempotency_keys_expires_at_idx" ON "idempotency_keys" ("expires_at")
+  WHERE expires_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Accounting period a transfer belongs to, which may differ from when it
+-- was recorded (created_at). Existing rows take their recording day (UTC).
+ALTER TABLE "transfers" ADD COLUMN "effective_date" date;
+
+UPDATE "transfers" SET "effective_date" = (created_at AT TIME ZONE 'UTC')::date;
+
+ALTER TABLE "transfers"
+  ALTER COLUMN "effective_date" SET DEFAULT ((now() AT TIME ZONE 'UTC')::date),
+  ALTER COLUMN "effective_date" SET NOT NULL;
+
+CREATE INDEX "transfers_effective_date_idx" ON "transfers" ("effective_date");
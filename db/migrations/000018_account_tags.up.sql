@@ -0,0 +1,5 @@
+-- Free-form labels for grouping accounts (e.g. "merchant", "payroll").
+ALTER TABLE "accounts" ADD COLUMN "tags" text[] NOT NULL DEFAULT '{}';
+
+-- Serves tags @> ARRAY[...] containment filters.
+CREATE INDEX "accounts_tags_idx" ON "accounts" USING GIN ("tags");
@@ -0,0 +1,20 @@
+-- One row per batch adjustment run; run_id is the caller's idempotency handle.
+CREATE TABLE "adjustment_runs" (
+  "run_id" text PRIMARY KEY,
+  "request_hash" text NOT NULL,
+  "status" text NOT NULL DEFAULT 'running' CHECK ("status" IN ('running', 'completed')),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz
+);
+
+-- Each account a run has visited, so a re-run never adjusts it twice.
+-- transfer_id is NULL when the account was visited but not adjusted (zero
+-- amount, or a fee it couldn't cover). amount is signed from the account's
+-- side: positive credited it, negative debited it.
+CREATE TABLE "adjustment_run_items" (
+  "run_id" text NOT NULL REFERENCES "adjustment_runs" ("run_id"),
+  "account_id" bigint NOT NULL REFERENCES "accounts" ("id"),
+  "transfer_id" bigint,
+  "amount" bigint NOT NULL,
+  PRIMARY KEY ("run_id", "account_id")
+);
@@ -0,0 +1,23 @@
+-- An opening-balance equity account is the negative side of every imported
+-- opening balance, so it is the one kind of account allowed below zero.
+ALTER TABLE "accounts" ADD COLUMN "allow_negative" boolean NOT NULL DEFAULT false;
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_balance_check";
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK (balance >= 0 OR allow_negative);
+
+-- One row per opening-balance import; import_id is the caller's idempotency handle.
+CREATE TABLE "opening_balance_imports" (
+  "import_id" text PRIMARY KEY,
+  "request_hash" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- The opening transfer for each account. The primary key means an account
+-- is opened once, whichever import does it.
+CREATE TABLE "opening_balances" (
+  "account_id" bigint PRIMARY KEY REFERENCES "accounts" ("id"),
+  "import_id" text NOT NULL REFERENCES "opening_balance_imports" ("import_id"),
+  "transfer_id" bigint NOT NULL REFERENCES "transfers" ("id"),
+  "amount" bigint NOT NULL CHECK ("amount" > 0)
+);
+
+CREATE INDEX ON "opening_balances" ("import_id");
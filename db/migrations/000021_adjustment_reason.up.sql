@@ -0,0 +1,4 @@
+-- The operator's audit reason for a batch adjustment, kept on the run and
+-- on each transfer it makes. NULL on ordinary transfers and older runs.
+ALTER TABLE "adjustment_runs" ADD COLUMN "reason" text;
+ALTER TABLE "transfers" ADD COLUMN "reason" text;
@@ -0,0 +1,5 @@
+-- balance_version counts balance changes and backs If-Match on transfers.
+-- It is separate from version, which keeps counting only name/metadata/tags
+-- edits, so a transfer or recompute never fails a pending PATCH.
+ALTER TABLE "accounts"
+  ADD COLUMN "balance_version" bigint NOT NULL DEFAULT 1;
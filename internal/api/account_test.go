@@ -0,0 +1,110 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCreateAccountRejectsNegativeBalance(t *testing.T) {
+	h := newTestHandler(t, nil)
+	rec := serve(h.CreateAccount, "POST", "/api/v1/accounts", `{"initial_balance":-1}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "INVALID_INITIAL_BALANCE" {
+		t.Fatalf("got %d %s, want 422 INVALID_INITIAL_BALANCE", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateAccountMalformedBody(t *testing.T) {
+	h := newTestHandler(t, nil)
+	cases := []struct {
+		name, body string
+		status     int
+		code       string
+	}{
+		{"empty body", ``, http.StatusUnprocessableEntity, "EMPTY_BODY"},
+		{"malformed JSON", `{"initial_balance":`, http.StatusBadRequest, "MALFORMED_JSON"},
+		{"wrong type", `{"initial_balance":"lots"}`, http.StatusBadRequest, "MALFORMED_JSON"},
+		{"unknown field", `{"balance":10}`, http.StatusBadRequest, "MALFORMED_JSON"},
+		{"trailing data", `{"initial_balance":10} {}`, http.StatusBadRequest, "MALFORMED_JSON"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(h.CreateAccount, "POST", "/api/v1/accounts", tc.body, nil)
+			if rec.Code != tc.status || errorBody(t, rec)["code"] != tc.code {
+				t.Fatalf("got %d %s, want %d %s", rec.Code, rec.Body, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestAccountDetailsValidated(t *testing.T) {
+	h := newTestHandler(t, nil)
+	long := strings.Repeat("é", maxAccountNameLen+1) // counted in characters, not bytes
+
+	rec := serve(h.CreateAccount, "POST", "/api/v1/accounts", `{"name":"`+long+`","metadata":{"owner":"alice","limits":{"daily":5}}}`, nil)
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "INVALID_ACCOUNT_DETAILS" {
+		t.Fatalf("create: got %d %s, want 422 INVALID_ACCOUNT_DETAILS", rec.Code, rec.Body)
+	}
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/v1/accounts/1", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		rec := httptest.NewRecorder()
+		h.UpdateAccount(rec, req)
+		return rec
+	}
+	rec = patch(`{"name":"Alice's checking"}`)
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "VERSION_REQUIRED" {
+		t.Errorf("patch without version: got %d %s", rec.Code, rec.Body)
+	}
+	rec = patch(`{"metadata":{"tags":["a"]},"version":1}`)
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "INVALID_ACCOUNT_DETAILS" {
+		t.Errorf("patch with nested metadata: got %d %s", rec.Code, rec.Body)
+	}
+	rec = patch(`{"balance":1000000,"version":1}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("patch of the balance: got %d %s, want 400", rec.Code, rec.Body)
+	}
+}
+
+func TestAccountTagsValidated(t *testing.T) {
+	h := newTestHandler(t, nil)
+	many := make([]string, maxAccountTags+1)
+	for i := range many {
+		many[i] = fmt.Sprintf(`"t%d"`, i)
+	}
+	cases := []struct {
+		name, tags string
+		codes      []string
+	}{
+		{"empty tag", `["payroll",""]`, []string{"INVALID_TAGS"}},
+		{"blank tag", `["  "]`, []string{"INVALID_TAGS"}},
+		{"too long", `["` + strings.Repeat("x", maxTagLen+1) + `"]`, []string{"INVALID_TAGS"}},
+		{"duplicate", `["payroll","merchant","payroll"]`, []string{"INVALID_TAGS"}},
+		{"too many", `[` + strings.Join(many, ",") + `]`, []string{"INVALID_TAGS"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(h.CreateAccount, "POST", "/api/v1/accounts", `{"tags":`+tc.tags+`}`, nil)
+			if rec.Code != http.StatusUnprocessableEntity || !slices.Equal(fieldCodes(t, rec), tc.codes) {
+				t.Errorf("create: got %d %s, want 422 %v", rec.Code, rec.Body, tc.codes)
+			}
+			req := mux.SetURLVars(httptest.NewRequest("PATCH", "/api/v1/accounts/1", strings.NewReader(`{"tags":`+tc.tags+`,"version":1}`)), map[string]string{"id": "1"})
+			rec = httptest.NewRecorder()
+			h.UpdateAccount(rec, req)
+			if rec.Code != http.StatusUnprocessableEntity || !slices.Equal(fieldCodes(t, rec), tc.codes) {
+				t.Errorf("patch: got %d %s, want 422 %v", rec.Code, rec.Body, tc.codes)
+			}
+		})
+	}
+
+	rec := serve(h.ListAccounts, "GET", "/api/v1/accounts?tag=payroll&tag=", "", nil)
+	if rec.Code != http.StatusUnprocessableEntity || !slices.Equal(fieldCodes(t, rec), []string{"INVALID_TAGS"}) {
+		t.Errorf("list with an empty tag: got %d %s, want 422 INVALID_TAGS", rec.Code, rec.Body)
+	}
+}
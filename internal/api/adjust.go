@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+const maxAdjustBasisPoints = 10000 // 100% of balance
+
+// BatchAdjust applies an interest or fee rule to a tagged set of accounts
+// against the treasury account. The run_id in the body plays the part of an
+// idempotency key, so a retried or resumed run never adjusts an account
+// twice. A reason is required for the audit trail. It is mounted on the
+// admin router only.
+func (h *Handler) BatchAdjust(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/admin/batch-adjust"
+	if h.treasuryAccountID == 0 {
+		h.respondError(w, http.StatusNotFound, "Batch adjustments are not enabled", "POST", endpoint)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid body", "POST", endpoint)
+		return
+	}
+	var req domain.BatchAdjustRequest
+	if err := unmarshalBody(body, &req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", endpoint)
+		return
+	}
+
+	var errs fieldErrors
+	if req.RunID == "" {
+		errs.add("run_id", "RUN_ID_REQUIRED", "run_id is required")
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Reason == "":
+		errs.add("reason", "REASON_REQUIRED", "reason is required")
+	case utf8.RuneCountInString(req.Reason) > h.maxReasonLength:
+		errs.add("reason", "REASON_TOO_LONG", fmt.Sprintf("reason must be at most %d characters", h.maxReasonLength))
+	}
+	switch req.Rule.Kind {
+	case domain.AdjustFlat:
+		if req.Rule.Amount == 0 {
+			errs.add("rule.amount", "INVALID_RULE", "rule.amount must be non-zero")
+		}
+	case domain.AdjustPercent:
+		if req.Rule.BasisPoints == 0 || req.Rule.BasisPoints < -maxAdjustBasisPoints || req.Rule.BasisPoints > maxAdjustBasisPoints {
+			errs.add("rule.basis_points", "INVALID_RULE", "rule.basis_points must be non-zero and within ±10000")
+		}
+	default:
+		errs.add("rule.kind", "INVALID_RULE", "rule.kind must be flat or percent")
+	}
+	if !domain.ValidTransferType(req.Type) {
+		errs.add("type", "INVALID_TRANSFER_TYPE", invalidTypeMessage)
+	}
+	validTags(&errs, req.Tags)
+	if errs.respond(h, w, "POST", endpoint) {
+		return
+	}
+
+	res, err := h.store.BatchAdjust(r.Context(), h.treasuryAccountID, req, h.requestHash(req, body), h.adjustChunk)
+	if err != nil {
+		if errors.Is(err, store.ErrKeyMismatch) {
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, "RUN_ID_REUSED", "run_id reused with a different request", "POST", endpoint)
+			return
+		}
+		h.respondStoreError(w, err, "POST", endpoint)
+		return
+	}
+	log.Printf("Batch adjustment %s: %d accounts adjusted, +%d/-%d, reason %q", res.RunID, res.AccountsAdjusted, res.TotalCredited, res.TotalDebited, res.Reason)
+	h.respondJSON(w, http.StatusOK, res, "POST", endpoint)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// A batch adjustment must say why it ran. These answer before the store is
+// reached.
+func TestBatchAdjustRequiresReason(t *testing.T) {
+	h := newTestHandler(t, testConfig(t, map[string]string{"TREASURY_ACCOUNT_ID": "1", "MAX_REASON_LENGTH": "20"}))
+	cases := []struct {
+		name   string
+		reason string
+		code   string
+	}{
+		{"missing", `""`, "REASON_REQUIRED"},
+		{"blank", `"   "`, "REASON_REQUIRED"},
+		{"too long", `"` + strings.Repeat("x", 21) + `"`, "REASON_TOO_LONG"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"run_id":"r1","reason":` + tc.reason + `,"rule":{"kind":"flat","amount":10}}`
+			rec := serve(h.BatchAdjust, "POST", "/api/v1/admin/batch-adjust", body, nil)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("got %d %s, want 422", rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tc.code) {
+				t.Errorf("body %s does not name %s", rec.Body, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+func blockPairs(t *testing.T, pairs ...domain.BlockedPair) *validation.PairBlocklist {
+	t.Helper()
+	b := validation.NewPairBlocklist(func(context.Context) ([]domain.BlockedPair, error) { return pairs, nil }, false)
+	if _, err := b.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Every screen POST /transfers applies must apply to each hop of a chain,
+// or a one-hop chain is a way around it. All of these answer before the
+// store is reached.
+func TestCreateChainScreensEveryHop(t *testing.T) {
+	key := map[string]string{"Idempotency-Key": "k1"}
+	twoHops := `{"hops":[{"from_account_id":1,"to_account_id":2,"amount":10},{"from_account_id":2,"to_account_id":3,"amount":10}]}`
+
+	t.Run("blocked pair", func(t *testing.T) {
+		h := newTestHandler(t, nil, blockPairs(t, domain.BlockedPair{FromAccountID: 2, ToAccountID: 3}))
+		rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", twoHops, key)
+		if rec.Code != http.StatusForbidden || errorBody(t, rec)["code"] != "BLOCKED_PAIR" {
+			t.Fatalf("got %d %s, want 403 BLOCKED_PAIR", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("amount limit", func(t *testing.T) {
+		h := newTestHandler(t, nil, validation.MaxAmount{Limit: 5})
+		rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", twoHops, key)
+		if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "AMOUNT_LIMIT_EXCEEDED" {
+			t.Fatalf("got %d %s, want 422 AMOUNT_LIMIT_EXCEEDED", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		// Account 1 has already spent its one transfer this window.
+		h := newTestHandler(t, testConfig(t, map[string]string{"ACCOUNT_RATE_LIMIT": "1"}))
+		h.limiter.Allow(1)
+		rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", twoHops, key)
+		if rec.Code != http.StatusTooManyRequests || errorBody(t, rec)["code"] != "ACCOUNT_RATE_LIMITED" {
+			t.Fatalf("got %d %s, want 429 ACCOUNT_RATE_LIMITED", rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Error("missing Retry-After")
+		}
+	})
+
+	t.Run("in-flight cap", func(t *testing.T) {
+		h := newTestHandler(t, testConfig(t, map[string]string{"ACCOUNT_CONCURRENCY": "1"}))
+		release, ok := h.inflight.Acquire(context.Background(), false, 3)
+		if !ok {
+			t.Fatal("could not take the only slot")
+		}
+		defer release()
+		rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", twoHops, key)
+		if rec.Code != http.StatusTooManyRequests || errorBody(t, rec)["code"] != "ACCOUNT_BUSY" {
+			t.Fatalf("got %d %s, want 429 ACCOUNT_BUSY", rec.Code, rec.Body)
+		}
+	})
+}
+
+// A chain at either limit gets past the size checks (here to the date
+// check, so no store is needed); one over is refused before anything else.
+func TestChainSizeLimits(t *testing.T) {
+	key := map[string]string{"Idempotency-Key": "k1"}
+	env := map[string]string{"MAX_CHAIN_HOPS": "3", "MAX_CHAIN_ACCOUNTS": "4"}
+	chain := func(pairs ...[2]int64) string {
+		hops := make([]string, len(pairs))
+		for i, p := range pairs {
+			hops[i] = fmt.Sprintf(`{"from_account_id":%d,"to_account_id":%d,"amount":10,"effective_date":"someday"}`, p[0], p[1])
+		}
+		return `{"hops":[` + strings.Join(hops, ",") + `]}`
+	}
+	cases := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"hops at the limit", chain([2]int64{1, 2}, [2]int64{2, 3}, [2]int64{3, 4}), "INVALID_EFFECTIVE_DATE"},
+		{"one hop over", chain([2]int64{1, 2}, [2]int64{2, 3}, [2]int64{3, 4}, [2]int64{4, 1}), "TOO_MANY_LEGS"},
+		{"accounts at the limit", chain([2]int64{1, 2}, [2]int64{3, 4}), "INVALID_EFFECTIVE_DATE"},
+		{"one account over", chain([2]int64{1, 2}, [2]int64{3, 4}, [2]int64{4, 5}), "TOO_MANY_ACCOUNTS"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, testConfig(t, env))
+			rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", tc.body, key)
+			if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != tc.code {
+				t.Fatalf("got %d %s, want 422 %s", rec.Code, rec.Body, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Compress gzips response bodies of at least minSize bytes for clients that
+// send "Accept-Encoding: gzip". Smaller bodies, responses that already carry
+// a Content-Encoding, and /metrics (Prometheus negotiates its own) are
+// passed through untouched.
+func Compress(minSize int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/metrics" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			next.ServeHTTP(gw, r)
+			// Not deferred: if the handler panics, the held-back status must
+			// not go out as a 200 before Recover writes its 500.
+			gw.finish()
+		})
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			// "gzip;q=0" explicitly refuses it.
+			if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if f, err := strconv.ParseFloat(q, 64); err == nil && f == 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter buffers the start of the body until it knows whether the
+// response reaches minSize, then either compresses or writes it as is.
+// The status code is held back with it, since Content-Encoding must be set
+// before the header goes out.
+type gzipWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	plain   bool
+}
+
+func (g *gzipWriter) Unwrap() http.ResponseWriter { return g.ResponseWriter }
+
+func (g *gzipWriter) WriteHeader(code int) {
+	g.status = code
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	switch {
+	case g.gz != nil:
+		return g.gz.Write(p)
+	case g.plain:
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= g.minSize {
+		if err := g.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start commits to compressing, unless the handler already encoded the body.
+func (g *gzipWriter) start() error {
+	h := g.ResponseWriter.Header()
+	if h.Get("Content-Encoding") != "" {
+		return g.flushPlain()
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	_, err := g.gz.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+func (g *gzipWriter) flushPlain() error {
+	g.plain = true
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) == 0 {
+		return nil
+	}
+	_, err := g.ResponseWriter.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+// finish writes out whatever the handler left: the gzip trailer, or a body
+// too small to be worth compressing.
+func (g *gzipWriter) finish() {
+	switch {
+	case g.gz != nil:
+		g.gz.Close()
+	case !g.plain:
+		g.flushPlain()
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestCompress(t *testing.T) {
+	h := newTestHandler(t, nil)
+	entries := make([]domain.LedgerEntry, 500)
+	for i := range entries {
+		entries[i] = domain.LedgerEntry{ID: int64(i + 1), TransferID: int64(i/2 + 1), AccountID: 1, Delta: -100, CreatedAt: time.Unix(0, 0).UTC()}
+	}
+	list := func(w http.ResponseWriter, r *http.Request) {
+		h.respondJSON(w, http.StatusOK, domain.AccountEntries{AccountID: 1, Entries: entries}, "GET", "/accounts/{id}/entries")
+	}
+	small := func(w http.ResponseWriter, r *http.Request) {
+		h.respondJSON(w, http.StatusCreated, map[string]int{"id": 1}, "POST", "/accounts")
+	}
+	get := func(handler http.HandlerFunc, acceptGzip bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/accounts/1/entries", nil)
+		if acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		rec := httptest.NewRecorder()
+		h.Recover(Compress(1024)(handler)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	plain := get(list, false)
+	if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("without gzip: got %d, Content-Encoding %q", plain.Code, plain.Header().Get("Content-Encoding"))
+	}
+
+	t.Run("large body is compressed", func(t *testing.T) {
+		rec := get(list, true)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("got %d, Content-Encoding %q, want 200 gzip", rec.Code, rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, plain.Body.Bytes()) {
+			t.Error("decompressed body differs from the uncompressed response")
+		}
+		if rec.Body.Len() >= plain.Body.Len() {
+			t.Errorf("compressed %d bytes, uncompressed %d", rec.Body.Len(), plain.Body.Len())
+		}
+	})
+
+	t.Run("small body passes through", func(t *testing.T) {
+		rec := get(small, true)
+		if rec.Code != http.StatusCreated || rec.Header().Get("Content-Encoding") != "" {
+			t.Fatalf("got %d, Content-Encoding %q, want 201 uncompressed", rec.Code, rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Body.String() != "{\"id\":1}\n" {
+			t.Errorf("body = %q", rec.Body)
+		}
+	})
+
+	t.Run("panic still reaches Recover as a 500", func(t *testing.T) {
+		rec := get(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panic("boom")
+		}, true)
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("got %d %s, want 500", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// CONTENTION_STATUS picks how a NOWAIT lock failure is surfaced; a request
+// colliding with an in-progress idempotency key is a 409 either way.
+func TestContentionStatusMapping(t *testing.T) {
+	cases := []struct {
+		setting    string
+		err        error
+		status     int
+		retryAfter bool
+	}{
+		{setting: "409", err: store.ErrLockContention, status: http.StatusConflict},
+		{setting: "503", err: store.ErrLockContention, status: http.StatusServiceUnavailable, retryAfter: true},
+		{setting: "409", err: fmt.Errorf("exec: %w", store.ErrLockContention), status: http.StatusConflict},
+		{setting: "503", err: fmt.Errorf("exec: %w", store.ErrLockContention), status: http.StatusServiceUnavailable, retryAfter: true},
+		{setting: "409", err: store.ErrConflict, status: http.StatusConflict},
+		{setting: "503", err: store.ErrConflict, status: http.StatusConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.setting+"/"+tc.err.Error(), func(t *testing.T) {
+			h := newTestHandler(t, testConfig(t, map[string]string{"CONTENTION_STATUS": tc.setting}))
+			rec := httptest.NewRecorder()
+			h.respondStoreError(rec, tc.err, "POST", "/transfers")
+			if rec.Code != tc.status {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tc.status)
+			}
+			if got := rec.Header().Get("Retry-After") != ""; got != tc.retryAfter {
+				t.Errorf("Retry-After set = %v, want %v", got, tc.retryAfter)
+			}
+			if body := errorBody(t, rec); tc.err != store.ErrConflict && body["code"] != "LOCK_CONTENTION" {
+				t.Errorf("code = %v, want LOCK_CONTENTION", body["code"])
+			}
+		})
+	}
+
+	t.Run("only 409 or 503", func(t *testing.T) {
+		t.Setenv("DB_SOURCE", "postgres://unused")
+		t.Setenv("CONTENTION_STATUS", "500")
+		if _, err := config.Load(); err == nil {
+			t.Fatal("CONTENTION_STATUS=500 was accepted")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireJSON(t *testing.T) {
+	h := newTestHandler(t, nil)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	srv := h.RequireJSON(ok)
+	cases := []struct {
+		name, method, contentType, body string
+		want                            int
+	}{
+		{"json", "POST", "application/json", `{}`, http.StatusNoContent},
+		{"json with utf-8 charset", "POST", "application/json; charset=UTF-8", `{}`, http.StatusNoContent},
+		{"missing", "POST", "", `{}`, http.StatusUnsupportedMediaType},
+		{"form", "POST", "application/x-www-form-urlencoded", `amount=10`, http.StatusUnsupportedMediaType},
+		{"text", "PATCH", "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"other charset", "POST", "application/json; charset=latin1", `{}`, http.StatusUnsupportedMediaType},
+		{"unparseable", "POST", "application/json;;", `{}`, http.StatusUnsupportedMediaType},
+		{"bodiless POST", "POST", "", ``, http.StatusNoContent},
+		{"GET", "GET", "text/plain", `ignored`, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/v1/transfers", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tc.want)
+			}
+			if tc.want == http.StatusUnsupportedMediaType && errorBody(t, rec)["code"] != "UNSUPPORTED_MEDIA_TYPE" {
+				t.Errorf("code = %v", errorBody(t, rec)["code"])
+			}
+		})
+	}
+}
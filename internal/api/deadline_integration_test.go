@@ -0,0 +1,78 @@
+//go:build integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// stallingIdempotency is PostgresIdempotency whose Complete, the last step
+// before commit, hangs until the request deadline while stall is set: a
+// transfer stuck with its account locks held and its funds already moved.
+type stallingIdempotency struct {
+	*store.PostgresIdempotency
+	stall atomic.Bool
+}
+
+func (s *stallingIdempotency) Complete(ctx context.Context, tx pgx.Tx, key string, status int, resp any, transferIDs ...int64) error {
+	if s.stall.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.PostgresIdempotency.Complete(ctx, tx, key, status, resp, transferIDs...)
+}
+
+// Lookup is only a cache in front of Reserve; "not known here" is always a
+// valid answer.
+func (s *stallingIdempotency) Lookup(context.Context, string, string, string) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func TestTransferPastDeadlineRollsBack(t *testing.T) {
+	idem := &stallingIdempotency{PostgresIdempotency: store.NewPostgresIdempotency(nil, 0)}
+	idem.stall.Store(true)
+	s := store.NewTestStore(t, store.WithIdempotencyStore(idem))
+	h := NewHandler(s, testConfig(t, nil), prometheus.NewRegistry(), RawSHA256{})
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+
+	// The same per-request deadline main puts on the locking routes.
+	withDeadline := func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 50*time.Millisecond)
+		defer cancel()
+		h.CreateTransfer(w, r.WithContext(ctx))
+	}
+	key := map[string]string{"Idempotency-Key": "stuck"}
+
+	rec := serve(withDeadline, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+	if rec.Code != http.StatusServiceUnavailable || errorBody(t, rec)["code"] != "TIMEOUT" {
+		t.Fatalf("got %d %s, want 503 TIMEOUT", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After")
+	}
+	store.AssertBalance(t, s, a, 1000)
+	store.AssertBalance(t, s, b, 0)
+	if _, err := s.GetTransfer(context.Background(), "1"); !errors.Is(err, store.ErrTransferNotFound) {
+		t.Fatalf("transfer 1 after rollback: err = %v, want ErrTransferNotFound", err)
+	}
+
+	// The key rolled back with the rest, so the client's retry goes through.
+	idem.stall.Store(false)
+	rec = serve(withDeadline, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("retry: got %d %s, want 201", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 900)
+	store.AssertBalance(t, s, b, 100)
+	store.AssertInvariants(t, s)
+}
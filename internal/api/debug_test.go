@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+)
+
+func TestDebugInfo(t *testing.T) {
+	h := newTestHandler(t, nil)
+	info := h.DebugInfo(BuildInfo{Version: "unknown", Commit: "unknown", BuildTime: "unknown"})
+	rec := serve(info, "GET", "/debug/info", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	body := errorBody(t, rec) // a plain JSON object, like the error envelope
+	for _, field := range []string{"version", "commit", "build_time", "go_version", "gomaxprocs", "uptime"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("missing %q in %s", field, rec.Body)
+		}
+	}
+	if body["version"] != "unknown" || body["go_version"] != runtime.Version() {
+		t.Errorf("version %v, go_version %v", body["version"], body["go_version"])
+	}
+	if n, _ := body["gomaxprocs"].(float64); n < 1 {
+		t.Errorf("gomaxprocs = %v", body["gomaxprocs"])
+	}
+}
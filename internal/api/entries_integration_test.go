@@ -0,0 +1,47 @@
+//go:build integration
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+func getEntries(h *Handler, id string) *httptest.ResponseRecorder {
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/accounts/"+id+"/entries", nil), map[string]string{"id": id})
+	rec := httptest.NewRecorder()
+	h.GetAccountEntries(rec, req)
+	return rec
+}
+
+func TestAccountEntriesErrors(t *testing.T) {
+	h, _ := newStoreHandler(t, nil)
+	rec := getEntries(h, "424242")
+	if rec.Code != http.StatusNotFound || errorBody(t, rec)["error"] != "Account not found" {
+		t.Errorf("missing account: got %d %s, want 404 Account not found", rec.Code, rec.Body)
+	}
+
+	// A pool that is already closed fails every query with a driver error.
+	pool, err := pgxpool.New(context.Background(), os.Getenv("TEST_DB_SOURCE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	broken := NewHandler(store.NewLedgerStore(pool), testConfig(t, nil), prometheus.NewRegistry(), RawSHA256{})
+	rec = getEntries(broken, "1")
+	if rec.Code != http.StatusInternalServerError || errorBody(t, rec)["code"] != "INTERNAL_ERROR" {
+		t.Fatalf("database error: got %d %s, want 500 INTERNAL_ERROR", rec.Code, rec.Body)
+	}
+	if msg := errorBody(t, rec)["error"]; msg != "Internal server error" || strings.Contains(rec.Body.String(), "closed") {
+		t.Errorf("database error body not sanitized: %s", rec.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// Store failures reach clients as the standard envelope with a stable code;
+// driver error text goes to the log and nowhere else.
+func TestStoreErrorsSanitized(t *testing.T) {
+	const raw = `relation "ledger_entries_p20261015" does not exist`
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("entries for 7: %w", store.ErrAccountNotFound), http.StatusNotFound, ""},
+		{"database error", &pgconn.PgError{Code: "42P01", Message: raw}, http.StatusInternalServerError, "INTERNAL_ERROR"},
+		{"wrapped database error", fmt.Errorf("get entries: %w", &pgconn.PgError{Code: "42P01", Message: raw}), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			prev := log.Writer()
+			log.SetOutput(&logged)
+			defer log.SetOutput(prev)
+
+			h := newTestHandler(t, nil)
+			rec := httptest.NewRecorder()
+			h.respondStoreError(rec, tc.err, "GET", "/accounts/{id}/entries")
+			if rec.Code != tc.status {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tc.status)
+			}
+			body := errorBody(t, rec)
+			if tc.code != "" && body["code"] != tc.code {
+				t.Errorf("code = %v, want %s", body["code"], tc.code)
+			}
+			if strings.Contains(rec.Body.String(), "ledger_entries") || strings.Contains(rec.Body.String(), "42P01") {
+				t.Errorf("client body leaks the driver error: %s", rec.Body)
+			}
+			if tc.status == http.StatusInternalServerError && !strings.Contains(logged.String(), raw) {
+				t.Errorf("raw error not logged; log: %q", logged.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+func (h *Handler) CreateEscrow(w http.ResponseWriter, r *http.Request) {
+	if h.escrowAccountID == 0 {
+		h.respondError(w, http.StatusNotFound, "Escrow is not enabled", "POST", "/escrow")
+		return
+	}
+
+	idemKey, body, ok := h.readIdempotent(w, r, "POST", "/escrow")
+	if !ok {
+		return
+	}
+
+	var req domain.EscrowRequest
+	if err := unmarshalBody(body, &req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", "/escrow")
+		return
+	}
+	if req.Amount <= 0 {
+		h.respondError(w, http.StatusUnprocessableEntity, "Amount must be positive", "POST", "/escrow")
+		return
+	}
+	if req.BuyerAccountID == req.SellerAccountID {
+		h.respondError(w, http.StatusUnprocessableEntity, "Buyer and seller must differ", "POST", "/escrow")
+		return
+	}
+	// Release would then be a transfer from the escrow account to itself.
+	if req.BuyerAccountID == h.escrowAccountID || req.SellerAccountID == h.escrowAccountID {
+		h.respondError(w, http.StatusUnprocessableEntity, "Buyer and seller must not be the escrow account", "POST", "/escrow")
+		return
+	}
+
+	// The hold and its release together move money from buyer to seller, so
+	// that pair is what the risk rules see, and the buyer is charged against
+	// its rate limit. Only the buyer takes an in-flight slot: the seller
+	// isn't locked until release, and the escrow account sits in every hold,
+	// so capping it would cap escrow as a whole.
+	screened := domain.TransferRequest{FromAccountID: req.BuyerAccountID, ToAccountID: req.SellerAccountID, Amount: req.Amount, Type: domain.TransferTypePayment}
+	reqHash := h.requestHash(req, body)
+	replay := h.replaying(r.Context(), store.OpEscrowCreate, idemKey, reqHash)
+	if !h.validateTransfer(w, r, screened, "/escrow") || (!replay && !h.allowTransfer(w, req.BuyerAccountID, "/escrow")) {
+		return
+	}
+	release, ok := h.acquireAccounts(r.Context(), w, "/escrow", req.BuyerAccountID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	resp, err := h.store.CreateEscrow(r.Context(), h.escrowAccountID, req, idemKey, reqHash)
+	if err != nil {
+		h.respondStoreError(w, err, "POST", "/escrow")
+		return
+	}
+
+	w.Header().Set("Idempotency-Replayed", strconv.FormatBool(resp.Replayed))
+	h.respondJSON(w, http.StatusCreated, resp, "POST", "/escrow")
+}
+
+func (h *Handler) ReleaseEscrow(w http.ResponseWriter, r *http.Request) {
+	h.settleEscrow(w, r, "/escrow/{id}/release", h.store.ReleaseEscrow)
+}
+
+func (h *Handler) RefundEscrow(w http.ResponseWriter, r *http.Request) {
+	h.settleEscrow(w, r, "/escrow/{id}/refund", h.store.RefundEscrow)
+}
+
+type settleFunc func(ctx context.Context, id int64, idempotencyKey, reqHash string) (*domain.EscrowResponse, error)
+
+func (h *Handler) settleEscrow(w http.ResponseWriter, r *http.Request, endpoint string, settle settleFunc) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid escrow ID", "POST", endpoint)
+		return
+	}
+
+	idemKey, body, ok := h.readIdempotent(w, r, "POST", endpoint)
+	if !ok {
+		return
+	}
+	// The body is usually empty, so the path is what pins a key to one escrow.
+	reqHash := h.hasher.Fingerprint([]byte(r.URL.Path), body)
+
+	resp, err := settle(r.Context(), id, idemKey, reqHash)
+	if err != nil {
+		h.respondStoreError(w, err, "POST", endpoint)
+		return
+	}
+
+	w.Header().Set("Idempotency-Replayed", strconv.FormatBool(resp.Replayed))
+	h.respondJSON(w, http.StatusOK, resp, "POST", endpoint)
+}
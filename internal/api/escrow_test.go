@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// Escrow moves money from buyer to seller, so it must pass the same screens
+// as a transfer between them. All of these answer before the store is
+// reached.
+func TestCreateEscrowScreensBuyerAndSeller(t *testing.T) {
+	key := map[string]string{"Idempotency-Key": "k1"}
+	const escrow = `{"buyer_account_id":1,"seller_account_id":2,"amount":10}`
+	cases := []struct {
+		name       string
+		env        map[string]string
+		validators []validation.TransferValidator
+		body       string
+		status     int
+		code       string
+	}{
+		{name: "seller is the escrow account", body: `{"buyer_account_id":1,"seller_account_id":99,"amount":10}`, status: http.StatusUnprocessableEntity},
+		{name: "buyer is the escrow account", body: `{"buyer_account_id":99,"seller_account_id":2,"amount":10}`, status: http.StatusUnprocessableEntity},
+		{
+			name:       "blocked pair",
+			validators: []validation.TransferValidator{blockPairs(t, domain.BlockedPair{FromAccountID: 1, ToAccountID: 2})},
+			body:       escrow, status: http.StatusForbidden, code: "BLOCKED_PAIR",
+		},
+		{
+			name:       "blocked seller",
+			validators: []validation.TransferValidator{validation.NewAccountBlocklist([]int64{2})},
+			body:       escrow, status: http.StatusUnprocessableEntity, code: "ACCOUNT_BLOCKED",
+		},
+		{
+			name:       "amount limit",
+			validators: []validation.TransferValidator{validation.MaxAmount{Limit: 5}},
+			body:       escrow, status: http.StatusUnprocessableEntity, code: "AMOUNT_LIMIT_EXCEEDED",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, testConfig(t, map[string]string{"ESCROW_ACCOUNT_ID": "99"}), tc.validators...)
+			rec := serve(h.CreateEscrow, "POST", "/api/v1/escrow", tc.body, key)
+			if rec.Code != tc.status {
+				t.Fatalf("got %d %s, want %d", rec.Code, rec.Body, tc.status)
+			}
+			if tc.code != "" && errorBody(t, rec)["code"] != tc.code {
+				t.Errorf("code = %v, want %s", errorBody(t, rec)["code"], tc.code)
+			}
+		})
+	}
+
+	t.Run("rate limit", func(t *testing.T) {
+		h := newTestHandler(t, testConfig(t, map[string]string{"ESCROW_ACCOUNT_ID": "99", "ACCOUNT_RATE_LIMIT": "1"}))
+		h.limiter.Allow(1)
+		rec := serve(h.CreateEscrow, "POST", "/api/v1/escrow", escrow, key)
+		if rec.Code != http.StatusTooManyRequests || errorBody(t, rec)["code"] != "ACCOUNT_RATE_LIMITED" {
+			t.Fatalf("got %d %s, want 429 ACCOUNT_RATE_LIMITED", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("in-flight cap", func(t *testing.T) {
+		h := newTestHandler(t, testConfig(t, map[string]string{"ESCROW_ACCOUNT_ID": "99", "ACCOUNT_CONCURRENCY": "1"}))
+		release, _ := h.inflight.Acquire(context.Background(), false, 1)
+		defer release()
+		rec := serve(h.CreateEscrow, "POST", "/api/v1/escrow", escrow, key)
+		if rec.Code != http.StatusTooManyRequests || errorBody(t, rec)["code"] != "ACCOUNT_BUSY" {
+			t.Fatalf("got %d %s, want 429 ACCOUNT_BUSY", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package api
+
+import "net/http"
+
+// fieldError is one problem with one request field.
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// fieldErrors accumulates every field-level problem in a request so the
+// client can fix them in one round trip instead of one per attempt.
+type fieldErrors []fieldError
+
+func (e *fieldErrors) add(field, code, msg string) {
+	*e = append(*e, fieldError{Field: field, Code: code, Message: msg})
+}
+
+// respond writes a 422 listing every issue and reports whether there were
+// any. The top-level "error" and "code" repeat the first issue, so clients
+// that only read those see the same response as before.
+func (e fieldErrors) respond(h *Handler, w http.ResponseWriter, method, endpoint string) bool {
+	if len(e) == 0 {
+		return false
+	}
+	h.respondJSON(w, http.StatusUnprocessableEntity, map[string]any{
+		"error":  e[0].Message,
+		"code":   e[0].Code,
+		"errors": e,
+	}, method, endpoint)
+	return true
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// BodyFingerprinter hashes the request parts that an idempotent retry must
+// repeat. Two requests with the same key must fingerprint identically to be
+// treated as the same request.
+//
+// Switching implementations changes every fingerprint: retries of requests
+// made before the switch will no longer match their stored hash and are
+// rejected as key reuse until those keys expire.
+type BodyFingerprinter interface {
+	Fingerprint(parts ...[]byte) string
+}
+
+// RequestFingerprinter is a BodyFingerprinter that can also hash the
+// decoded request instead of its bytes. Handlers use FingerprintRequest
+// whenever they have a decoded request to offer.
+type RequestFingerprinter interface {
+	BodyFingerprinter
+	FingerprintRequest(req any, parts ...[]byte) string
+}
+
+// NewBodyFingerprinter returns the implementation named by
+// IDEMPOTENCY_FINGERPRINT: "raw" (the default), "canonical-json" or
+// "parsed".
+func NewBodyFingerprinter(name string) (BodyFingerprinter, bool) {
+	switch name {
+	case "", "raw":
+		return RawSHA256{}, true
+	case "canonical-json":
+		return CanonicalJSON{}, true
+	case "parsed":
+		return ParsedRequest{}, true
+	}
+	return nil, false
+}
+
+// RawSHA256 hashes the bytes exactly as received: any difference, even
+// whitespace or key order, makes a different request.
+type RawSHA256 struct{}
+
+func (RawSHA256) Fingerprint(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, p := range parts {
+		hash.Write(p)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CanonicalJSON re-marshals each JSON part deterministically (sorted keys,
+// no insignificant whitespace, numbers kept verbatim) before hashing, so
+// semantically equal retries match. Parts that aren't JSON, such as a URL
+// path, are hashed as-is.
+type CanonicalJSON struct{}
+
+func (CanonicalJSON) Fingerprint(parts ...[]byte) string {
+	canonical := make([][]byte, len(parts))
+	for i, p := range parts {
+		canonical[i] = canonicalize(p)
+	}
+	return RawSHA256{}.Fingerprint(canonical...)
+}
+
+func canonicalize(p []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return p
+	}
+	out, err := json.Marshal(v) // map keys are marshaled in sorted order
+	if err != nil {
+		return p
+	}
+	return out
+}
+
+// ParsedRequest hashes the request as decoded, so any two bodies that decode
+// to the same request match: key order, whitespace, camelCase vs snake_case
+// keys and quoted vs bare amounts all stop mattering. Fields the request
+// type ignores don't count either. Bodies with no decoded request, such as
+// an escrow release, fall back to CanonicalJSON.
+type ParsedRequest struct{}
+
+func (ParsedRequest) Fingerprint(parts ...[]byte) string {
+	return CanonicalJSON{}.Fingerprint(parts...)
+}
+
+// FingerprintRequest hashes parts (e.g. the URL path) followed by req's
+// fields. %+v prints every field, json:"-" ones included, and map keys
+// sorted; request types must not hold pointers, whose addresses would leak
+// into the hash.
+func (ParsedRequest) FingerprintRequest(req any, parts ...[]byte) string {
+	return RawSHA256{}.Fingerprint(append(parts, []byte(fmt.Sprintf("%T%+v", req, req)))...)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestCanonicalJSONIgnoresKeyOrder(t *testing.T) {
+	path := []byte("/api/v1/transfers")
+	original := []byte(`{"from_account_id":1,"to_account_id":2,"amount":100}`)
+	cases := []struct {
+		name      string
+		retry     string
+		raw, canc bool // whether each fingerprinter matches the original
+	}{
+		{"identical", `{"from_account_id":1,"to_account_id":2,"amount":100}`, true, true},
+		{"reordered keys", `{"amount":100,"to_account_id":2,"from_account_id":1}`, false, true},
+		{"whitespace", "{ \"from_account_id\": 1,\n  \"to_account_id\": 2, \"amount\": 100 }", false, true},
+		{"different amount", `{"amount":101,"to_account_id":2,"from_account_id":1}`, false, false},
+		{"number spelled differently", `{"from_account_id":1,"to_account_id":2,"amount":1e2}`, false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, canonical := RawSHA256{}, CanonicalJSON{}
+			if got := raw.Fingerprint(original, path) == raw.Fingerprint([]byte(tc.retry), path); got != tc.raw {
+				t.Errorf("raw match = %v, want %v", got, tc.raw)
+			}
+			if got := canonical.Fingerprint(original, path) == canonical.Fingerprint([]byte(tc.retry), path); got != tc.canc {
+				t.Errorf("canonical match = %v, want %v", got, tc.canc)
+			}
+		})
+	}
+
+	// Parts that aren't JSON still count.
+	var canonical CanonicalJSON
+	if canonical.Fingerprint(original, path) == canonical.Fingerprint(original, []byte("/api/v1/escrow")) {
+		t.Error("canonical fingerprint ignores the path")
+	}
+}
+
+func TestNewBodyFingerprinter(t *testing.T) {
+	for name, want := range map[string]BodyFingerprinter{"": RawSHA256{}, "raw": RawSHA256{}, "canonical-json": CanonicalJSON{}} {
+		if got, ok := NewBodyFingerprinter(name); !ok || got != want {
+			t.Errorf("%q: got %T, %v", name, got, ok)
+		}
+	}
+	if _, ok := NewBodyFingerprinter("md5"); ok {
+		t.Error("accepted an unknown fingerprinter")
+	}
+}
+
+func TestParsedRequestHash(t *testing.T) {
+	original := `{"from_account_id":1,"to_account_id":2,"amount":100}`
+	cases := []struct {
+		name        string
+		retry       string
+		raw, parsed bool // whether each mode matches the original
+	}{
+		{"identical", original, true, true},
+		{"reordered keys", `{"amount":100,"to_account_id":2,"from_account_id":1}`, false, true},
+		{"camelCase keys", `{"fromAccountId":1,"toAccountId":2,"amount":100}`, false, true},
+		{"quoted amount", `{"from_account_id":1,"to_account_id":2,"amount":"100"}`, false, true},
+		{"different amount", `{"amount":101,"to_account_id":2,"from_account_id":1}`, false, false},
+		{"swapped accounts", `{"from_account_id":2,"to_account_id":1,"amount":100}`, false, false},
+	}
+	hash := func(h *Handler, body string) string {
+		t.Helper()
+		var req domain.TransferRequest
+		if err := unmarshalBody([]byte(body), &req); err != nil {
+			t.Fatalf("%s: %v", body, err)
+		}
+		return h.requestHash(req, []byte(body))
+	}
+	raw := NewHandler(nil, testConfig(t, nil), prometheus.NewRegistry(), RawSHA256{})
+	parsed := NewHandler(nil, testConfig(t, nil), prometheus.NewRegistry(), ParsedRequest{})
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hash(raw, original) == hash(raw, tc.retry); got != tc.raw {
+				t.Errorf("raw match = %v, want %v", got, tc.raw)
+			}
+			if got := hash(parsed, original) == hash(parsed, tc.retry); got != tc.parsed {
+				t.Errorf("parsed match = %v, want %v", got, tc.parsed)
+			}
+		})
+	}
+}
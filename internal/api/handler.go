@@ -2,114 +2,623 @@ package api
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"context"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/punchamoorthee/ledgerops/internal/audit"
+	"github.com/punchamoorthee/ledgerops/internal/config"
 	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/ratelimit"
 	"github.com/punchamoorthee/ledgerops/internal/store"
-)
-
-// Prometheus Metrics
-var (
-	httpReqTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "ledger_http_requests_total",
-		Help: "Total HTTP requests classified by status",
-	}, []string{"method", "endpoint", "status"})
-
-	httpLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "ledger_http_request_duration_seconds",
-		Help:    "Request latency distribution",
-		Buckets: []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1},
-	}, []string{"method", "endpoint"})
+	"github.com/punchamoorthee/ledgerops/internal/validation"
 )
 
 type Handler struct {
-	store *store.LedgerStore
+	store      *store.LedgerStore
+	metrics    *Metrics
+	limiter    *ratelimit.SlidingWindow  // nil when per-account limiting is off
+	inflight   *ratelimit.KeyedSemaphore // nil when the per-account concurrency cap is off
+	validators []validation.TransferValidator
+	hasher     BodyFingerprinter
+
+	statementMaxWindow time.Duration
+	effectiveWindow    time.Duration
+	escrowAccountID    int64 // 0 disables the escrow endpoints
+	treasuryAccountID  int64 // 0 disables batch adjustments
+	adjustChunk        int
+	maxReasonLength    int
+	recomputeChunk     int
+	equityAccountID    int64 // 0 disables opening-balance imports
+	autoKeyEndpoints   map[string]bool
+	asyncEnabled       bool
+	maxKeyTTL          time.Duration
+	maxKeyWait         time.Duration
+	contentionStatus   int
+	inflightWait       time.Duration
+	maxChainHops       int
+	maxChainAccounts   int
+	receipts           *ReceiptSigner // nil when receipt signing is off
+	camelCase          bool           // respond with camelCase keys
 }
 
-func NewHandler(s *store.LedgerStore) *Handler {
-	return &Handler{store: s}
+func NewHandler(s *store.LedgerStore, cfg *config.Config, reg prometheus.Registerer, hasher BodyFingerprinter, validators ...validation.TransferValidator) *Handler {
+	h := &Handler{
+		store:              s,
+		metrics:            NewMetrics(reg, cfg.MetricsPrefix),
+		validators:         validators,
+		hasher:             hasher,
+		statementMaxWindow: cfg.StatementMaxWindow,
+		effectiveWindow:    cfg.EffectiveDateWindow,
+		escrowAccountID:    cfg.EscrowAccountID,
+		treasuryAccountID:  cfg.TreasuryAccountID,
+		adjustChunk:        cfg.BatchAdjustChunk,
+		maxReasonLength:    cfg.MaxReasonLength,
+		recomputeChunk:     cfg.RecomputeChunk,
+		equityAccountID:    cfg.OpeningEquityAccountID,
+		autoKeyEndpoints:   make(map[string]bool),
+		asyncEnabled:       cfg.AsyncWorkers > 0,
+		maxKeyTTL:          cfg.IdempotencyMaxTTL,
+		maxKeyWait:         cfg.IdempotencyMaxWait,
+		contentionStatus:   cfg.ContentionStatus,
+		inflightWait:       cfg.AccountConcurrencyWait,
+		maxChainHops:       cfg.MaxChainHops,
+		maxChainAccounts:   cfg.MaxChainAccounts,
+		camelCase:          cfg.JSONNaming == NamingCamel,
+	}
+	for _, e := range cfg.IdempotencyOptional {
+		h.autoKeyEndpoints[e] = true
+	}
+	if cfg.ReceiptSigningKey != "" {
+		h.receipts = NewReceiptSigner([]byte(cfg.ReceiptSigningKey))
+	}
+	if cfg.AccountRateLimit > 0 {
+		h.limiter = ratelimit.NewSlidingWindow(cfg.AccountRateLimit, cfg.AccountRateWindow)
+	}
+	if cfg.AccountConcurrency > 0 {
+		h.inflight = ratelimit.NewKeyedSemaphore(cfg.AccountConcurrency)
+	}
+	return h
 }
 
 func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
-	timer := prometheus.NewTimer(httpLatency.WithLabelValues("POST", "/transfers"))
+	rec := &outcomeRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.createTransfer(rec, r)
+	h.metrics.observeTransfer(rec.status, rec.code)
+}
+
+func (h *Handler) createTransfer(w http.ResponseWriter, r *http.Request) {
+	timer := prometheus.NewTimer(h.metrics.httpLatency.WithLabelValues("POST", "/transfers"))
 	defer timer.ObserveDuration()
 
-	idemKey := r.Header.Get("Idempotency-Key")
-	if idemKey == "" {
-		h.respondError(w, http.StatusBadRequest, "Missing Idempotency-Key header", "POST", "/transfers")
+	idemKey, body, ok := h.readIdempotent(w, r, "POST", "/transfers")
+	if !ok {
+		return
+	}
+	ctx, ok := h.keyTTL(w, r, "POST", "/transfers")
+	if !ok {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, "Failed to read body", "POST", "/transfers")
+	var req domain.TransferRequest
+	if err := unmarshalBody(body, &req); err != nil {
+		if errors.Is(err, domain.ErrAmountFormat) {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_AMOUNT", err.Error(), "POST", "/transfers")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", "/transfers")
 		return
 	}
 
-	// Create Hash for Idempotency check
-	hash := sha256.Sum256(body)
-	reqHash := hex.EncodeToString(hash[:])
+	if v := r.Header.Get("If-Match"); v != "" {
+		version, ok := parseVersionETag(v)
+		if !ok {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_IF_MATCH", "If-Match must be the sender account's ETag", "POST", "/transfers")
+			return
+		}
+		req.IfSenderVersion = version
+	}
 
-	// Re-populate body for decoder
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	var errs fieldErrors
+	if req.Amount <= 0 && !req.Sweep {
+		errs.add("amount", "INVALID_AMOUNT", "Amount must be positive")
+	}
+	if req.FromAccountID == req.ToAccountID {
+		errs.add("to_account_id", "SELF_TRANSFER", "Cannot transfer to self")
+	}
+	if !domain.ValidTransferType(req.Type) {
+		errs.add("type", "INVALID_TRANSFER_TYPE", invalidTypeMessage)
+	}
+	h.checkEffectiveDate(&errs, "effective_date", req.EffectiveDate)
+	if errs.respond(h, w, "POST", "/transfers") {
+		return
+	}
 
-	var req domain.TransferRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", "/transfers")
+	reqHash := h.requestHash(req, body)
+
+	// A sweep's amount is unknown here; the store re-validates it.
+	replay := h.replaying(ctx, store.OpTransfer, idemKey, reqHash)
+	if !h.validateTransfer(w, r, req, "/transfers") || (!replay && !h.allowTransfer(w, req.FromAccountID, "/transfers")) {
 		return
 	}
 
-	if req.Amount <= 0 {
-		h.respondError(w, http.StatusUnprocessableEntity, "Amount must be positive", "POST", "/transfers")
+	// Sweeps resolve their amount under the lock, and conditional transfers
+	// check the sender there, so both always run inline.
+	if h.asyncEnabled && prefersAsync(r) && !req.Sweep && req.IfSenderVersion == 0 {
+		resp, err := awaitKey(ctx, h.keyWait(r), func() (*domain.TransferResponse, error) {
+			return h.store.EnqueueTransfer(ctx, req, idemKey, reqHash)
+		})
+		if err != nil {
+			h.respondStoreError(w, err, "POST", "/transfers")
+			return
+		}
+		if resp.Transfer.PublicID != "" {
+			w.Header().Set("Location", fmt.Sprintf("/transfers/%s", resp.Transfer.PublicID))
+		}
+		w.Header().Set("Idempotency-Replayed", strconv.FormatBool(resp.Replayed))
+		w.Header().Set("Preference-Applied", "respond-async")
+		h.respondJSON(w, http.StatusAccepted, resp, "POST", "/transfers")
 		return
 	}
-	if req.FromAccountID == req.ToAccountID {
-		h.respondError(w, http.StatusUnprocessableEntity, "Cannot transfer to self", "POST", "/transfers")
+
+	// Queued transfers take no locks until the worker runs them, so only the
+	// inline path counts against the per-account cap.
+	release, ok := h.acquireAccounts(ctx, w, "/transfers", req.FromAccountID, req.ToAccountID)
+	if !ok {
 		return
 	}
+	defer release()
 
-	resp, err := h.store.ExecTransfer(r.Context(), req, idemKey, reqHash)
+	resp, err := awaitKey(ctx, h.keyWait(r), func() (*domain.TransferResponse, error) {
+		return h.store.ExecTransfer(ctx, req, idemKey, reqHash)
+	})
 	if err != nil {
-		switch err {
-		case store.ErrConflict:
-			h.respondError(w, http.StatusConflict, "Request in progress or lock contention", "POST", "/transfers")
-		case store.ErrAccountNotFound:
-			h.respondError(w, http.StatusNotFound, "Account not found", "POST", "/transfers")
-		case store.ErrKeyMismatch:
-			h.respondError(w, http.StatusUnprocessableEntity, "Idempotency key reused with different payload", "POST", "/transfers")
-		case store.ErrFunds:
-			h.respondError(w, http.StatusUnprocessableEntity, "Insufficient funds", "POST", "/transfers")
-		default:
-			h.respondError(w, http.StatusInternalServerError, err.Error(), "POST", "/transfers")
-		}
+		h.respondStoreError(w, err, "POST", "/transfers")
 		return
 	}
 
-	w.Header().Set("Location", fmt.Sprintf("/transfers/%d", resp.Transfer.ID))
+	// The body is the same representation GET Location returns, which
+	// Content-Location tells the client, so it need not re-fetch.
+	h.receipts.sign(resp)
+	if resp.Transfer.PublicID != "" {
+		loc := fmt.Sprintf("/transfers/%s", resp.Transfer.PublicID)
+		w.Header().Set("Location", loc)
+		w.Header().Set("Content-Location", loc)
+	}
+	w.Header().Set("Cache-Control", transferCacheControl(resp.Transfer.Status))
+	w.Header().Set("Idempotency-Replayed", strconv.FormatBool(resp.Replayed))
 	// In a real scenario, we might return 200 for replays and 201 for creations,
 	// but the payload handles the differentiation.
 	h.respondJSON(w, http.StatusCreated, resp, "POST", "/transfers")
 }
 
+// validateTransfer runs the pluggable risk rules against one leg before
+// anything is reserved or locked, answering for it when one refuses.
+func (h *Handler) validateTransfer(w http.ResponseWriter, r *http.Request, req domain.TransferRequest, endpoint string) bool {
+	for _, v := range h.validators {
+		if err := v.Validate(r.Context(), req); err != nil {
+			h.respondStoreError(w, err, "POST", endpoint)
+			return false
+		}
+	}
+	return true
+}
+
+// replaying reports whether the request would only replay a completed
+// response, in which case it isn't charged against the rate limit: a
+// client retrying a transfer that already went through must not be refused
+// for it. The store is only asked when limiting is on; a failed lookup
+// charges as usual and leaves the error to the store call that follows.
+func (h *Handler) replaying(ctx context.Context, op, key, reqHash string) bool {
+	if h.limiter == nil {
+		return false
+	}
+	ok, err := h.store.Replayable(ctx, op, key, reqHash)
+	return err == nil && ok
+}
+
+// allowTransfer charges one transfer from the sender against its rate
+// limit, shedding bursts from a single account before any DB locks.
+func (h *Handler) allowTransfer(w http.ResponseWriter, from int64, endpoint string) bool {
+	if h.limiter == nil {
+		return true
+	}
+	if ok, retryAfter := h.limiter.Allow(from); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		h.respondErrorCode(w, http.StatusTooManyRequests, "ACCOUNT_RATE_LIMITED", "Too many transfers from this account", "POST", endpoint)
+		return false
+	}
+	return true
+}
+
+// acquireAccounts takes a per-account in-flight slot on every account the
+// request will lock, answering 429 when the cap is reached. release is a
+// no-op when the cap is off.
+func (h *Handler) acquireAccounts(ctx context.Context, w http.ResponseWriter, endpoint string, ids ...int64) (release func(), ok bool) {
+	if h.inflight == nil {
+		return func() {}, true
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, h.inflightWait)
+	defer cancel()
+	release, ok = h.inflight.Acquire(waitCtx, h.inflightWait > 0, ids...)
+	if !ok {
+		w.Header().Set("Retry-After", "1")
+		h.respondErrorCode(w, http.StatusTooManyRequests, "ACCOUNT_BUSY", "Too many transfers in flight on this account", "POST", endpoint)
+	}
+	return release, ok
+}
+
+func (h *Handler) CreateChain(w http.ResponseWriter, r *http.Request) {
+	idemKey, body, ok := h.readIdempotent(w, r, "POST", "/transfers/chain")
+	if !ok {
+		return
+	}
+
+	var req domain.ChainRequest
+	if err := unmarshalBody(body, &req); err != nil {
+		if errors.Is(err, domain.ErrAmountFormat) {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_AMOUNT", err.Error(), "POST", "/transfers/chain")
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", "/transfers/chain")
+		return
+	}
+	// Size limits come first: an oversized chain is rejected before any of
+	// its hops are examined, let alone locked.
+	if len(req.Hops) > h.maxChainHops {
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "TOO_MANY_LEGS", fmt.Sprintf("A chain may have at most %d hops", h.maxChainHops), "POST", "/transfers/chain")
+		return
+	}
+	accounts := make(map[int64]bool)
+	var accountIDs []int64
+	for _, hop := range req.Hops {
+		for _, id := range []int64{hop.FromAccountID, hop.ToAccountID} {
+			if !accounts[id] {
+				accounts[id] = true
+				accountIDs = append(accountIDs, id)
+			}
+		}
+	}
+	if len(accounts) > h.maxChainAccounts {
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "TOO_MANY_ACCOUNTS", fmt.Sprintf("A chain may touch at most %d distinct accounts", h.maxChainAccounts), "POST", "/transfers/chain")
+		return
+	}
+
+	var errs fieldErrors
+	for i, hop := range req.Hops {
+		h.checkEffectiveDate(&errs, fmt.Sprintf("hops[%d].effective_date", i), hop.EffectiveDate)
+	}
+	if errs.respond(h, w, "POST", "/transfers/chain") {
+		return
+	}
+
+	// Each hop is a transfer in its own right: every hop must pass the same
+	// rules as POST /transfers, then counts against its sender's rate limit.
+	for _, hop := range req.Hops {
+		if !h.validateTransfer(w, r, hop, "/transfers/chain") {
+			return
+		}
+	}
+	reqHash := h.requestHash(req, body)
+	if !h.replaying(r.Context(), store.OpChain, idemKey, reqHash) {
+		for _, hop := range req.Hops {
+			if !h.allowTransfer(w, hop.FromAccountID, "/transfers/chain") {
+				return
+			}
+		}
+	}
+	release, ok := h.acquireAccounts(r.Context(), w, "/transfers/chain", accountIDs...)
+	if !ok {
+		return
+	}
+	defer release()
+
+	resp, err := awaitKey(r.Context(), h.keyWait(r), func() (*domain.ChainResponse, error) {
+		return h.store.ExecChain(r.Context(), req, idemKey, reqHash)
+	})
+	if err != nil {
+		h.respondStoreError(w, err, "POST", "/transfers/chain")
+		return
+	}
+
+	w.Header().Set("Idempotency-Replayed", strconv.FormatBool(resp.Replayed))
+	h.respondJSON(w, http.StatusCreated, resp, "POST", "/transfers/chain")
+}
+
+// checkEffectiveDate adds a problem to errs unless v is empty or a date
+// within effectiveWindow of today (UTC).
+func (h *Handler) checkEffectiveDate(errs *fieldErrors, field, v string) {
+	if v == "" {
+		return
+	}
+	d, err := time.Parse(domain.EffectiveDateLayout, v)
+	if err != nil {
+		errs.add(field, "INVALID_EFFECTIVE_DATE", "effective_date must be a date (YYYY-MM-DD)")
+		return
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if skew := d.Sub(today); skew > h.effectiveWindow || -skew > h.effectiveWindow {
+		errs.add(field, "INVALID_EFFECTIVE_DATE", fmt.Sprintf("effective_date must be within %s of today", h.effectiveWindow))
+	}
+}
+
+// requestHash fingerprints an idempotent request: its decoded form when the
+// configured fingerprinter hashes requests, otherwise parts then body.
+func (h *Handler) requestHash(req any, body []byte, parts ...[]byte) string {
+	if rf, ok := h.hasher.(RequestFingerprinter); ok {
+		return rf.FingerprintRequest(req, parts...)
+	}
+	return h.hasher.Fingerprint(append(parts, body)...)
+}
+
+// prefersAsync reports whether the client sent "Prefer: respond-async" (RFC 7240).
+func prefersAsync(r *http.Request) bool {
+	for _, v := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyWait is how long to wait for an in-flight request holding the same
+// idempotency key: 0 unless the client sent "Prefer: wait" or
+// "Prefer: wait=<seconds>", and never more than maxKeyWait.
+func (h *Handler) keyWait(r *http.Request) time.Duration {
+	for _, v := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			if !strings.EqualFold(name, "wait") {
+				continue
+			}
+			if secs, err := strconv.Atoi(val); err == nil && secs >= 0 && time.Duration(secs)*time.Second < h.maxKeyWait {
+				return time.Duration(secs) * time.Second
+			}
+			return h.maxKeyWait
+		}
+	}
+	return 0
+}
+
+// awaitKey runs call, and while it fails because another request holds the
+// idempotency key, retries it with backoff for up to wait. Once the other
+// request commits, the retry replays its stored response. Lock contention
+// is not waited on; it is a different request's lock.
+func awaitKey[T any](ctx context.Context, wait time.Duration, call func() (T, error)) (T, error) {
+	deadline := time.Now().Add(wait)
+	backoff := 10 * time.Millisecond
+	for {
+		resp, err := call()
+		if !errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrLockContention) || time.Now().Add(backoff).After(deadline) {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 200*time.Millisecond)
+	}
+}
+
+// readIdempotent pulls the Idempotency-Key header and the raw body of a
+// mutating request. On failure it has already written the response.
+func (h *Handler) readIdempotent(w http.ResponseWriter, r *http.Request, method, endpoint string) (string, []byte, bool) {
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		if !h.autoKeyEndpoints[endpoint] {
+			h.respondError(w, http.StatusBadRequest, "Missing Idempotency-Key header", method, endpoint)
+			return "", nil, false
+		}
+		// Opted-in endpoint: proceed with a one-off key. Retries of this
+		// request will not be deduplicated.
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			h.respondError(w, http.StatusInternalServerError, "Failed to generate idempotency key", method, endpoint)
+			return "", nil, false
+		}
+		idemKey = "auto-" + hex.EncodeToString(b[:])
+		log.Printf("%s %s: no Idempotency-Key, generated %s", method, endpoint, idemKey)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "Failed to read body", method, endpoint)
+		return "", nil, false
+	}
+
+	// Re-populate body for decoder
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	return idemKey, body, true
+}
+
+// keyTTL applies the optional Idempotency-Key-TTL header (whole seconds) to
+// the request context, clamped to the configured maximum. On failure it has
+// already written the response.
+func (h *Handler) keyTTL(w http.ResponseWriter, r *http.Request, method, endpoint string) (context.Context, bool) {
+	v := r.Header.Get("Idempotency-Key-TTL")
+	if v == "" {
+		return r.Context(), true
+	}
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || secs <= 0 {
+		h.respondErrorCode(w, http.StatusBadRequest, "INVALID_IDEMPOTENCY_TTL", "Idempotency-Key-TTL must be a positive number of seconds", method, endpoint)
+		return nil, false
+	}
+	ttl := h.maxKeyTTL
+	if secs < int64(ttl/time.Second) {
+		ttl = time.Duration(secs) * time.Second
+	}
+	return store.WithKeyTTL(r.Context(), ttl), true
+}
+
+// RequireJSON rejects mutating requests that carry a body under any media
+// type other than application/json with 415 UNSUPPORTED_MEDIA_TYPE. A
+// charset parameter is allowed if it is utf-8. Bodiless requests (e.g.
+// escrow release) pass through.
+func (h *Handler) RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength == 0 || jsonContentType(r.Header.Get("Content-Type")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.respondErrorCode(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+			"Content-Type must be application/json", r.Method, routeEndpoint(r))
+	})
+}
+
+func jsonContentType(v string) bool {
+	mt, params, err := mime.ParseMediaType(v)
+	if err != nil || mt != "application/json" {
+		return false
+	}
+	for k, p := range params {
+		if k != "charset" || !strings.EqualFold(p, "utf-8") {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeJSON strictly decodes the request body into dst: the body must be a
+// single JSON object with no unknown fields. On failure it has already
+// written the response.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, dst any, method, endpoint string) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.respondErrorCode(w, http.StatusBadRequest, "MALFORMED_JSON", "Malformed JSON: "+err.Error(), method, endpoint)
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(renameKeys(body, camelToSnake)))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after JSON object")
+	}
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, io.EOF):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "EMPTY_BODY", "Request body is required", method, endpoint)
+	default:
+		h.respondErrorCode(w, http.StatusBadRequest, "MALFORMED_JSON", "Malformed JSON: "+err.Error(), method, endpoint)
+	}
+	return false
+}
+
+const invalidTypeMessage = "type must be one of: transfer, payment, refund, fee, adjustment"
+
+// respondStoreError maps the store's write-path errors, and validator
+// rejections, to HTTP responses.
+func (h *Handler) respondStoreError(w http.ResponseWriter, err error, method, endpoint string) {
+	var verr *validation.Error
+	switch {
+	case errors.Is(err, validation.ErrBlockedPair):
+		h.respondErrorCode(w, http.StatusForbidden, validation.ErrBlockedPair.Code, validation.ErrBlockedPair.Message, method, endpoint)
+	case errors.As(err, &verr):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, verr.Code, verr.Message, method, endpoint)
+	case errors.Is(err, store.ErrInvariantViolation):
+		h.metrics.invariantViolations.Inc()
+		log.Printf("ERROR %s %s: %v", method, endpoint, err)
+		h.respondErrorCode(w, http.StatusInternalServerError, "INVARIANT_VIOLATION", "Ledger invariant violated; the write was rolled back", method, endpoint)
+	case errors.Is(err, store.ErrUnbalancedEntries):
+		log.Printf("ERROR %s %s: %v", method, endpoint, err)
+		h.respondErrorCode(w, http.StatusInternalServerError, "UNBALANCED_ENTRIES", "Ledger entries do not balance; nothing was written", method, endpoint)
+	case store.IsTimeout(err):
+		w.Header().Set("Retry-After", "1")
+		h.respondErrorCode(w, http.StatusServiceUnavailable, "TIMEOUT", "Request timed out; the transaction was rolled back", method, endpoint)
+	case errors.Is(err, store.ErrLockContention):
+		if h.contentionStatus == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "1")
+		}
+		h.respondErrorCode(w, h.contentionStatus, "LOCK_CONTENTION", "Account is busy with another transfer; retry", method, endpoint)
+	case errors.Is(err, store.ErrConflict):
+		h.respondError(w, http.StatusConflict, "Request in progress or lock contention", method, endpoint)
+	case errors.Is(err, store.ErrAccountNotFound):
+		h.respondError(w, http.StatusNotFound, "Account not found", method, endpoint)
+	case errors.Is(err, store.ErrKeyMismatch):
+		h.respondError(w, http.StatusUnprocessableEntity, "Idempotency key reused with different payload", method, endpoint)
+	case errors.Is(err, store.ErrIdempotencyOperationMismatch):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_OPERATION_MISMATCH", "Idempotency key was already used for a different operation", method, endpoint)
+	case errors.Is(err, store.ErrFunds):
+		h.respondError(w, http.StatusUnprocessableEntity, "Insufficient funds", method, endpoint)
+	case errors.Is(err, store.ErrInvalidAmount):
+		h.respondError(w, http.StatusUnprocessableEntity, "Amount must be positive", method, endpoint)
+	case errors.Is(err, store.ErrSelfTransfer):
+		h.respondError(w, http.StatusUnprocessableEntity, "Cannot transfer to self", method, endpoint)
+	case errors.Is(err, store.ErrInvalidEffectiveDate):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "INVALID_EFFECTIVE_DATE", "effective_date must be a date (YYYY-MM-DD)", method, endpoint)
+	case errors.Is(err, store.ErrInvalidType):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "INVALID_TRANSFER_TYPE", invalidTypeMessage, method, endpoint)
+	case errors.Is(err, store.ErrVersionConflict):
+		h.respondErrorCode(w, http.StatusConflict, "VERSION_CONFLICT", "Account was modified; re-read it and retry with the current version", method, endpoint)
+	case errors.Is(err, store.ErrPreconditionFailed):
+		h.respondErrorCode(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED", "Sender account changed since it was read", method, endpoint)
+	case errors.Is(err, store.ErrBelowMinimumBalance):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "BELOW_MINIMUM_BALANCE", "Transfer would take the balance below the account's minimum balance", method, endpoint)
+	case errors.Is(err, store.ErrNegativeMinBalance):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "INVALID_MIN_BALANCE", "Minimum balance must not be negative", method, endpoint)
+	case errors.Is(err, store.ErrNegativeBalance):
+		h.respondErrorCode(w, http.StatusUnprocessableEntity, "INVALID_INITIAL_BALANCE", "Initial balance must not be negative", method, endpoint)
+	case errors.Is(err, store.ErrTransferNotFound):
+		h.respondError(w, http.StatusNotFound, "Transfer not found", method, endpoint)
+	case errors.Is(err, store.ErrEmptyChain):
+		h.respondError(w, http.StatusUnprocessableEntity, "Chain must have at least one hop", method, endpoint)
+	case errors.Is(err, store.ErrEscrowNotFound):
+		h.respondError(w, http.StatusNotFound, "Escrow not found", method, endpoint)
+	case errors.Is(err, store.ErrEscrowNotHeld):
+		h.respondError(w, http.StatusConflict, "Escrow already released or refunded", method, endpoint)
+	case errors.Is(err, store.ErrAlreadyOpened):
+		h.respondErrorCode(w, http.StatusConflict, "ACCOUNT_ALREADY_OPENED", "An account already has an opening balance", method, endpoint)
+	default:
+		h.respondInternalError(w, err, method, endpoint)
+	}
+}
+
 func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
-	type req struct {
-		InitialBalance int64 `json:"initial_balance"`
+	var p domain.CreateAccountRequest
+	if !h.decodeJSON(w, r, &p, "POST", "/accounts") {
+		return
 	}
-	var p req
-	json.NewDecoder(r.Body).Decode(&p)
 
-	id, err := h.store.CreateAccount(r.Context(), p.InitialBalance)
+	// Zero is a valid opening balance; negative would open the account insolvent.
+	var errs fieldErrors
+	if p.InitialBalance < 0 {
+		errs.add("initial_balance", "INVALID_INITIAL_BALANCE", "Initial balance must not be negative")
+	}
+	validAccountDetails(&errs, p.Name, p.Metadata)
+	validTags(&errs, p.Tags)
+	if errs.respond(h, w, "POST", "/accounts") {
+		return
+	}
+
+	id, err := h.store.CreateAccount(r.Context(), p)
 	if err != nil {
-		h.respondError(w, http.StatusInternalServerError, err.Error(), "POST", "/accounts")
+		h.respondStoreError(w, err, "POST", "/accounts")
 		return
 	}
 	h.respondJSON(w, http.StatusCreated, map[string]int64{"id": id}, "POST", "/accounts")
@@ -125,19 +634,679 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 			h.respondError(w, http.StatusNotFound, "Account not found", "GET", "/accounts")
 			return
 		}
-		h.respondError(w, http.StatusInternalServerError, err.Error(), "GET", "/accounts")
+		h.respondInternalError(w, err, "GET", "/accounts")
 		return
 	}
+	w.Header().Set("ETag", versionETag(acc.BalanceVersion))
 	h.respondJSON(w, http.StatusOK, acc, "GET", "/accounts")
 }
 
+// ListAccounts serves GET /accounts. Each repeated tag parameter narrows the
+// listing to accounts carrying all of the given tags; limit and cursor page
+// through the result in ID order.
+func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	tags := r.URL.Query()["tag"]
+	var errs fieldErrors
+	validTags(&errs, tags)
+	if errs.respond(h, w, "GET", "/accounts") {
+		return
+	}
+	limit, after, ok := h.pageParams(w, r, "/accounts")
+	if !ok {
+		return
+	}
+
+	page, err := h.store.ListAccounts(r.Context(), tags, after, limit)
+	if err != nil {
+		h.respondInternalError(w, err, "GET", "/accounts")
+		return
+	}
+	h.respondJSON(w, http.StatusOK, page, "GET", "/accounts")
+}
+
+// versionETag renders an account's balance version as a strong ETag. Send it
+// back as If-Match on a transfer to make the transfer conditional on the
+// sender.
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseVersionETag accepts an ETag from versionETag, or the bare version.
+func parseVersionETag(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil && n > 0
+}
+
+// UpdateAccount edits an account's name, metadata and tags. The body's version
+// must match the account's; a stale version gets 409 so the client can
+// re-read and retry instead of overwriting someone else's edit.
+func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/accounts/{id}"
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid account ID", "PATCH", endpoint)
+		return
+	}
+
+	var upd domain.AccountUpdate
+	if !h.decodeJSON(w, r, &upd, "PATCH", endpoint) {
+		return
+	}
+	var errs fieldErrors
+	if upd.Version < 1 {
+		errs.add("version", "VERSION_REQUIRED", "version is required")
+	}
+	var name string
+	if upd.Name != nil {
+		name = *upd.Name
+	}
+	validAccountDetails(&errs, name, upd.Metadata)
+	validTags(&errs, upd.Tags)
+	if errs.respond(h, w, "PATCH", endpoint) {
+		return
+	}
+
+	acc, err := h.store.UpdateAccount(r.Context(), id, upd)
+	if err != nil {
+		h.respondStoreError(w, err, "PATCH", endpoint)
+		return
+	}
+	w.Header().Set("ETag", versionETag(acc.BalanceVersion))
+	h.respondJSON(w, http.StatusOK, acc, "PATCH", endpoint)
+}
+
+const (
+	maxAccountNameLen = 100
+	maxAccountTags    = 20
+	maxTagLen         = 50
+)
+
+// validAccountDetails checks the descriptive account fields, adding any
+// problems to errs. Metadata must be a flat object: values may be strings,
+// numbers, booleans or null.
+func validAccountDetails(errs *fieldErrors, name string, metadata map[string]any) {
+	if utf8.RuneCountInString(name) > maxAccountNameLen {
+		errs.add("name", "INVALID_ACCOUNT_DETAILS", fmt.Sprintf("name must be at most %d characters", maxAccountNameLen))
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch metadata[k].(type) {
+		case map[string]any, []any:
+			errs.add("metadata."+k, "INVALID_ACCOUNT_DETAILS", fmt.Sprintf("metadata.%s must be a string, number, boolean or null", k))
+		}
+	}
+}
+
+// validTags checks an account's tag list: at most maxAccountTags entries,
+// each non-empty, at most maxTagLen characters and free of duplicates.
+func validTags(errs *fieldErrors, tags []string) {
+	if len(tags) > maxAccountTags {
+		errs.add("tags", "INVALID_TAGS", fmt.Sprintf("at most %d tags are allowed", maxAccountTags))
+		return
+	}
+	seen := make(map[string]bool, len(tags))
+	for i, t := range tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(t) == "":
+			errs.add(field, "INVALID_TAGS", "tags must not be empty")
+		case utf8.RuneCountInString(t) > maxTagLen:
+			errs.add(field, "INVALID_TAGS", fmt.Sprintf("tags must be at most %d characters", maxTagLen))
+		case seen[t]:
+			errs.add(field, "INVALID_TAGS", fmt.Sprintf("duplicate tag %q", t))
+		}
+		seen[t] = true
+	}
+}
+
+func (h *Handler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/accounts/{id}/statement"
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid account ID", "GET", endpoint)
+		return
+	}
+
+	q := r.URL.Query()
+	from, errFrom := time.Parse(time.RFC3339, q.Get("from"))
+	to, errTo := time.Parse(time.RFC3339, q.Get("to"))
+	if errFrom != nil || errTo != nil {
+		h.respondErrorCode(w, http.StatusBadRequest, "INVALID_DATE_RANGE", "from and to must be RFC 3339 timestamps", "GET", endpoint)
+		return
+	}
+	if !from.Before(to) {
+		h.respondErrorCode(w, http.StatusBadRequest, "INVALID_DATE_RANGE", "from must be before to", "GET", endpoint)
+		return
+	}
+	if to.Sub(from) > h.statementMaxWindow {
+		h.respondErrorCode(w, http.StatusBadRequest, "INVALID_DATE_RANGE", fmt.Sprintf("Statement window may not exceed %s", h.statementMaxWindow), "GET", endpoint)
+		return
+	}
+
+	st, err := h.store.GetStatement(r.Context(), id, from, to)
+	if err != nil {
+		if err == store.ErrAccountNotFound {
+			h.respondError(w, http.StatusNotFound, "Account not found", "GET", endpoint)
+			return
+		}
+		h.respondInternalError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, st, "GET", endpoint)
+}
+
+// GetAccountSummary serves dashboard totals. The optional window (a Go
+// duration such as "720h") bounds the activity aggregation to recent entries.
+func (h *Handler) GetAccountSummary(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/accounts/{id}/summary"
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid account ID", "GET", endpoint)
+		return
+	}
+
+	var since *time.Time
+	if v := r.URL.Query().Get("window"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_WINDOW", "window must be a positive duration such as 24h", "GET", endpoint)
+			return
+		}
+		t := time.Now().Add(-window)
+		since = &t
+	}
+
+	sum, err := h.store.GetAccountSummary(r.Context(), id, since)
+	if err != nil {
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, sum, "GET", endpoint)
+}
+
+// GetAccountEntries returns a page of the account's ledger entries with
+// balance_after, paged by limit and cursor.
+func (h *Handler) GetAccountEntries(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/accounts/{id}/entries"
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid account ID", "GET", endpoint)
+		return
+	}
+
+	limit, after, ok := h.pageParams(w, r, endpoint)
+	if !ok {
+		return
+	}
+
+	entries, err := h.store.GetEntries(r.Context(), id, after, limit)
+	if err != nil {
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, entries, "GET", endpoint)
+}
+
+// GetTransfer accepts either the internal numeric ID or the public UUID.
+func (h *Handler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.store.GetTransfer(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondStoreError(w, err, "GET", "/transfers/{id}")
+		return
+	}
+	h.receipts.sign(resp)
+	w.Header().Set("Cache-Control", transferCacheControl(resp.Transfer.Status))
+	h.respondJSON(w, http.StatusOK, resp, "GET", "/transfers/{id}")
+}
+
+// GetTransferByKey serves GET /transfers/by-key/{key}: the transfer a
+// POST /transfers with that Idempotency-Key created. Both misses are 404s
+// but carry different codes, so a client can tell "retry later" from
+// "never happened".
+func (h *Handler) GetTransferByKey(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/transfers/by-key/{key}"
+	resp, err := h.store.GetTransferByKey(r.Context(), mux.Vars(r)["key"])
+	switch {
+	case errors.Is(err, store.ErrKeyNotFound):
+		h.respondErrorCode(w, http.StatusNotFound, "KEY_NOT_FOUND", "No transfer was created with this idempotency key", "GET", endpoint)
+		return
+	case errors.Is(err, store.ErrKeyInProgress):
+		h.respondErrorCode(w, http.StatusNotFound, "KEY_IN_PROGRESS", "The request with this idempotency key has not finished", "GET", endpoint)
+		return
+	case err != nil:
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.receipts.sign(resp)
+	w.Header().Set("Cache-Control", transferCacheControl(resp.Transfer.Status))
+	h.respondJSON(w, http.StatusOK, resp, "GET", endpoint)
+}
+
+const maxBatchGetIDs = 100
+
+// BatchGetTransfers serves POST /transfers/batch-get for clients that need
+// many transfers at once, e.g. to reconcile a file. It is a read; POST only
+// carries the ID list.
+func (h *Handler) BatchGetTransfers(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/transfers/batch-get"
+	var req domain.TransferBatchRequest
+	if !h.decodeJSON(w, r, &req, "POST", endpoint) {
+		return
+	}
+	var errs fieldErrors
+	if len(req.IDs) == 0 || len(req.IDs) > maxBatchGetIDs {
+		errs.add("ids", "INVALID_IDS", fmt.Sprintf("ids must list between 1 and %d transfer IDs", maxBatchGetIDs))
+	}
+	if errs.respond(h, w, "POST", endpoint) {
+		return
+	}
+
+	batch, err := h.store.GetTransfers(r.Context(), req.IDs)
+	if err != nil {
+		h.respondStoreError(w, err, "POST", endpoint)
+		return
+	}
+	for i := range batch.Transfers {
+		h.receipts.sign(&batch.Transfers[i])
+	}
+	h.respondJSON(w, http.StatusOK, batch, "POST", endpoint)
+}
+
+// transferCacheControl lets clients reuse a settled transfer: completed and
+// failed transfers never change. Pending ones must be revalidated.
+func transferCacheControl(status string) string {
+	if status == "pending" {
+		return "no-cache"
+	}
+	return "private, max-age=86400"
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// pageParams reads the limit and cursor query parameters shared by keyset
+// listings, writing a 400 and returning false if either is invalid.
+func (h *Handler) pageParams(w http.ResponseWriter, r *http.Request, endpoint string) (int, *store.Cursor, bool) {
+	q := r.URL.Query()
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			h.respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize), "GET", endpoint)
+			return 0, nil, false
+		}
+		limit = n
+	}
+	var after *store.Cursor
+	if v := q.Get("cursor"); v != "" {
+		c, err := store.DecodeCursor(v)
+		if err != nil {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_CURSOR", "Invalid cursor", "GET", endpoint)
+			return 0, nil, false
+		}
+		after = c
+	}
+	return limit, after, true
+}
+
+// SearchTransfers serves GET /transfers with optional min_amount, max_amount,
+// from, to, effective_from, effective_to, status, limit and cursor query
+// parameters.
+func (h *Handler) SearchTransfers(w http.ResponseWriter, r *http.Request) {
+	h.listTransfers(w, r, "/transfers", 0)
+}
+
+// GetAccountTransfers serves GET /accounts/{id}/transfers: the same listing
+// and filters as SearchTransfers, restricted to one account.
+func (h *Handler) GetAccountTransfers(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/accounts/{id}/transfers"
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid account ID", "GET", endpoint)
+		return
+	}
+	if _, err := h.store.GetAccount(r.Context(), id); err != nil {
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.listTransfers(w, r, endpoint, id)
+}
+
+func (h *Handler) listTransfers(w http.ResponseWriter, r *http.Request, endpoint string, accountID int64) {
+	f, err := parseTransferFilter(r.URL.Query())
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			h.respondErrorCode(w, http.StatusBadRequest, "INVALID_CURSOR", "Invalid cursor", "GET", endpoint)
+			return
+		}
+		h.respondError(w, http.StatusBadRequest, err.Error(), "GET", endpoint)
+		return
+	}
+	f.AccountID = accountID
+
+	page, err := h.store.SearchTransfers(r.Context(), f)
+	if err != nil {
+		h.respondInternalError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, page, "GET", endpoint)
+}
+
+// parseTransferFilter validates listing query parameters. Errors other than
+// store.ErrInvalidCursor carry a client-facing message.
+func parseTransferFilter(q url.Values) (store.TransferFilter, error) {
+	f := store.TransferFilter{Limit: defaultPageSize}
+
+	for _, p := range []struct {
+		name string
+		dst  **int64
+	}{{"min_amount", &f.MinAmount}, {"max_amount", &f.MaxAmount}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("%s must be a non-negative integer", p.name)
+			}
+			*p.dst = &n
+		}
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		return f, fmt.Errorf("min_amount must not exceed max_amount")
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 timestamp", p.name)
+			}
+			*p.dst = &t
+		}
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, fmt.Errorf("from must be before to")
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"effective_from", &f.EffectiveFrom}, {"effective_to", &f.EffectiveTo}} {
+		if v := q.Get(p.name); v != "" {
+			d, err := time.Parse(domain.EffectiveDateLayout, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be a date (YYYY-MM-DD)", p.name)
+			}
+			*p.dst = &d
+		}
+	}
+	if f.EffectiveFrom != nil && f.EffectiveTo != nil && !f.EffectiveFrom.Before(*f.EffectiveTo) {
+		return f, fmt.Errorf("effective_from must be before effective_to")
+	}
+
+	switch status := q.Get("status"); status {
+	case "", "pending", "completed", "failed":
+		f.Status = status
+	default:
+		return f, fmt.Errorf("status must be one of: pending, completed, failed")
+	}
+
+	if typ := q.Get("type"); domain.ValidTransferType(typ) {
+		f.Type = typ
+	} else {
+		return f, errors.New(invalidTypeMessage)
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		f.Limit = n
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := store.DecodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.Cursor = c
+	}
+	return f, nil
+}
+
+// BuildInfo identifies the running binary; the values come from ldflags.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// DebugInfo returns a handler reporting what is deployed and how long it
+// has been up. Uptime counts from when the handler is created.
+func (h *Handler) DebugInfo(b BuildInfo) http.HandlerFunc {
+	started := time.Now()
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.respondJSON(w, http.StatusOK, struct {
+			BuildInfo
+			GoVersion  string `json:"go_version"`
+			GOMAXPROCS int    `json:"gomaxprocs"`
+			Uptime     string `json:"uptime"`
+		}{b, runtime.Version(), runtime.GOMAXPROCS(0), time.Since(started).Round(time.Second).String()}, "GET", "/debug/info")
+	}
+}
+
+// VerifyAccount returns a handler that checks one account for balance drift.
+func (h *Handler) VerifyAccount(m *audit.DriftMonitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const endpoint = "/admin/accounts/{id}/verify"
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "Invalid account ID", "POST", endpoint)
+			return
+		}
+		v, err := m.Verify(r.Context(), id)
+		if err != nil {
+			h.respondStoreError(w, err, "POST", endpoint)
+			return
+		}
+		h.respondJSON(w, http.StatusOK, v, "POST", endpoint)
+	}
+}
+
+// ReconcileDiff lists accounts whose stored balance has drifted from their
+// ledger entries, paged by the after and limit query parameters. It is the
+// read-only look before any repair. Admin router only.
+func (h *Handler) ReconcileDiff(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/admin/reconcile/diff"
+	q := r.URL.Query()
+	var after int64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			h.respondError(w, http.StatusBadRequest, "after must be a non-negative account ID", "GET", endpoint)
+			return
+		}
+		after = n
+	}
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			h.respondError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize), "GET", endpoint)
+			return
+		}
+		limit = n
+	}
+
+	diff, err := h.store.ReconcileDiff(r.Context(), after, limit)
+	if err != nil {
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, diff, "GET", endpoint)
+}
+
+// recomputeBudget bounds one recompute-all call; a walk that runs longer
+// stops after the current chunk and reports where to resume.
+const recomputeBudget = 30 * time.Second
+
+// RecomputeAll rewrites every account's stored balance from its ledger
+// entries, walking the table in ID-ordered chunks from the after query
+// parameter. It is safe under live traffic: only one account is locked at
+// a time. When the walk outlasts recomputeBudget the response carries
+// next_after to resume from. Admin router only.
+func (h *Handler) RecomputeAll(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/admin/recompute-all"
+	var after int64
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			h.respondError(w, http.StatusBadRequest, "after must be a non-negative account ID", "POST", endpoint)
+			return
+		}
+		after = n
+	}
+
+	total := &domain.RecomputeResult{}
+	start := time.Now()
+	for {
+		res, err := h.store.RecomputeBalances(r.Context(), after, h.recomputeChunk)
+		if err != nil {
+			h.respondStoreError(w, err, "POST", endpoint)
+			return
+		}
+		total.Processed += res.Processed
+		total.Corrected += res.Corrected
+		if res.NextAfter == 0 {
+			total.NextAfter = 0
+			break
+		}
+		after, total.NextAfter = res.NextAfter, res.NextAfter
+		if time.Since(start) >= recomputeBudget {
+			break
+		}
+	}
+	h.respondJSON(w, http.StatusOK, total, "POST", endpoint)
+}
+
+// TraceTransfer returns the full recorded story of one transfer for
+// incident investigation. It is mounted on the admin router only.
+func (h *Handler) TraceTransfer(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/admin/transfers/{id}/trace"
+	trace, err := h.store.TraceTransfer(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondStoreError(w, err, "GET", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, trace, "GET", endpoint)
+}
+
+// ReloadBlocklist returns a handler that refreshes the blocked-pairs cache on demand.
+func (h *Handler) ReloadBlocklist(b *validation.PairBlocklist) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := b.Reload(r.Context())
+		if err != nil {
+			h.respondInternalError(w, err, "POST", "/admin/blocklist/reload")
+			return
+		}
+		h.respondJSON(w, http.StatusOK, map[string]int{"pairs": n}, "POST", "/admin/blocklist/reload")
+	}
+}
+
 func (h *Handler) respondJSON(w http.ResponseWriter, code int, payload interface{}, method, endpoint string) {
-	httpReqTotal.WithLabelValues(method, endpoint, strconv.Itoa(code)).Inc()
+	if !responseAbandoned(w) {
+		h.metrics.httpReqTotal.WithLabelValues(method, metricEndpoint(w, endpoint), strconv.Itoa(code)).Inc()
+	}
 	w.Header().Set("Content-Type", "application/json")
+	v2 := responseVersion(w) == apiV2
+	if !h.camelCase && !v2 {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(payload)
+		return
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// Reshape before renaming: moneyKeys are snake_case.
+	if v2 {
+		body = v2Body(body, code)
+	}
+	if h.camelCase {
+		body = renameKeys(body, snakeToCamel)
+	}
 	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(payload)
+	w.Write(append(body, '\n'))
 }
 
 func (h *Handler) respondError(w http.ResponseWriter, code int, msg, method, endpoint string) {
 	h.respondJSON(w, code, map[string]string{"error": msg}, method, endpoint)
 }
+
+// NotFound answers requests no route on router matches in the usual error
+// envelope. A path that router serves under other methods gets the 405
+// instead: mux forgets a method mismatch inside a subrouter when a later
+// route there shares the prefix, and falls back to NotFoundHandler.
+func (h *Handler) NotFound(router *mux.Router) http.Handler {
+	notAllowed := h.MethodNotAllowed(router)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(allowedMethods(router, r)) > 0 {
+			notAllowed.ServeHTTP(w, r)
+			return
+		}
+		h.respondErrorCode(w, http.StatusNotFound, "NOT_FOUND", "No such endpoint", r.Method, "unmatched")
+	})
+}
+
+// MethodNotAllowed answers requests whose path matches a route on router but
+// whose method does not, listing the methods that would match in Allow.
+func (h *Handler) MethodNotAllowed(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowedMethods(router, r), ", "))
+		h.respondErrorCode(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed on this endpoint", r.Method, "unmatched")
+	})
+}
+
+// allowedMethods lists the methods under which router fully matches r's
+// path. A full match is reliable where mux's mismatch reporting is not.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, m := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// respondInternalError logs err in full and gives the client only a generic
+// 500: driver and SQL error text can reveal schema and query details.
+func (h *Handler) respondInternalError(w http.ResponseWriter, err error, method, endpoint string) {
+	log.Printf("ERROR %s %s: %v", method, endpoint, err)
+	h.respondErrorCode(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error", method, endpoint)
+}
+
+// respondErrorCode is respondError with a stable machine-readable code
+// alongside the human message.
+func (h *Handler) respondErrorCode(w http.ResponseWriter, code int, errCode, msg, method, endpoint string) {
+	if rec, ok := w.(*outcomeRecorder); ok {
+		rec.code = errCode
+	}
+	h.respondJSON(w, code, map[string]string{"error": msg, "code": errCode}, method, endpoint)
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestCreateTransferRateLimited(t *testing.T) {
+	h := newTestHandler(t, testConfig(t, map[string]string{"ACCOUNT_RATE_LIMIT": "3", "ACCOUNT_RATE_WINDOW": "1m"}))
+	for i := 0; i < 3; i++ {
+		h.limiter.Allow(1) // three transfers already made this window
+	}
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":10}`, map[string]string{"Idempotency-Key": "k4"})
+	if rec.Code != http.StatusTooManyRequests || errorBody(t, rec)["code"] != "ACCOUNT_RATE_LIMITED" {
+		t.Fatalf("got %d %s, want 429 ACCOUNT_RATE_LIMITED", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" || got == "0" {
+		t.Errorf("Retry-After = %q", got)
+	}
+}
+
+func TestIdempotencyKeyModes(t *testing.T) {
+	h := newTestHandler(t, testConfig(t, map[string]string{"IDEMPOTENCY_OPTIONAL_ENDPOINTS": "/accounts"}))
+	read := func(endpoint string) (string, *httptest.ResponseRecorder, bool) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1"+endpoint, strings.NewReader(`{"a":1}`))
+		key, body, ok := h.readIdempotent(rec, req, "POST", endpoint)
+		if ok && string(body) != `{"a":1}` {
+			t.Errorf("%s: body = %q", endpoint, body)
+		}
+		return key, rec, ok
+	}
+
+	t.Run("required", func(t *testing.T) {
+		_, rec, ok := read("/transfers")
+		if ok || rec.Code != http.StatusBadRequest {
+			t.Fatalf("ok=%v, got %d %s, want 400", ok, rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("auto", func(t *testing.T) {
+		k1, _, ok1 := read("/accounts")
+		k2, _, ok2 := read("/accounts")
+		if !ok1 || !ok2 {
+			t.Fatal("opted-in endpoint rejected a request without a key")
+		}
+		if !strings.HasPrefix(k1, "auto-") || k1 == k2 {
+			t.Errorf("generated keys %q and %q, want distinct auto- keys", k1, k2)
+		}
+	})
+
+	t.Run("client key wins", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "mine")
+		if key, _, _ := h.readIdempotent(rec, req, "POST", "/accounts"); key != "mine" {
+			t.Errorf("key = %q, want the client's", key)
+		}
+	})
+}
+
+func TestTransferTypeValidated(t *testing.T) {
+	h := newTestHandler(t, nil)
+	key := map[string]string{"Idempotency-Key": "k1"}
+
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":10,"type":"bonus"}`, key)
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "INVALID_TRANSFER_TYPE" {
+		t.Fatalf("got %d %s, want 422 INVALID_TRANSFER_TYPE", rec.Code, rec.Body)
+	}
+
+	if _, err := parseTransferFilter(url.Values{"type": {"bonus"}}); err == nil {
+		t.Error("search accepted an unknown type")
+	}
+	f, err := parseTransferFilter(url.Values{"type": {"refund"}})
+	if err != nil || f.Type != "refund" {
+		t.Errorf("type=refund: filter %+v, err %v", f, err)
+	}
+}
+
+func TestCreateTransferInvalidAmountString(t *testing.T) {
+	h := newTestHandler(t, nil)
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":"12.50"}`, map[string]string{"Idempotency-Key": "k1"})
+	if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "INVALID_AMOUNT" {
+		t.Fatalf("got %d %s, want 400 INVALID_AMOUNT", rec.Code, rec.Body)
+	}
+}
+
+func TestIdempotencyKeyTTLHeader(t *testing.T) {
+	h := newTestHandler(t, nil)
+	for _, v := range []string{"0", "-5", "1.5", "an hour"} {
+		rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":10}`,
+			map[string]string{"Idempotency-Key": "k1", "Idempotency-Key-TTL": v})
+		if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "INVALID_IDEMPOTENCY_TTL" {
+			t.Errorf("TTL %q: got %d %s, want 400 INVALID_IDEMPOTENCY_TTL", v, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestCreateTransferReportsEveryFieldError(t *testing.T) {
+	h := newTestHandler(t, nil)
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":4,"to_account_id":4,"amount":0,"type":"bonus"}`, map[string]string{"Idempotency-Key": "k1"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got %d %s, want 422", rec.Code, rec.Body)
+	}
+	if codes := fieldCodes(t, rec); !slices.Equal(codes, []string{"INVALID_AMOUNT", "SELF_TRANSFER", "INVALID_TRANSFER_TYPE"}) {
+		t.Errorf("codes = %v", codes)
+	}
+	// The top level repeats the first issue for clients that predate the list.
+	body := errorBody(t, rec)
+	if body["code"] != "INVALID_AMOUNT" || body["error"] != "Amount must be positive" {
+		t.Errorf("top level = %v / %v, want the first field error", body["code"], body["error"])
+	}
+	fields, _ := body["errors"].([]any)
+	if len(fields) > 1 && fields[1].(map[string]any)["field"] != "to_account_id" {
+		t.Errorf("second error names field %v", fields[1].(map[string]any)["field"])
+	}
+}
+
+func TestEffectiveDateWindow(t *testing.T) {
+	h := newTestHandler(t, testConfig(t, map[string]string{"EFFECTIVE_DATE_WINDOW": "720h"}))
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	cases := []struct {
+		date string
+		ok   bool
+	}{
+		{date: "", ok: true},
+		{date: today.Format(domain.EffectiveDateLayout), ok: true},
+		{date: today.AddDate(0, 0, -30).Format(domain.EffectiveDateLayout), ok: true},
+		{date: today.AddDate(0, 0, 30).Format(domain.EffectiveDateLayout), ok: true},
+		{date: today.AddDate(0, 0, -31).Format(domain.EffectiveDateLayout)},
+		{date: today.AddDate(0, 0, 31).Format(domain.EffectiveDateLayout)},
+		{date: "2024-13-01"},
+		{date: today.Format(time.RFC3339)},
+	}
+	for _, tc := range cases {
+		var errs fieldErrors
+		h.checkEffectiveDate(&errs, "effective_date", tc.date)
+		if ok := len(errs) == 0; ok != tc.ok {
+			t.Errorf("%q: accepted = %v, want %v", tc.date, ok, tc.ok)
+		}
+	}
+
+	body := `{"from_account_id":1,"to_account_id":2,"amount":10,"effective_date":"` + today.AddDate(-1, 0, 0).Format(domain.EffectiveDateLayout) + `"}`
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", body, map[string]string{"Idempotency-Key": "k1"})
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "INVALID_EFFECTIVE_DATE" {
+		t.Fatalf("got %d %s, want 422 INVALID_EFFECTIVE_DATE", rec.Code, rec.Body)
+	}
+}
+
+func TestKeyWait(t *testing.T) {
+	h := newTestHandler(t, testConfig(t, map[string]string{"IDEMPOTENCY_MAX_WAIT": "3s"}))
+	cases := []struct {
+		prefer []string
+		want   time.Duration
+	}{
+		{want: 0},
+		{prefer: []string{"respond-async"}, want: 0},
+		{prefer: []string{"wait"}, want: 3 * time.Second},
+		{prefer: []string{"wait=1"}, want: time.Second},
+		{prefer: []string{"wait=60"}, want: 3 * time.Second},
+		{prefer: []string{"wait=soon"}, want: 3 * time.Second},
+		{prefer: []string{"respond-async, Wait=2"}, want: 2 * time.Second},
+		{prefer: []string{"return=minimal", "wait=0"}, want: 0},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest("POST", "/api/v1/transfers", nil)
+		for _, v := range tc.prefer {
+			r.Header.Add("Prefer", v)
+		}
+		if got := h.keyWait(r); got != tc.want {
+			t.Errorf("Prefer %q: wait %s, want %s", tc.prefer, got, tc.want)
+		}
+	}
+}
+
+func TestIfMatchParsed(t *testing.T) {
+	cases := []struct {
+		header  string
+		version int64
+		ok      bool
+	}{
+		{header: `"7"`, version: 7, ok: true},
+		{header: `7`, version: 7, ok: true},
+		{header: ` "12" `, version: 12, ok: true},
+		{header: `W/"7"`},
+		{header: `"0"`},
+		{header: `"-1"`},
+		{header: `*`},
+		{header: `"abc"`},
+	}
+	for _, tc := range cases {
+		version, ok := parseVersionETag(tc.header)
+		if ok != tc.ok || (ok && version != tc.version) {
+			t.Errorf("parseVersionETag(%q) = %d, %v; want %d, %v", tc.header, version, ok, tc.version, tc.ok)
+		}
+	}
+
+	h := newTestHandler(t, nil)
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":10}`, map[string]string{"Idempotency-Key": "k1", "If-Match": "*"})
+	if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "INVALID_IF_MATCH" {
+		t.Fatalf("got %d %s, want 400 INVALID_IF_MATCH", rec.Code, rec.Body)
+	}
+}
+
+// Entries are only ever served a page at a time; a client cannot ask for
+// more than maxPageSize.
+func TestEntriesLimitBounded(t *testing.T) {
+	h := newTestHandler(t, nil)
+	for _, limit := range []string{"0", "-1", strconv.Itoa(maxPageSize + 1), "all"} {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/accounts/1/entries?limit="+limit, nil), map[string]string{"id": "1"})
+		rec := httptest.NewRecorder()
+		h.GetAccountEntries(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: got %d %s, want 400", limit, rec.Code, rec.Body)
+		}
+	}
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v1/accounts/1/entries?cursor=garbage", nil), map[string]string{"id": "1"})
+	rec := httptest.NewRecorder()
+	h.GetAccountEntries(rec, req)
+	if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "INVALID_CURSOR" {
+		t.Errorf("bad cursor: got %d %s, want 400 INVALID_CURSOR", rec.Code, rec.Body)
+	}
+}
+
+func TestBatchGetIDsBounded(t *testing.T) {
+	h := newTestHandler(t, nil)
+	tooMany := make([]string, maxBatchGetIDs+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+	for _, body := range []string{`{"ids":[]}`, `{}`, `{"ids":[` + strings.Join(tooMany, ",") + `]}`} {
+		rec := serve(h.BatchGetTransfers, "POST", "/api/v1/transfers/batch-get", body, nil)
+		if rec.Code != http.StatusUnprocessableEntity || !slices.Equal(fieldCodes(t, rec), []string{"INVALID_IDS"}) {
+			t.Errorf("%.40s: got %d %s, want 422 INVALID_IDS", body, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestRecomputeAllCursorParsed(t *testing.T) {
+	h := newTestHandler(t, nil)
+	for _, after := range []string{"-1", "x", "1.5"} {
+		rec := serve(h.RecomputeAll, "POST", "/api/v1/admin/recompute-all?after="+after, "", nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("after=%s: got %d %s, want 400", after, rec.Code, rec.Body)
+		}
+	}
+	t.Setenv("RECOMPUTE_CHUNK", "0")
+	if _, err := config.Load(); err == nil {
+		t.Error("RECOMPUTE_CHUNK=0 accepted")
+	}
+}
@@ -0,0 +1,59 @@
+//go:build integration
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// HEAD runs the GET handler; the server drops the body but keeps the status
+// and headers.
+func TestHeadAccount(t *testing.T) {
+	h, s := newStoreHandler(t, nil)
+	a := store.SeedAccount(t, s, 100)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/accounts/{id}", h.GetAccount).Methods("GET", "HEAD")
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	get, err := http.Get(fmt.Sprintf("%s/api/v1/accounts/%d", srv.URL, a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get.Body.Close()
+
+	cases := []struct {
+		name   string
+		id     int64
+		status int
+	}{
+		{"existing", a, http.StatusOK},
+		{"missing", a + 1, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Head(fmt.Sprintf("%s/api/v1/accounts/%d", srv.URL, tc.id))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status || len(body) != 0 {
+				t.Fatalf("got %d with %d body bytes, want %d and none", resp.StatusCode, len(body), tc.status)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if tc.status == http.StatusOK && resp.Header.Get("ETag") != get.Header.Get("ETag") {
+				t.Errorf("ETag = %q, GET gave %q", resp.Header.Get("ETag"), get.Header.Get("ETag"))
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+//go:build integration
+
+package api
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// newStoreHandler builds a Handler on a fresh test store. It skips the test
+// when TEST_DB_SOURCE is unset, like store.NewTestStore.
+func newStoreHandler(t *testing.T, cfg *config.Config, validators ...validation.TransferValidator) (*Handler, *store.LedgerStore) {
+	t.Helper()
+	s := store.NewTestStore(t)
+	if cfg == nil {
+		cfg = testConfig(t, nil)
+	}
+	return NewHandler(s, cfg, prometheus.NewRegistry(), RawSHA256{}, validators...), s
+}
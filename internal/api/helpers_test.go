@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/config"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+// testConfig loads the real defaults with env applied on top, the same way
+// the server does at startup.
+func testConfig(t *testing.T, env map[string]string) *config.Config {
+	t.Helper()
+	t.Setenv("DB_SOURCE", "postgres://unused")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return cfg
+}
+
+// newTestHandler builds a Handler with no database behind it, for paths
+// that answer before reaching the database. Its store has seen no keys.
+func newTestHandler(t *testing.T, cfg *config.Config, validators ...validation.TransferValidator) *Handler {
+	t.Helper()
+	if cfg == nil {
+		cfg = testConfig(t, nil)
+	}
+	s := store.NewLedgerStore(nil, store.WithIdempotencyStore(noKeys{}))
+	return NewHandler(s, cfg, prometheus.NewRegistry(), RawSHA256{}, validators...)
+}
+
+// noKeys is an IdempotencyStore that has never seen a key. Only Lookup is
+// implemented; the rest would need a database.
+type noKeys struct{ store.IdempotencyStore }
+
+func (noKeys) Lookup(context.Context, string, string, string) (json.RawMessage, error) {
+	return nil, nil
+}
+
+// serve runs one request through handler and returns the recorded response.
+func serve(handler http.HandlerFunc, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// errorBody decodes the standard error envelope.
+func errorBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", rec.Body.String(), err)
+	}
+	return body
+}
+
+// fieldCodes lists the codes of a 422's field errors, in order.
+func fieldCodes(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var codes []string
+	fields, _ := errorBody(t, rec)["errors"].([]any)
+	for _, f := range fields {
+		codes = append(codes, f.(map[string]any)["code"].(string))
+	}
+	return codes
+}
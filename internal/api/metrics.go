@@ -0,0 +1,202 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics holds the Prometheus collectors for the HTTP layer. Every metric
+// is registered with reg under the configured namespace.
+type Metrics struct {
+	httpReqTotal *prometheus.CounterVec
+	httpLatency  *prometheus.HistogramVec
+	httpInflight *prometheus.GaugeVec
+	httpTimeouts *prometheus.CounterVec
+
+	invariantViolations prometheus.Counter
+	panics              prometheus.Counter
+
+	// SLO burn rate: transfer_errors_total{class="server"} over
+	// transfers_total is the error ratio. For a 99.9% objective, page when
+	// it exceeds 14.4x the budget (1.44%) over both 1h and 5m, and ticket
+	// at 6x (0.6%) over both 6h and 30m. "contention" (409s, and lock
+	// contention when it is mapped to 503) is expected under hot-spot load
+	// and "client" errors are the caller's; neither burns the budget.
+	transfersTotal prometheus.Counter
+	transferErrors *prometheus.CounterVec
+}
+
+func NewMetrics(reg prometheus.Registerer, namespace string) *Metrics {
+	factory := promauto.With(reg)
+	return &Metrics{
+		httpReqTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total HTTP requests classified by status",
+		}, []string{"method", "endpoint", "status"}),
+
+		httpLatency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Request latency distribution",
+			Buckets:   []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1},
+		}, []string{"method", "endpoint"}),
+
+		httpInflight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_inflight_requests",
+			Help:      "Requests currently being served",
+		}, []string{"endpoint"}),
+
+		httpTimeouts: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_request_timeouts_total",
+			Help:      "Requests cut off with a 503 because the handler overran the request timeout",
+		}, []string{"method", "endpoint"}),
+
+		invariantViolations: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "invariant_violation_total",
+			Help:      "Writes rejected by the ledger invariant trigger; any increase is a bug",
+		}),
+
+		panics: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_total",
+			Help:      "Handler panics recovered and answered with a 500",
+		}),
+
+		transfersTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_total",
+			Help:      "Transfer requests answered, whatever the outcome",
+		}),
+
+		transferErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfer_errors_total",
+			Help:      "Failed transfer requests by class: client (4xx), server (5xx) or contention",
+		}, []string{"class"}),
+	}
+}
+
+// observeTransfer counts one answered transfer request. code is the error
+// code in the body, if any.
+func (m *Metrics) observeTransfer(status int, code string) {
+	m.transfersTotal.Inc()
+	if class := transferErrorClass(status, code); class != "" {
+		m.transferErrors.WithLabelValues(class).Inc()
+	}
+}
+
+func transferErrorClass(status int, code string) string {
+	switch {
+	case status == http.StatusConflict || code == "LOCK_CONTENTION":
+		return "contention"
+	case status >= 500:
+		return "server"
+	case status >= 400:
+		return "client"
+	}
+	return ""
+}
+
+// outcomeRecorder remembers the status and error code a handler answered
+// with. respondErrorCode fills in the code when writing through one.
+type outcomeRecorder struct {
+	http.ResponseWriter
+	status int
+	code   string
+}
+
+func (o *outcomeRecorder) WriteHeader(code int) {
+	o.status = code
+	o.ResponseWriter.WriteHeader(code)
+}
+
+func (o *outcomeRecorder) Unwrap() http.ResponseWriter { return o.ResponseWriter }
+
+// TrackInflight counts requests in flight per route. The route template is
+// used as the label (e.g. "/transfers/{id}"), keeping cardinality bounded.
+func (h *Handler) TrackInflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g := h.metrics.httpInflight.WithLabelValues(routeEndpoint(r))
+		g.Inc()
+		defer g.Dec() // deferred so a panicking handler can't leak the count
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeEndpoint is the matched route's template without the /api/v1
+// prefix, or "unmatched".
+func routeEndpoint(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return strings.TrimPrefix(tpl, "/api/v1")
+		}
+	}
+	return "unmatched"
+}
+
+// RouteLabels hands the matched route template to respondJSON, which then
+// labels http_requests_total with it instead of the endpoint the handler
+// passed, so a handler can never label by a concrete path. Mount it last
+// on each subrouter: the Timeout middleware's buffered writer hides any
+// writer installed outside it.
+func (h *Handler) RouteLabels(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&routeWriter{ResponseWriter: w, endpoint: routeEndpoint(r)}, r)
+	})
+}
+
+// routeWriter carries the route template to respondJSON.
+type routeWriter struct {
+	http.ResponseWriter
+	endpoint string
+}
+
+func (rw *routeWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+// metricEndpoint is the route template on w or any writer it wraps, or
+// fallback when no RouteLabels middleware ran.
+func metricEndpoint(w http.ResponseWriter, fallback string) string {
+	for {
+		switch t := w.(type) {
+		case *routeWriter:
+			return t.endpoint
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return fallback
+		}
+	}
+}
+
+// Recover turns a handler panic into a logged stack trace, a 500 JSON error
+// and a panics_total increment. Wrap the whole router with it so it covers
+// every middleware too. http.ErrAbortHandler is re-panicked: it is net/http's
+// signal to abort the response silently.
+func (h *Handler) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			h.metrics.panics.Inc()
+			log.Printf("ERROR panic serving %s %s: %v\n%s", r.Method, r.URL.Path, err, debug.Stack())
+			// The route is unknown out here; a fixed label keeps cardinality bounded.
+			h.respondError(w, http.StatusInternalServerError, "Internal server error", r.Method, "panic")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
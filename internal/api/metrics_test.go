@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+func TestMetricsNamespace(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg, "acme_ledger")
+	m.httpReqTotal.WithLabelValues("GET", "/accounts/{id}", "200").Inc()
+	m.httpLatency.WithLabelValues("GET", "/accounts/{id}").Observe(0.01)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) == 0 {
+		t.Fatal("nothing registered")
+	}
+	for _, f := range families {
+		if !strings.HasPrefix(f.GetName(), "acme_ledger_") {
+			t.Errorf("metric %s is outside the namespace", f.GetName())
+		}
+	}
+}
+
+func TestInvariantViolationCounted(t *testing.T) {
+	h := newTestHandler(t, nil)
+	rec := httptest.NewRecorder()
+	h.respondStoreError(rec, fmt.Errorf("%w: transfer 7", store.ErrInvariantViolation), "POST", "/transfers")
+	if rec.Code != http.StatusInternalServerError || errorBody(t, rec)["code"] != "INVARIANT_VIOLATION" {
+		t.Fatalf("got %d %s, want 500 INVARIANT_VIOLATION", rec.Code, rec.Body)
+	}
+	if got := testutil.ToFloat64(h.metrics.invariantViolations); got != 1 {
+		t.Errorf("invariant violations counted %v times, want 1", got)
+	}
+}
+
+func TestInflightGaugeReturnsToZero(t *testing.T) {
+	h := newTestHandler(t, nil)
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := mux.NewRouter()
+	r.Use(h.TrackInflight)
+	r.HandleFunc("/api/v1/transfers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})
+	r.HandleFunc("/api/v1/boom", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	srv := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() { recover() }() // stands in for net/http's own recovery
+		r.ServeHTTP(w, req)
+	})
+	gauge := func(endpoint string) float64 {
+		return testutil.ToFloat64(h.metrics.httpInflight.WithLabelValues(endpoint))
+	}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/api/v1/transfers/%d", i), nil))
+		}()
+		<-entered
+	}
+	if got := gauge("/transfers/{id}"); got != 3 {
+		t.Errorf("in flight = %v with three requests blocked, want 3 under one route label", got)
+	}
+	close(release)
+	wg.Wait()
+	if got := gauge("/transfers/{id}"); got != 0 {
+		t.Errorf("in flight = %v after the requests finished", got)
+	}
+
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/boom", nil))
+	if got := gauge("/boom"); got != 0 {
+		t.Errorf("in flight = %v after a panic", got)
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	h := newTestHandler(t, nil)
+	srv := h.Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		var m map[string]int
+		m["nil map"]++
+	}))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/transfers", nil))
+	if rec.Code != http.StatusInternalServerError || errorBody(t, rec)["error"] == nil {
+		t.Fatalf("got %d %s, want a 500 JSON error", rec.Code, rec.Body)
+	}
+	if got := testutil.ToFloat64(h.metrics.panics); got != 1 {
+		t.Errorf("panics counted %v times, want 1", got)
+	}
+
+	// http.ErrAbortHandler is net/http's way to abort a response; it must
+	// reach the server untouched, and isn't a bug to count.
+	abort := h.Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) }))
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler re-panicked", err)
+			}
+		}()
+		abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	if got := testutil.ToFloat64(h.metrics.panics); got != 1 {
+		t.Errorf("panics counted %v times after an abort, want still 1", got)
+	}
+}
+
+// Every way a transfer can fail lands in exactly one error class, and lock
+// contention is kept out of client and server so it never burns the SLO.
+func TestTransferErrorClass(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error  // answered through respondStoreError
+		env    string // CONTENTION_STATUS
+		status int
+		class  string
+	}{
+		{name: "insufficient funds", err: store.ErrFunds, class: "client"},
+		{name: "unknown account", err: store.ErrAccountNotFound, class: "client"},
+		{name: "key reused", err: store.ErrKeyMismatch, class: "client"},
+		{name: "below minimum balance", err: store.ErrBelowMinimumBalance, class: "client"},
+		{name: "precondition failed", err: store.ErrPreconditionFailed, class: "client"},
+		{name: "key in progress", err: store.ErrConflict, class: "contention"},
+		{name: "lock contention as 409", err: store.ErrLockContention, class: "contention"},
+		{name: "lock contention as 503", err: store.ErrLockContention, env: "503", class: "contention"},
+		{name: "invariant violation", err: store.ErrInvariantViolation, class: "server"},
+		{name: "timeout", err: context.DeadlineExceeded, class: "server"},
+		{name: "unexpected", err: errors.New("connection reset"), class: "server"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{}
+			if tc.env != "" {
+				env["CONTENTION_STATUS"] = tc.env
+			}
+			h := newTestHandler(t, testConfig(t, env))
+			rec := &outcomeRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+			h.respondStoreError(rec, tc.err, "POST", "/transfers")
+			h.metrics.observeTransfer(rec.status, rec.code)
+
+			if got := transferErrorClass(rec.status, rec.code); got != tc.class {
+				t.Errorf("%d %s classed %q, want %q", rec.status, rec.code, got, tc.class)
+			}
+			if got := testutil.ToFloat64(h.metrics.transferErrors.WithLabelValues(tc.class)); got != 1 {
+				t.Errorf("%s errors = %v, want 1", tc.class, got)
+			}
+			if got := testutil.ToFloat64(h.metrics.transfersTotal); got != 1 {
+				t.Errorf("transfers = %v, want 1", got)
+			}
+		})
+	}
+
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted} {
+		if got := transferErrorClass(status, ""); got != "" {
+			t.Errorf("%d classed %q, want no error", status, got)
+		}
+	}
+
+	// Requests refused before the store count too.
+	h := newTestHandler(t, nil)
+	serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":2,"amount":10}`, nil)
+	serve(h.CreateTransfer, "POST", "/api/v1/transfers", `{"from_account_id":1,"to_account_id":1,"amount":10}`, map[string]string{"Idempotency-Key": "k"})
+	if got := testutil.ToFloat64(h.metrics.transferErrors.WithLabelValues("client")); got != 2 {
+		t.Errorf("client errors = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(h.metrics.transfersTotal); got != 2 {
+		t.Errorf("transfers = %v, want 2", got)
+	}
+}
+
+// Two concrete account paths land in one series labelled by the route
+// template, even when the handler hands respondJSON the raw path.
+func TestRequestsLabelledByRouteTemplate(t *testing.T) {
+	h := newTestHandler(t, nil)
+	r := mux.NewRouter()
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.Use(h.RouteLabels)
+	v1.HandleFunc("/accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.respondJSON(w, http.StatusOK, map[string]string{"id": mux.Vars(r)["id"]}, "GET", r.URL.Path)
+	})
+	for _, path := range []string{"/api/v1/accounts/123", "/api/v1/accounts/456"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	if n := testutil.CollectAndCount(h.metrics.httpReqTotal); n != 1 {
+		t.Errorf("%d request series, want 1", n)
+	}
+	if got := testutil.ToFloat64(h.metrics.httpReqTotal.WithLabelValues("GET", "/accounts/{id}", "200")); got != 2 {
+		t.Errorf("/accounts/{id} counted %v requests, want 2", got)
+	}
+}
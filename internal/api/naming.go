@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// JSON key styles for responses. Requests are accepted in either.
+const (
+	NamingSnake = "snake"
+	NamingCamel = "camel"
+)
+
+// Objects under these keys hold caller-chosen keys, which are returned and
+// accepted exactly as stored.
+var verbatimKeys = map[string]bool{"metadata": true}
+
+// renameKeys re-encodes a JSON document with every object key passed
+// through rename, leaving verbatimKeys subtrees alone. Input that isn't a
+// single JSON value is returned unchanged so the caller's own decoding
+// reports the error. Object keys come out sorted.
+func renameKeys(p []byte, rename func(string) string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber() // keep amounts exactly as written
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return p
+	}
+	out, err := json.Marshal(renameValue(v, rename))
+	if err != nil {
+		return p
+	}
+	return out
+}
+
+func renameValue(v any, rename func(string) string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			name := rename(k)
+			if verbatimKeys[name] {
+				out[name] = val
+				continue
+			}
+			out[name] = renameValue(val, rename)
+		}
+		return out
+	case []any:
+		for i := range t {
+			t[i] = renameValue(t[i], rename)
+		}
+		return t
+	}
+	return v
+}
+
+// snakeToCamel turns from_account_id into fromAccountId.
+func snakeToCamel(k string) string {
+	if !strings.Contains(k, "_") {
+		return k
+	}
+	parts := strings.Split(k, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// camelToSnake turns fromAccountId into from_account_id. Keys that are
+// already snake_case pass through unchanged.
+func camelToSnake(k string) string {
+	var b strings.Builder
+	for i, r := range k {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unmarshalBody decodes a request body sent with either snake_case or
+// camelCase keys.
+func unmarshalBody(body []byte, dst any) error {
+	return json.Unmarshal(renameKeys(body, camelToSnake), dst)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestKeyCaseConversion(t *testing.T) {
+	cases := []struct{ snake, camel string }{
+		{"id", "id"},
+		{"from_account_id", "fromAccountId"},
+		{"receipt_signature", "receiptSignature"},
+		{"balance_after", "balanceAfter"},
+	}
+	for _, tc := range cases {
+		if got := snakeToCamel(tc.snake); got != tc.camel {
+			t.Errorf("snakeToCamel(%q) = %q, want %q", tc.snake, got, tc.camel)
+		}
+		if got := camelToSnake(tc.camel); got != tc.snake {
+			t.Errorf("camelToSnake(%q) = %q, want %q", tc.camel, got, tc.snake)
+		}
+		if got := camelToSnake(tc.snake); got != tc.snake {
+			t.Errorf("camelToSnake(%q) = %q, want it unchanged", tc.snake, got)
+		}
+	}
+}
+
+func TestRenameKeys(t *testing.T) {
+	in := `{"transfer":{"from_account_id":1,"amount":9007199254740993},"metadata":{"cost_center":"eu_ops"},"entries":[{"balance_after":5}]}`
+	want := `{"entries":[{"balanceAfter":5}],"metadata":{"cost_center":"eu_ops"},"transfer":{"amount":9007199254740993,"fromAccountId":1}}`
+	if got := string(renameKeys([]byte(in), snakeToCamel)); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	for _, bad := range []string{`{"a":`, `{"a":1} {"b":2}`, ``} {
+		if got := string(renameKeys([]byte(bad), snakeToCamel)); got != bad {
+			t.Errorf("renameKeys(%q) = %q, want it unchanged", bad, got)
+		}
+	}
+}
+
+// Requests parse the same whichever case their keys use.
+func TestUnmarshalBodyAcceptsEitherCase(t *testing.T) {
+	want := domain.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 10, EffectiveDate: "2026-10-15"}
+	for _, body := range []string{
+		`{"from_account_id":1,"to_account_id":2,"amount":10,"effective_date":"2026-10-15"}`,
+		`{"fromAccountId":1,"toAccountId":2,"amount":10,"effectiveDate":"2026-10-15"}`,
+		`{"fromAccountId":1,"to_account_id":2,"amount":10,"effectiveDate":"2026-10-15"}`,
+	} {
+		var got domain.TransferRequest
+		if err := unmarshalBody([]byte(body), &got); err != nil {
+			t.Errorf("%s: %v", body, err)
+			continue
+		}
+		if got.FromAccountID != want.FromAccountID || got.ToAccountID != want.ToAccountID || got.Amount != want.Amount || got.EffectiveDate != want.EffectiveDate {
+			t.Errorf("%s parsed as %+v", body, got)
+		}
+	}
+}
+
+func TestResponseNaming(t *testing.T) {
+	payload := domain.AccountVerification{AccountID: 3, StoredBalance: 10, ComputedBalance: 10, Consistent: true}
+	cases := []struct {
+		naming string
+		want   []string
+		absent []string
+	}{
+		{naming: NamingSnake, want: []string{`"account_id":3`, `"stored_balance":10`}, absent: []string{"accountId"}},
+		{naming: NamingCamel, want: []string{`"accountId":3`, `"storedBalance":10`, `"computedBalance":10`}, absent: []string{"account_id"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.naming, func(t *testing.T) {
+			h := newTestHandler(t, testConfig(t, map[string]string{"JSON_NAMING": tc.naming}))
+			rec := httptest.NewRecorder()
+			h.respondJSON(rec, http.StatusOK, payload, "GET", "/test")
+			for _, s := range tc.want {
+				if !strings.Contains(rec.Body.String(), s) {
+					t.Errorf("body %s lacks %s", rec.Body, s)
+				}
+			}
+			for _, s := range tc.absent {
+				if strings.Contains(rec.Body.String(), s) {
+					t.Errorf("body %s has %s", rec.Body, s)
+				}
+			}
+
+			// Camel input is accepted either way.
+			rec = serve(h.CreateAccount, "POST", "/api/v1/accounts", `{"initialBalance":-1}`, nil)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("camel request: got %d %s, want 422", rec.Code, rec.Body)
+			}
+		})
+	}
+}
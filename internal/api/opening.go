@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// maxOpeningBalances caps one import; it runs as a single transaction
+// holding a lock on every account in it.
+const maxOpeningBalances = 1000
+
+// ImportOpeningBalances records migrated opening balances as transfers from
+// the opening-balance equity account instead of bare balances. The
+// import_id in the body plays the part of an idempotency key. It is mounted
+// on the admin router only.
+func (h *Handler) ImportOpeningBalances(w http.ResponseWriter, r *http.Request) {
+	const endpoint = "/admin/opening-balances"
+	if h.equityAccountID == 0 {
+		h.respondError(w, http.StatusNotFound, "Opening-balance imports are not enabled", "POST", endpoint)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid body", "POST", endpoint)
+		return
+	}
+	var req domain.OpeningBalancesRequest
+	if err := unmarshalBody(body, &req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid JSON", "POST", endpoint)
+		return
+	}
+
+	var errs fieldErrors
+	if req.ImportID == "" {
+		errs.add("import_id", "IMPORT_ID_REQUIRED", "import_id is required")
+	}
+	switch {
+	case len(req.Balances) == 0:
+		errs.add("balances", "BALANCES_REQUIRED", "balances must not be empty")
+	case len(req.Balances) > maxOpeningBalances:
+		errs.add("balances", "TOO_MANY_BALANCES", fmt.Sprintf("balances must list at most %d accounts", maxOpeningBalances))
+	}
+	seen := make(map[int64]bool, len(req.Balances))
+	for i, b := range req.Balances {
+		field := fmt.Sprintf("balances[%d]", i)
+		switch {
+		case b.AccountID <= 0:
+			errs.add(field+".account_id", "INVALID_ACCOUNT_ID", "account_id must be positive")
+		case b.AccountID == h.equityAccountID:
+			errs.add(field+".account_id", "INVALID_ACCOUNT_ID", "account_id must not be the equity account")
+		case seen[b.AccountID]:
+			errs.add(field+".account_id", "DUPLICATE_ACCOUNT", fmt.Sprintf("account %d appears more than once", b.AccountID))
+		}
+		seen[b.AccountID] = true
+		if b.Amount <= 0 {
+			errs.add(field+".amount", "INVALID_AMOUNT", "amount must be positive")
+		}
+	}
+	if errs.respond(h, w, "POST", endpoint) {
+		return
+	}
+
+	res, err := h.store.ImportOpeningBalances(r.Context(), h.equityAccountID, req, h.requestHash(req, body))
+	if err != nil {
+		if errors.Is(err, store.ErrKeyMismatch) {
+			h.respondErrorCode(w, http.StatusUnprocessableEntity, "IMPORT_ID_REUSED", "import_id reused with a different request", "POST", endpoint)
+			return
+		}
+		h.respondStoreError(w, err, "POST", endpoint)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, res, "POST", endpoint)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpeningBalancesValidated(t *testing.T) {
+	off := newTestHandler(t, nil)
+	if rec := serve(off.ImportOpeningBalances, "POST", "/admin/opening-balances", `{}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("without an equity account: status = %d, want 404", rec.Code)
+	}
+
+	h := newTestHandler(t, testConfig(t, map[string]string{"OPENING_EQUITY_ACCOUNT_ID": "9"}))
+	many := make([]string, maxOpeningBalances+1)
+	for i := range many {
+		many[i] = fmt.Sprintf(`{"account_id":%d,"amount":1}`, i+10)
+	}
+
+	tests := []struct {
+		name, body string
+		want       []string
+	}{
+		{"no import id", `{"balances":[{"account_id":1,"amount":5}]}`, []string{"IMPORT_ID_REQUIRED"}},
+		{"no balances", `{"import_id":"r"}`, []string{"BALANCES_REQUIRED"}},
+		{"too many", `{"import_id":"r","balances":[` + strings.Join(many, ",") + `]}`, []string{"TOO_MANY_BALANCES"}},
+		{"equity account", `{"import_id":"r","balances":[{"account_id":9,"amount":5}]}`, []string{"INVALID_ACCOUNT_ID"}},
+		{"bad account and amount", `{"import_id":"r","balances":[{"account_id":0,"amount":0}]}`, []string{"INVALID_ACCOUNT_ID", "INVALID_AMOUNT"}},
+		{"duplicate", `{"import_id":"r","balances":[{"account_id":1,"amount":5},{"account_id":1,"amount":5}]}`, []string{"DUPLICATE_ACCOUNT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(h.ImportOpeningBalances, "POST", "/admin/opening-balances", tt.body, nil)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body)
+			}
+			if got := fieldCodes(t, rec); !slices.Equal(got, tt.want) {
+				t.Errorf("codes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if rec := serve(h.ImportOpeningBalances, "POST", "/admin/opening-balances", `{`, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed JSON: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestReceiptSignature(t *testing.T) {
+	signer := NewReceiptSigner([]byte(strings.Repeat("k", 32)))
+	transfer := domain.Transfer{
+		ID: 7, PublicID: "0b6f6c1e-8d4a-4d2e-9a51-3f1f7c2b9e10", FromAccountID: 1, ToAccountID: 2, Amount: 250,
+		Type: domain.TransferTypePayment, Status: "completed", EffectiveDate: "2026-10-15",
+		CreatedAt: time.Date(2026, 10, 15, 9, 30, 0, 123456000, time.UTC),
+	}
+	sig := signer.Sign(transfer)
+	if !signer.Verify(transfer, sig) {
+		t.Fatal("valid receipt did not verify")
+	}
+
+	// The same instant in another zone is the same receipt.
+	local := transfer
+	local.CreatedAt = transfer.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+	if !signer.Verify(local, sig) {
+		t.Error("receipt did not verify with created_at in another zone")
+	}
+
+	tampered := map[string]func(*domain.Transfer){
+		"amount":         func(t *domain.Transfer) { t.Amount = 2500 },
+		"recipient":      func(t *domain.Transfer) { t.ToAccountID = 3 },
+		"sender":         func(t *domain.Transfer) { t.FromAccountID = 9 },
+		"type":           func(t *domain.Transfer) { t.Type = domain.TransferTypeRefund },
+		"effective date": func(t *domain.Transfer) { t.EffectiveDate = "2026-09-30" },
+		"created at":     func(t *domain.Transfer) { t.CreatedAt = t.CreatedAt.Add(time.Microsecond) },
+	}
+	for field, tamper := range tampered {
+		changed := transfer
+		tamper(&changed)
+		if signer.Verify(changed, sig) {
+			t.Errorf("receipt still verifies with a tampered %s", field)
+		}
+	}
+
+	flipped := []byte(sig)
+	flipped[0] ^= 1
+	for name, bad := range map[string]string{"flipped": string(flipped), "truncated": sig[:len(sig)-2], "not hex": "zz" + sig[2:], "empty": ""} {
+		if signer.Verify(transfer, bad) {
+			t.Errorf("%s signature verified", name)
+		}
+	}
+	if NewReceiptSigner([]byte(strings.Repeat("x", 32))).Verify(transfer, sig) {
+		t.Error("receipt verified under another key")
+	}
+}
+
+func TestReceiptOnlyOnCompletedTransfers(t *testing.T) {
+	signer := NewReceiptSigner([]byte(strings.Repeat("k", 32)))
+	for status, want := range map[string]bool{"completed": true, "pending": false, "failed": false} {
+		resp := &domain.TransferResponse{Transfer: domain.Transfer{ID: 1, Status: status}}
+		signer.sign(resp)
+		if got := resp.ReceiptSignature != ""; got != want {
+			t.Errorf("%s transfer signed = %v, want %v", status, got, want)
+		}
+	}
+
+	var off *ReceiptSigner
+	resp := &domain.TransferResponse{Transfer: domain.Transfer{ID: 1, Status: "completed"}}
+	off.sign(resp)
+	if resp.ReceiptSignature != "" {
+		t.Error("signed with signing off")
+	}
+}
+
+func TestVerifyReceiptRequests(t *testing.T) {
+	rec := serve(newTestHandler(t, nil).VerifyReceipt, "GET", "/api/v1/transfers/1/verify?signature=ab", "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("signing off: got %d %s, want 404", rec.Code, rec.Body)
+	}
+
+	h := newTestHandler(t, testConfig(t, map[string]string{"RECEIPT_SIGNING_KEY": strings.Repeat("k", 32)}))
+	rec = serve(h.VerifyReceipt, "GET", "/api/v1/transfers/1/verify", "", nil)
+	if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "SIGNATURE_REQUIRED" {
+		t.Errorf("no signature: got %d %s, want 400 SIGNATURE_REQUIRED", rec.Code, rec.Body)
+	}
+}
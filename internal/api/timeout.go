@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Timeout bounds every request to d. The deadline is on the request context,
+// so a handler waiting on the database has its transaction canceled and
+// rolled back. A handler that overruns anyway is cut off: the client gets a
+// 503 TIMEOUT at the deadline, counted in http_request_timeouts_total and
+// observed in the latency histogram, and whatever the handler writes later
+// is discarded. Responses are buffered until the handler returns, so
+// streaming handlers must be mounted on a router without this middleware.
+func (h *Handler) Timeout(d time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							p = fmt.Sprintf("%v\n\n%s", p, debug.Stack())
+						}
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p) // let Recover answer it on the serving goroutine
+			case <-done:
+				tw.flushTo(w)
+			case <-ctx.Done():
+				tw.abandon()
+				endpoint := routeEndpoint(r)
+				h.metrics.httpTimeouts.WithLabelValues(r.Method, endpoint).Inc()
+				h.metrics.httpLatency.WithLabelValues(r.Method, endpoint).Observe(time.Since(start).Seconds())
+				w.Header().Set("Retry-After", "1")
+				h.respondErrorCode(w, http.StatusServiceUnavailable, "TIMEOUT", "Request timed out", r.Method, endpoint)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response so it can be dropped if the deadline
+// wins. After abandon, writes succeed but go nowhere.
+type timeoutWriter struct {
+	mu        sync.Mutex
+	header    http.Header
+	buf       bytes.Buffer
+	status    int
+	abandoned bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.status == 0 {
+		tw.status = code
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	if tw.abandoned {
+		return len(p), nil
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) abandon() {
+	tw.mu.Lock()
+	tw.abandoned = true
+	tw.mu.Unlock()
+}
+
+// responseAbandoned reports whether w, or a writer it wraps, belongs to a
+// request Timeout has already answered. The 503 was counted then, so the
+// overrunning handler's own response must not be counted again.
+func responseAbandoned(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case *timeoutWriter:
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return t.abandoned
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// flushTo copies the finished response to w. Only called once the handler
+// has returned, so nothing else touches tw.
+func (tw *timeoutWriter) flushTo(w http.ResponseWriter) {
+	dst := w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	w.WriteHeader(tw.status)
+	w.Write(tw.buf.Bytes())
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// A handler that overruns the deadline gets a 503 sent on its behalf; its
+// late response is dropped and not counted a second time.
+func TestTimeoutSlowHandler(t *testing.T) {
+	h := newTestHandler(t, nil)
+	finished := make(chan struct{})
+	r := mux.NewRouter()
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.Use(h.Timeout(20*time.Millisecond), h.RouteLabels)
+	v1.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond) // overrun past the deadline
+		h.respondJSON(w, http.StatusOK, map[string]string{"late": "yes"}, "GET", "/slow")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || errorBody(t, rec)["code"] != "TIMEOUT" {
+		t.Fatalf("got %d %s, want 503 TIMEOUT", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After")
+	}
+
+	<-finished
+	if got := testutil.ToFloat64(h.metrics.httpTimeouts.WithLabelValues("GET", "/slow")); got != 1 {
+		t.Errorf("timeouts = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(h.metrics.httpReqTotal.WithLabelValues("GET", "/slow", "503")); got != 1 {
+		t.Errorf("503s counted = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(h.metrics.httpReqTotal.WithLabelValues("GET", "/slow", "200")); got != 0 {
+		t.Errorf("abandoned 200s counted = %v, want 0", got)
+	}
+	if strings.Contains(rec.Body.String(), "late") {
+		t.Errorf("late response leaked into the 503: %q", rec.Body)
+	}
+}
+
+func TestTimeoutFastHandler(t *testing.T) {
+	h := newTestHandler(t, nil)
+	r := mux.NewRouter()
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+	v1.Use(h.Timeout(time.Second), h.RouteLabels)
+	v1.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "kept")
+		h.respondJSON(w, http.StatusCreated, map[string]int{"id": 1}, "GET", "/fast")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/fast", nil))
+	if rec.Code != http.StatusCreated || rec.Header().Get("X-Test") != "kept" || rec.Body.String() != "{\"id\":1}\n" {
+		t.Fatalf("got %d %v %q", rec.Code, rec.Header(), rec.Body)
+	}
+	if got := testutil.ToFloat64(h.metrics.httpReqTotal.WithLabelValues("GET", "/fast", "201")); got != 1 {
+		t.Errorf("201s counted = %v, want 1", got)
+	}
+}
@@ -0,0 +1,382 @@
+//go:build integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
+)
+
+func transferBody(from, to, amount int64) string {
+	return fmt.Sprintf(`{"from_account_id":%d,"to_account_id":%d,"amount":%d}`, from, to, amount)
+}
+
+// A validator's rejection happens before the store is touched.
+func TestValidatorRejectionLeavesLedgerUntouched(t *testing.T) {
+	h, s := newStoreHandler(t, nil, validation.MaxAmount{Limit: 50})
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "over"})
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "AMOUNT_LIMIT_EXCEEDED" {
+		t.Fatalf("got %d %s, want 422 AMOUNT_LIMIT_EXCEEDED", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 1000)
+	store.AssertBalance(t, s, b, 0)
+
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 50), map[string]string{"Idempotency-Key": "within"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s, want 201", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 950)
+	store.AssertBalance(t, s, b, 50)
+	store.AssertInvariants(t, s)
+}
+
+func TestReplayedHeader(t *testing.T) {
+	h, s := newStoreHandler(t, nil)
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	key := map[string]string{"Idempotency-Key": "replay-me"}
+
+	for i, want := range []string{"false", "true"} {
+		rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("attempt %d: got %d %s", i+1, rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("Idempotency-Replayed"); got != want {
+			t.Errorf("attempt %d: Idempotency-Replayed = %q, want %q", i+1, got, want)
+		}
+	}
+	store.AssertBalance(t, s, a, 900)
+}
+
+// Retrying a completed key is not a new transfer, so it replays even once
+// the sender has used up its rate limit.
+func TestReplayNotRateLimited(t *testing.T) {
+	h, s := newStoreHandler(t, testConfig(t, map[string]string{"ACCOUNT_RATE_LIMIT": "1", "ACCOUNT_RATE_WINDOW": "1m"}))
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	key := map[string]string{"Idempotency-Key": "limited"}
+
+	if rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), key); rec.Code != http.StatusCreated {
+		t.Fatalf("first: got %d %s", rec.Code, rec.Body)
+	}
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("retry: got %d replayed=%s %s, want 201 replay", rec.Code, rec.Header().Get("Idempotency-Replayed"), rec.Body)
+	}
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "fresh"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("new key: got %d %s, want 429", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 900)
+}
+
+// A replay points Location at the transfer the first attempt created, never
+// at an empty or zero ID.
+func TestReplayLocation(t *testing.T) {
+	h, s := newStoreHandler(t, nil)
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/transfers/{id}", h.GetTransfer).Methods("GET")
+	key := map[string]string{"Idempotency-Key": "located"}
+
+	first := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+	replay := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), key)
+	if first.Code != http.StatusCreated || replay.Code != http.StatusCreated {
+		t.Fatalf("got %d then %d: %s", first.Code, replay.Code, replay.Body)
+	}
+	loc := replay.Header().Get("Location")
+	if loc == "" || loc == "/transfers/" || loc == "/transfers/0" || loc != first.Header().Get("Location") {
+		t.Fatalf("replay Location %q, first %q", loc, first.Header().Get("Location"))
+	}
+	got := httptest.NewRecorder()
+	r.ServeHTTP(got, httptest.NewRequest("GET", "/api/v1"+loc, nil))
+	var resp domain.TransferResponse
+	if err := json.Unmarshal(got.Body.Bytes(), &resp); err != nil || got.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", loc, got.Code, got.Body)
+	}
+	if resp.Transfer.ID == 0 || resp.Transfer.Amount != 100 {
+		t.Errorf("Location resolves to %+v", resp.Transfer)
+	}
+}
+
+// A requested TTL above IDEMPOTENCY_MAX_TTL is cut down to it, and once it
+// passes the key is reaped and free for a new request.
+func TestKeyTTLClampedAndReaped(t *testing.T) {
+	h, s := newStoreHandler(t, testConfig(t, map[string]string{"IDEMPOTENCY_MAX_TTL": "1s"}))
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	ctx := context.Background()
+
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "short", "Idempotency-Key-TTL": "3600"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "default"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	expired, err := s.ExpiredIdempotencyKeys(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].Key != "short" {
+		t.Fatalf("expired keys = %+v, want only the clamped one", expired)
+	}
+	if err := s.DeleteExpiredIdempotencyKey(ctx, expired[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reaped key runs a new transfer, even with a different body; the
+	// default-retention key still replays.
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 50), map[string]string{"Idempotency-Key": "short"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotency-Replayed") != "false" {
+		t.Fatalf("reused reaped key: got %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "default"})
+	if rec.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("default key no longer replays: %d %s", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 750)
+}
+
+// The created response is the representation GET Location returns, byte
+// for byte, so a client never needs to re-fetch.
+func TestCreateResponseMatchesGet(t *testing.T) {
+	h, s := newStoreHandler(t, nil)
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/transfers/{id}", h.GetTransfer).Methods("GET")
+
+	created := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "inline"})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("got %d %s", created.Code, created.Body)
+	}
+	loc := created.Header().Get("Location")
+	if loc == "" || created.Header().Get("Content-Location") != loc {
+		t.Fatalf("Location %q, Content-Location %q", loc, created.Header().Get("Content-Location"))
+	}
+
+	got := httptest.NewRecorder()
+	r.ServeHTTP(got, httptest.NewRequest("GET", "/api/v1"+loc, nil))
+	if got.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", loc, got.Code, got.Body)
+	}
+	if created.Body.String() != got.Body.String() {
+		t.Errorf("create and GET differ:\ncreate %s\nGET    %s", created.Body, got.Body)
+	}
+	if created.Header().Get("Cache-Control") != got.Header().Get("Cache-Control") {
+		t.Errorf("Cache-Control: create %q, GET %q", created.Header().Get("Cache-Control"), got.Header().Get("Cache-Control"))
+	}
+}
+
+// The signature a created transfer carries verifies against the stored
+// transfer; any other signature does not.
+func TestReceiptVerifiesAgainstStoredTransfer(t *testing.T) {
+	h, s := newStoreHandler(t, testConfig(t, map[string]string{"RECEIPT_SIGNING_KEY": strings.Repeat("k", 32)}))
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/transfers/{id}/verify", h.VerifyReceipt).Methods("GET")
+
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "receipt"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	var created domain.TransferResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ReceiptSignature == "" {
+		t.Fatal("created transfer carries no receipt_signature")
+	}
+
+	verify := func(sig string) bool {
+		t.Helper()
+		got := httptest.NewRecorder()
+		r.ServeHTTP(got, httptest.NewRequest("GET", "/api/v1/transfers/"+created.Transfer.PublicID+"/verify?signature="+sig, nil))
+		if got.Code != http.StatusOK {
+			t.Fatalf("verify: got %d %s", got.Code, got.Body)
+		}
+		var body struct{ Valid bool }
+		if err := json.Unmarshal(got.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Valid
+	}
+	if !verify(created.ReceiptSignature) {
+		t.Error("valid receipt rejected")
+	}
+	last := "0"
+	if strings.HasSuffix(created.ReceiptSignature, "0") {
+		last = "1"
+	}
+	if verify(created.ReceiptSignature[:len(created.ReceiptSignature)-1] + last) {
+		t.Error("tampered receipt accepted")
+	}
+}
+
+// A transfer conditioned on the sender's ETag is refused once another
+// transfer has touched the sender since the client read it.
+func TestConditionalTransferRejectedAfterBump(t *testing.T) {
+	h, s := newStoreHandler(t, nil)
+	a, b, c := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0), store.SeedAccount(t, s, 500)
+	etag := func() string {
+		t.Helper()
+		req := mux.SetURLVars(httptest.NewRequest("GET", fmt.Sprintf("/api/v1/accounts/%d", a), nil), map[string]string{"id": fmt.Sprint(a)})
+		rec := httptest.NewRecorder()
+		h.GetAccount(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+			t.Fatalf("GET account: %d %s, ETag %q", rec.Code, rec.Body, rec.Header().Get("ETag"))
+		}
+		return rec.Header().Get("ETag")
+	}
+
+	read := etag()
+	// Another client's transfer into the sender lands in between.
+	if rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(c, a, 50), map[string]string{"Idempotency-Key": "bump"}); rec.Code != http.StatusCreated {
+		t.Fatalf("bump: %d %s", rec.Code, rec.Body)
+	}
+	rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "stale", "If-Match": read})
+	if rec.Code != http.StatusPreconditionFailed || errorBody(t, rec)["code"] != "PRECONDITION_FAILED" {
+		t.Fatalf("stale If-Match: got %d %s, want 412 PRECONDITION_FAILED", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 1050)
+
+	// The refusal released the key: the same key with a fresh ETag goes through.
+	rec = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "stale", "If-Match": etag()})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("fresh If-Match: got %d %s, want 201", rec.Code, rec.Body)
+	}
+	if rec := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "plain"}); rec.Code != http.StatusCreated {
+		t.Fatalf("no If-Match: got %d %s, want 201", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 850)
+	store.AssertBalance(t, s, b, 200)
+	store.AssertInvariants(t, s)
+}
+
+// A chain exactly at MAX_CHAIN_HOPS runs; one more hop is refused and
+// moves nothing.
+func TestChainAtHopLimitRuns(t *testing.T) {
+	h, s := newStoreHandler(t, testConfig(t, map[string]string{"MAX_CHAIN_HOPS": "3"}))
+	a, b, c, d := store.SeedAccount(t, s, 100), store.SeedAccount(t, s, 0), store.SeedAccount(t, s, 0), store.SeedAccount(t, s, 0)
+	hop := func(from, to int64) string { return transferBody(from, to, 10) }
+
+	atLimit := `{"hops":[` + hop(a, b) + "," + hop(b, c) + "," + hop(c, d) + `]}`
+	if rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", atLimit, map[string]string{"Idempotency-Key": "three"}); rec.Code != http.StatusCreated {
+		t.Fatalf("3 hops: got %d %s, want 201", rec.Code, rec.Body)
+	}
+	over := `{"hops":[` + hop(a, b) + "," + hop(b, c) + "," + hop(c, d) + "," + hop(d, a) + `]}`
+	rec := serve(h.CreateChain, "POST", "/api/v1/transfers/chain", over, map[string]string{"Idempotency-Key": "four"})
+	if rec.Code != http.StatusUnprocessableEntity || errorBody(t, rec)["code"] != "TOO_MANY_LEGS" {
+		t.Fatalf("4 hops: got %d %s, want 422 TOO_MANY_LEGS", rec.Code, rec.Body)
+	}
+	store.AssertBalance(t, s, a, 90)
+	store.AssertBalance(t, s, d, 10)
+	store.AssertInvariants(t, s)
+}
+
+// A retry with its keys reordered replays under the parsed fingerprint and
+// is a different request under the raw one.
+func TestReorderedRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		hasher BodyFingerprinter
+		status int
+	}{
+		{"parsed", ParsedRequest{}, http.StatusCreated},
+		{"raw", RawSHA256{}, http.StatusUnprocessableEntity},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := store.NewTestStore(t)
+			h := NewHandler(s, testConfig(t, nil), prometheus.NewRegistry(), tc.hasher)
+			a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+			key := map[string]string{"Idempotency-Key": "reordered"}
+
+			first := serve(h.CreateTransfer, "POST", "/api/v1/transfers", fmt.Sprintf(`{"from_account_id":%d,"to_account_id":%d,"amount":100}`, a, b), key)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("first: got %d %s", first.Code, first.Body)
+			}
+			retry := serve(h.CreateTransfer, "POST", "/api/v1/transfers", fmt.Sprintf(`{"amount":100, "to_account_id":%d, "from_account_id":%d}`, b, a), key)
+			if retry.Code != tc.status {
+				t.Fatalf("retry: got %d %s, want %d", retry.Code, retry.Body, tc.status)
+			}
+			if tc.status == http.StatusCreated && (retry.Header().Get("Idempotency-Replayed") != "true" || retry.Body.String() != first.Body.String()) {
+				t.Errorf("retry was not a replay: %s", retry.Body)
+			}
+			store.AssertBalance(t, s, a, 900)
+		})
+	}
+}
+
+// A client holding only the key can find what it created and tell a
+// request still running from one that never happened.
+func TestTransferByKey(t *testing.T) {
+	idem := &gatedIdempotency{PostgresIdempotency: store.NewPostgresIdempotency(nil, 0)}
+	s := store.NewTestStore(t, store.WithIdempotencyStore(idem))
+	h := NewHandler(s, testConfig(t, nil), prometheus.NewRegistry(), RawSHA256{})
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/transfers/by-key/{key}", h.GetTransferByKey).Methods("GET")
+	lookup := func(key string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/transfers/by-key/"+key, nil))
+		return rec
+	}
+
+	// Completed: the same representation the POST returned.
+	idem.entered, idem.release = make(chan struct{}, 1), make(chan struct{})
+	close(idem.release)
+	created := serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": "done"})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", created.Code, created.Body)
+	}
+	got := lookup("done")
+	if got.Code != http.StatusOK {
+		t.Fatalf("completed key: %d %s", got.Code, got.Body)
+	}
+	var byKey, fromPost domain.TransferResponse
+	if err := json.Unmarshal(got.Body.Bytes(), &byKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(created.Body.Bytes(), &fromPost); err != nil {
+		t.Fatal(err)
+	}
+	if byKey.Transfer.ID == 0 || byKey.Transfer.ID != fromPost.Transfer.ID {
+		t.Errorf("by-key transfer %d, created %d", byKey.Transfer.ID, fromPost.Transfer.ID)
+	}
+
+	// In progress: held in Complete with the key still reserved.
+	idem.entered, idem.release = make(chan struct{}, 1), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 50), map[string]string{"Idempotency-Key": "running"})
+	}()
+	<-idem.entered
+	rec := lookup("running")
+	close(idem.release)
+	<-done
+	if rec.Code != http.StatusNotFound || errorBody(t, rec)["code"] != "KEY_IN_PROGRESS" {
+		t.Errorf("in-progress key: %d %s, want 404 KEY_IN_PROGRESS", rec.Code, rec.Body)
+	}
+	if rec := lookup("running"); rec.Code != http.StatusOK {
+		t.Errorf("after finishing: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := lookup("never-sent"); rec.Code != http.StatusNotFound || errorBody(t, rec)["code"] != "KEY_NOT_FOUND" {
+		t.Errorf("unknown key: %d %s, want 404 KEY_NOT_FOUND", rec.Code, rec.Body)
+	}
+}
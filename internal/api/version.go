@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// Response shapes selected by the Accept-Version header. v1 is the original
+// shape. v2 wraps successful bodies as {"data": ...} and renders money as
+// decimal strings of minor units, so JavaScript clients don't lose
+// precision above 2^53. Error bodies keep the same envelope in both.
+const (
+	apiV1 = 1
+	apiV2 = 2
+)
+
+// moneyKeys are the fields holding minor-unit amounts.
+var moneyKeys = map[string]bool{
+	"amount": true, "balance": true, "min_balance": true, "initial_balance": true,
+	"delta": true, "balance_after": true, "opening_balance": true, "closing_balance": true,
+	"held": true, "available": true, "outbound_amount": true, "inbound_amount": true,
+	"stored_balance": true, "computed_balance": true, "drift": true,
+	"total_credited": true, "total_debited": true,
+}
+
+// APIVersion reads Accept-Version ("1" or "2"; absent means 1) and rejects
+// any other value with 400. The version travels to respondJSON on the
+// response writer, since that is what every handler hands it.
+func (h *Handler) APIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Version")
+		switch r.Header.Get("Accept-Version") {
+		case "", "1":
+			next.ServeHTTP(w, r)
+		case "2":
+			next.ServeHTTP(&versionWriter{ResponseWriter: w, version: apiV2}, r)
+		default:
+			h.respondErrorCode(w, http.StatusBadRequest, "UNSUPPORTED_VERSION", "Accept-Version must be 1 or 2", r.Method, routeEndpoint(r))
+		}
+	})
+}
+
+// versionWriter marks a response as wanting a non-default shape.
+type versionWriter struct {
+	http.ResponseWriter
+	version int
+}
+
+func (v *versionWriter) Unwrap() http.ResponseWriter { return v.ResponseWriter }
+
+// responseVersion finds the version on w or any writer it wraps.
+func responseVersion(w http.ResponseWriter) int {
+	for {
+		switch t := w.(type) {
+		case *versionWriter:
+			return t.version
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return apiV1
+		}
+	}
+}
+
+// v2Body reshapes an encoded v1 body for v2. Bodies that don't decode are
+// returned unchanged.
+func v2Body(body []byte, status int) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	v = moneyStrings(v)
+	if status < 400 {
+		v = map[string]any{"data": v}
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func moneyStrings(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if verbatimKeys[k] {
+				continue
+			}
+			if n, ok := val.(json.Number); ok && moneyKeys[k] {
+				t[k] = n.String()
+				continue
+			}
+			t[k] = moneyStrings(val)
+		}
+	case []any:
+		for i := range t {
+			t[i] = moneyStrings(t[i])
+		}
+	}
+	return v
+}
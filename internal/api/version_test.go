@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestTransferResponseVersions(t *testing.T) {
+	resp := &domain.TransferResponse{
+		Transfer: domain.Transfer{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 9007199254740993, Type: domain.TransferTypePayment, Status: "completed", CreatedAt: time.Unix(0, 0).UTC()},
+		Entries: []domain.LedgerEntry{
+			{ID: 1, TransferID: 7, AccountID: 1, Delta: -9007199254740993, BalanceAfter: 0},
+			{ID: 2, TransferID: 7, AccountID: 2, Delta: 9007199254740993, BalanceAfter: 9007199254740993},
+		},
+	}
+	run := func(h *Handler, version string, respond func(http.ResponseWriter)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/transfers/7", nil)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		rec := httptest.NewRecorder()
+		h.APIVersion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { respond(w) })).ServeHTTP(rec, req)
+		return rec
+	}
+	h := newTestHandler(t, nil)
+	ok := func(w http.ResponseWriter) { h.respondJSON(w, http.StatusOK, resp, "GET", "/transfers/{id}") }
+	decode := func(rec *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		dec := json.NewDecoder(rec.Body)
+		dec.UseNumber()
+		var body map[string]any
+		if err := dec.Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	for _, version := range []string{"", "1"} {
+		rec := run(h, version, ok)
+		body := decode(rec)
+		transfer := body["transfer"].(map[string]any)
+		if transfer["amount"] != json.Number("9007199254740993") {
+			t.Errorf("v%q amount = %#v, want the bare number", version, transfer["amount"])
+		}
+		if _, wrapped := body["data"]; wrapped {
+			t.Errorf("v%q body is wrapped in data", version)
+		}
+		if rec.Header().Get("Vary") != "Accept-Version" {
+			t.Errorf("v%q Vary = %q", version, rec.Header().Get("Vary"))
+		}
+	}
+
+	body := decode(run(h, "2", ok))
+	data, wrapped := body["data"].(map[string]any)
+	if !wrapped || len(body) != 1 {
+		t.Fatalf("v2 body %v, want only a data envelope", body)
+	}
+	transfer := data["transfer"].(map[string]any)
+	if transfer["amount"] != "9007199254740993" || transfer["id"] != json.Number("7") {
+		t.Errorf("v2 transfer amount %#v id %#v, want the amount as a string and the id as a number", transfer["amount"], transfer["id"])
+	}
+	entry := data["entries"].([]any)[0].(map[string]any)
+	if entry["delta"] != "-9007199254740993" || entry["balance_after"] != "0" || entry["account_id"] != json.Number("1") {
+		t.Errorf("v2 entry %v", entry)
+	}
+
+	// Errors keep one envelope in both versions.
+	failed := run(h, "2", func(w http.ResponseWriter) {
+		h.respondErrorCode(w, http.StatusNotFound, "NOT_FOUND", "Transfer not found", "GET", "/transfers/{id}")
+	})
+	if got := decode(failed); got["code"] != "NOT_FOUND" || got["data"] != nil {
+		t.Errorf("v2 error %v, want the plain error envelope", got)
+	}
+
+	for _, version := range []string{"3", "v2", "latest"} {
+		rec := run(h, version, ok)
+		if rec.Code != http.StatusBadRequest || errorBody(t, rec)["code"] != "UNSUPPORTED_VERSION" {
+			t.Errorf("Accept-Version %q: got %d %s, want 400 UNSUPPORTED_VERSION", version, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//go:build integration
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// gatedIdempotency is PostgresIdempotency whose Complete reports that the
+// request got there and then holds it, key still in_progress, until
+// release is closed.
+type gatedIdempotency struct {
+	*store.PostgresIdempotency
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedIdempotency) Complete(ctx context.Context, tx pgx.Tx, key string, status int, resp any, transferIDs ...int64) error {
+	g.entered <- struct{}{}
+	<-g.release
+	return g.PostgresIdempotency.Complete(ctx, tx, key, status, resp, transferIDs...)
+}
+
+// A second identical request arriving while the first holds the key gets
+// the first one's response under "Prefer: wait", and a 409 without it.
+func TestPreferWaitReturnsInFlightResult(t *testing.T) {
+	idem := &gatedIdempotency{PostgresIdempotency: store.NewPostgresIdempotency(nil, 0)}
+	s := store.NewTestStore(t, store.WithIdempotencyStore(idem))
+	h := NewHandler(s, testConfig(t, map[string]string{"IDEMPOTENCY_MAX_WAIT": "5s"}), prometheus.NewRegistry(), RawSHA256{})
+	a, b := store.SeedAccount(t, s, 1000), store.SeedAccount(t, s, 0)
+
+	// race runs a first request up to Complete, starts the second while the
+	// key is held, then lets the first commit.
+	race := func(key string, second map[string]string) (first, dup *httptest.ResponseRecorder) {
+		idem.entered, idem.release = make(chan struct{}, 1), make(chan struct{})
+		firstDone, dupDone := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(firstDone)
+			first = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), map[string]string{"Idempotency-Key": key})
+		}()
+		<-idem.entered
+		second["Idempotency-Key"] = key
+		go func() {
+			defer close(dupDone)
+			dup = serve(h.CreateTransfer, "POST", "/api/v1/transfers", transferBody(a, b, 100), second)
+		}()
+		time.Sleep(50 * time.Millisecond) // let it block behind the first's key
+		close(idem.release)
+		<-firstDone
+		<-dupDone
+		return first, dup
+	}
+
+	first, dup := race("waited", map[string]string{"Prefer": "wait"})
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first: got %d %s", first.Code, first.Body)
+	}
+	if dup.Code != http.StatusCreated || dup.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("waiting duplicate: got %d replayed=%s %s, want 201 replay", dup.Code, dup.Header().Get("Idempotency-Replayed"), dup.Body)
+	}
+	if first.Body.String() != dup.Body.String() {
+		t.Errorf("bodies differ:\nfirst %s\ndup   %s", first.Body, dup.Body)
+	}
+	if first.Header().Get("Location") != dup.Header().Get("Location") {
+		t.Errorf("Location: first %q, duplicate %q", first.Header().Get("Location"), dup.Header().Get("Location"))
+	}
+
+	first, dup = race("not-waited", map[string]string{})
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first: got %d %s", first.Code, first.Body)
+	}
+	if dup.Code != http.StatusConflict {
+		t.Fatalf("duplicate without Prefer: wait: got %d %s, want 409", dup.Code, dup.Body)
+	}
+
+	// Each key moved money once.
+	store.AssertBalance(t, s, a, 800)
+	store.AssertBalance(t, s, b, 200)
+	store.AssertInvariants(t, s)
+}
@@ -0,0 +1,84 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/worker"
+)
+
+// Processor drains the queue of pending transfers with a worker pool. The
+// store claims each transfer with SKIP LOCKED, so overlapping batches are
+// harmless.
+type Processor struct {
+	store  *store.LedgerStore
+	poller *worker.Poller[int64]
+
+	depth   prometheus.Gauge
+	latency prometheus.Histogram
+}
+
+func NewProcessor(s *store.LedgerStore, reg prometheus.Registerer, namespace string, concurrency, batchSize int, interval time.Duration) *Processor {
+	factory := promauto.With(reg)
+	p := &Processor{
+		store: s,
+		depth: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "async_queue_depth",
+			Help:      "Transfers accepted asynchronously and still pending",
+		}),
+		latency: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "async_processing_seconds",
+			Help:      "Time from enqueue to settlement of async transfers",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 30},
+		}),
+	}
+	p.poller = &worker.Poller[int64]{
+		Name:        "async transfers",
+		Fetch:       p.fetch,
+		Process:     p.process,
+		Concurrency: concurrency,
+		BatchSize:   batchSize,
+		Interval:    interval,
+		MaxInterval: 10 * interval,
+		Drain:       true,
+	}
+	return p
+}
+
+// Run processes the queue until ctx is canceled.
+func (p *Processor) Run(ctx context.Context) {
+	p.poller.Run(ctx)
+}
+
+func (p *Processor) fetch(ctx context.Context, limit int) ([]int64, error) {
+	if n, err := p.store.PendingTransferCount(ctx); err == nil {
+		p.depth.Set(float64(n))
+	}
+	return p.store.PendingTransferIDs(ctx, limit)
+}
+
+func (p *Processor) process(ctx context.Context, id int64) error {
+	t, err := p.store.ProcessPendingTransfer(ctx, id)
+	if errors.Is(err, store.ErrConflict) {
+		return nil // lock contention: still pending, retried on a later poll
+	}
+	if err != nil {
+		return fmt.Errorf("transfer %d: %w", id, err)
+	}
+	if t == nil {
+		return nil // claimed by another worker or already settled
+	}
+	p.latency.Observe(time.Since(t.CreatedAt).Seconds())
+	if t.Status == "failed" {
+		log.Printf("async transfers: transfer %d failed: %s", t.ID, t.FailureReason)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package async
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/store"
+)
+
+// Enqueue, let the processor settle the queue, and poll each transfer until
+// it reaches a final status, as a client would.
+func TestProcessorSettlesQueue(t *testing.T) {
+	s := store.NewTestStore(t)
+	ctx := context.Background()
+	a, b := store.SeedAccount(t, s, 100), store.SeedAccount(t, s, 0)
+
+	enqueue := func(key string, amount int64) string {
+		t.Helper()
+		resp, err := s.EnqueueTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: amount}, key, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Transfer.Status != "pending" || len(resp.Entries) != 0 {
+			t.Fatalf("enqueued %+v, want pending with no entries", resp)
+		}
+		return strconv.FormatInt(resp.Transfer.ID, 10)
+	}
+	ok, short := enqueue("async-ok", 60), enqueue("async-short", 60) // only the first fits
+	store.AssertBalance(t, s, a, 100)
+
+	p := NewProcessor(s, prometheus.NewRegistry(), "test", 2, 10, 10*time.Millisecond)
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		p.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		stop()
+		<-done
+	}()
+
+	poll := func(ref string) *domain.Transfer {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			resp, err := s.GetTransfer(ctx, ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Transfer.Status != "pending" {
+				return &resp.Transfer
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("transfer %s still pending", ref)
+		return nil
+	}
+	if tr := poll(ok); tr.Status != "completed" {
+		t.Errorf("first transfer: %+v, want completed", tr)
+	}
+	if tr := poll(short); tr.Status != "failed" || tr.FailureReason != "insufficient_funds" {
+		t.Errorf("second transfer: %+v, want failed for insufficient funds", tr)
+	}
+	store.AssertBalance(t, s, a, 40)
+	store.AssertBalance(t, s, b, 60)
+	store.AssertInvariants(t, s)
+
+	if n := testutil.CollectAndCount(p.latency); n != 1 {
+		t.Errorf("latency histogram has %d series", n)
+	}
+}
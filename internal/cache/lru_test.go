@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[int, string](2, time.Minute)
+	c.Add(1, "a", c.Generation())
+	c.Add(2, "b", c.Generation())
+	c.Get(1) // 2 is now the least recently used
+	c.Add(3, "c", c.Generation())
+
+	if _, ok := c.Get(2); ok {
+		t.Error("least recently used entry survived")
+	}
+	for _, k := range []int{1, 3} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("entry %d evicted", k)
+		}
+	}
+}
+
+func TestLRUExpires(t *testing.T) {
+	clock := time.Unix(0, 0)
+	c := NewLRU[int, string](4, time.Second)
+	c.now = func() time.Time { return clock }
+	c.Add(1, "a", c.Generation())
+
+	clock = clock.Add(time.Second)
+	if _, ok := c.Get(1); !ok {
+		t.Error("expired at exactly the TTL")
+	}
+	clock = clock.Add(time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Error("served past the TTL")
+	}
+}
+
+func TestLRUInvalidate(t *testing.T) {
+	c := NewLRU[int, string](4, time.Minute)
+	c.Add(1, "old", c.Generation())
+	c.Add(2, "other", c.Generation())
+
+	// A reader that looked before the write must not cache what it read.
+	gen := c.Generation()
+	c.Invalidate(1)
+	if _, ok := c.Get(1); ok {
+		t.Error("invalidated entry still served")
+	}
+	if _, ok := c.Get(2); !ok {
+		t.Error("unrelated entry dropped")
+	}
+	c.Add(1, "stale", gen)
+	if v, ok := c.Get(1); ok {
+		t.Errorf("stale read cached as %q", v)
+	}
+	c.Add(1, "fresh", c.Generation())
+	if v, _ := c.Get(1); v != "fresh" {
+		t.Errorf("got %q, want fresh", v)
+	}
+}
@@ -2,13 +2,225 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	DBSource string
 	Port     string
 	Env      string
+
+	// AdminPort, when set, moves /metrics, /debug/pprof and the admin
+	// endpoints onto a second listener so they can be firewalled off. Unset
+	// keeps everything on Port.
+	AdminPort string
+
+	// BindAddress is the interface both listeners bind to: an IP literal or
+	// "localhost". The default 0.0.0.0 accepts traffic on every interface;
+	// 127.0.0.1 suits a sidecar that should only serve its own pod.
+	BindAddress string
+
+	// DBConnectAttempts and DBConnectTimeout bound the startup retry loop
+	// while waiting for Postgres to accept connections.
+	DBConnectAttempts int
+	DBConnectTimeout  time.Duration
+
+	// SlowQueryThreshold logs and counts (slow_queries_total) every query
+	// that takes at least this long, by operation. 0 disables it.
+	SlowQueryThreshold time.Duration
+
+	// AccountRateLimit caps how many transfers a single source account may
+	// initiate within AccountRateWindow. 0 disables the limit.
+	AccountRateLimit  int
+	AccountRateWindow time.Duration
+
+	// AccountConcurrency caps in-flight synchronous transfers touching any
+	// one account on this instance, shedding hot-spot load before it piles
+	// onto the same row locks. Excess transfers wait up to
+	// AccountConcurrencyWait for a slot, or are rejected at once with 429
+	// when it is 0. 0 disables the cap.
+	AccountConcurrency     int
+	AccountConcurrencyWait time.Duration
+
+	// RequestTimeout bounds every API request; TransferTimeout is the tighter
+	// bound for endpoints that lock accounts. Expiry cancels the transaction
+	// so it rolls back instead of holding locks, and a request still running
+	// at RequestTimeout gets a 503 regardless. 0 disables either.
+	RequestTimeout  time.Duration
+	TransferTimeout time.Duration
+
+	// MaxTransferAmount rejects single transfers above it. 0 disables the check.
+	MaxTransferAmount int64
+
+	// MaxChainHops and MaxChainAccounts bound a chain transfer: its hop
+	// count and the distinct accounts it locks. A chain holds every one of
+	// those row locks until it commits, and with NOWAIT each extra account
+	// is another chance to collide with a concurrent transfer, so abort
+	// rates climb with lock footprint. Oversized chains get a 422.
+	MaxChainHops     int
+	MaxChainAccounts int
+	// BlockedAccountIDs may neither send nor receive transfers.
+	BlockedAccountIDs []int64
+
+	// BlocklistBidirectional makes a blocked_pairs row forbid both directions.
+	BlocklistBidirectional bool
+	// BlocklistRefresh is how often the blocked_pairs cache is reloaded.
+	BlocklistRefresh time.Duration
+
+	// StatementMaxWindow caps the from/to range of an account statement.
+	StatementMaxWindow time.Duration
+
+	// EffectiveDateWindow bounds how far a transfer's effective_date may be
+	// from today, in either direction, so a typo can't file a transfer in a
+	// long-closed or far-future period.
+	EffectiveDateWindow time.Duration
+
+	// EscrowAccountID is the system account that holds escrowed funds.
+	// 0 disables the escrow endpoints.
+	EscrowAccountID int64
+
+	// TreasuryAccountID is the system account that funds and receives batch
+	// adjustments (interest, fees). 0 disables the batch-adjust endpoint.
+	// BatchAdjustChunk is how many accounts each adjustment transaction covers.
+	TreasuryAccountID int64
+	BatchAdjustChunk  int
+
+	// MaxReasonLength caps the audit reason an adjustment run must give.
+	MaxReasonLength int
+
+	// OpeningEquityAccountID is the opening-balance equity account that
+	// imported opening balances are transferred from; it is allowed to go
+	// negative. 0 disables the opening-balances endpoint.
+	OpeningEquityAccountID int64
+
+	// NodeID enables Snowflake transfer IDs when set (0-1023); each API
+	// instance needs a distinct value. -1 keeps the serial sequence.
+	NodeID int64
+
+	// AccountIDFloor, when positive, moves the account ID sequence up to at
+	// least this value at startup so IDs don't reveal how many accounts
+	// exist. It only obscures volume; it is not access control. The seeder
+	// honors the same variable.
+	AccountIDFloor int64
+
+	// AccountCacheSize enables an in-process LRU in front of GetAccount when
+	// positive. Writes through this instance invalidate it immediately;
+	// writes through other instances are only bounded by AccountCacheTTL.
+	AccountCacheSize int
+	AccountCacheTTL  time.Duration
+
+	// MetricsPrefix is the Prometheus namespace for every metric we export
+	// (e.g. "ledger" -> ledger_http_requests_total). Changing it renames all
+	// series, so dashboards and alerts must be updated with it.
+	MetricsPrefix string
+
+	// ProcessMetrics adds the Go runtime and process collectors (go_*,
+	// process_*) to /metrics. Off by default, so only our series show.
+	ProcessMetrics bool
+
+	// DriftCheckInterval is how often the next chunk of DriftCheckChunk
+	// accounts is verified against its ledger entries. 0 disables sampling;
+	// the on-demand verify endpoint works regardless.
+	DriftCheckInterval time.Duration
+	DriftCheckChunk    int
+
+	// RecomputeChunk is how many accounts the recompute-all endpoint lists
+	// per query while rewriting balances from their entries.
+	RecomputeChunk int
+
+	// TransferIsolation is the isolation level of write transactions:
+	// "repeatable-read" (default) or "serializable". Serialization failures
+	// are retried a few times either way.
+	TransferIsolation string
+
+	// ContentionStatus is the HTTP status (409 or 503) for a transfer that
+	// lost the race for an account lock. 503 with Retry-After reads as
+	// "retry later" to load balancers and generic clients; 409 suits
+	// clients that treat contention as a business conflict. An idempotency
+	// key still in progress is always 409. cmd/benchmark only counts 409s
+	// as aborts, so keep the default when comparing against past results.
+	ContentionStatus int
+
+	// Compression gzips responses of at least CompressionMinBytes for
+	// clients that accept it. /metrics is never compressed by it.
+	Compression         bool
+	CompressionMinBytes int
+
+	// RequireJSONContentType rejects mutating API requests whose body is not
+	// sent as application/json (optionally with charset=utf-8) with 415.
+	// Disable it for legacy clients that send no or a wrong Content-Type.
+	RequireJSONContentType bool
+
+	// ReceiptSigningKey, when set, HMAC-signs completed transfers and enables
+	// GET /transfers/{id}/verify. Anyone verifying a receipt offline needs
+	// the same key, so share it only with parties trusted to hold it.
+	ReceiptSigningKey string
+
+	// JSONNaming is the key style of response bodies: "snake" (the default,
+	// from_account_id) or "camel" (fromAccountId). Requests are accepted in
+	// either style regardless. Metadata keys are never renamed, and error
+	// "field" values stay snake_case.
+	JSONNaming string
+
+	// IdempotencyOptional lists endpoints (e.g. "/transfers") where a missing
+	// Idempotency-Key header gets a server-generated key instead of a 400.
+	// A generated key is unique per request, so client retries on those
+	// endpoints are NOT deduplicated and can execute twice. Only opt in for
+	// callers that never retry or can tolerate duplicates.
+	IdempotencyOptional []string
+
+	// IdempotencyMaxBodyBytes caps the transfer and chain responses stored
+	// for replay. Larger ones keep only their transfer IDs and are rebuilt
+	// from the ledger on replay. 0 stores every response.
+	IdempotencyMaxBodyBytes int
+
+	// IdempotencyFingerprint selects how request bodies are hashed for key
+	// reuse checks: "raw" (byte-exact), "canonical-json" (ignores key order
+	// and whitespace) or "parsed" (hashes the decoded request, so any body
+	// that means the same request matches). Changing it on a live system
+	// makes retries of earlier requests fail as key reuse until those keys
+	// expire.
+	IdempotencyFingerprint string
+
+	// idempotency_keys is partitioned by UTC day (the partition size is
+	// fixed at one day). IdempotencyPartitionsAhead days of partitions are
+	// kept pre-created, and whole partitions are dropped once their day is
+	// older than IdempotencyRetention (0 never drops). The maintenance pass
+	// runs every IdempotencyPartitionInterval. Retention should comfortably
+	// exceed the longest client retry window.
+	IdempotencyRetention         time.Duration
+	IdempotencyPartitionsAhead   int
+	IdempotencyPartitionInterval time.Duration
+
+	// IdempotencyMaxTTL caps the lifetime a client may request for a transfer
+	// key with the Idempotency-Key-TTL header (seconds); longer hints are
+	// clamped. Partitions are kept for at least this long so long-lived keys
+	// survive. Keys whose TTL has passed are deleted every
+	// IdempotencyReapInterval. Keys sent without the header use
+	// IdempotencyRetention.
+	IdempotencyMaxTTL       time.Duration
+	IdempotencyReapInterval time.Duration
+
+	// IdempotencyMaxWait caps how long a request sent with "Prefer: wait"
+	// waits for an in-flight request holding the same key before giving up
+	// with the usual 409. The route timeout still applies.
+	IdempotencyMaxWait time.Duration
+
+	// AsyncWorkers is the size of the pool settling transfers accepted with
+	// "Prefer: respond-async". 0 disables async mode; such requests are then
+	// served synchronously.
+	AsyncWorkers      int
+	AsyncPollInterval time.Duration
+	AsyncBatchSize    int
+}
+
+// ListenAddr joins BindAddress with port, bracketing IPv6 literals.
+func (c *Config) ListenAddr(port string) string {
+	return net.JoinHostPort(c.BindAddress, port)
 }
 
 func Load() (*Config, error) {
@@ -21,15 +233,429 @@ func Load() (*Config, error) {
 	if port == "" {
 		port = "8080"
 	}
+	adminPort := os.Getenv("ADMIN_PORT")
+	if adminPort != "" && adminPort == port {
+		return nil, fmt.Errorf("ADMIN_PORT must differ from SERVER_PORT")
+	}
+	bindAddress := os.Getenv("BIND_ADDRESS")
+	if bindAddress == "" {
+		bindAddress = "0.0.0.0"
+	}
+	if bindAddress != "localhost" && net.ParseIP(bindAddress) == nil {
+		return nil, fmt.Errorf("BIND_ADDRESS must be an IP address or localhost, got %q", bindAddress)
+	}
+	for _, p := range []string{port, adminPort} {
+		if p == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("SERVER_PORT and ADMIN_PORT must be port numbers (1-65535), got %q", p)
+		}
+	}
 
 	env := os.Getenv("ENVIRONMENT")
 	if env == "" {
 		env = "development"
 	}
 
+	connectAttempts, err := getEnvInt("DB_CONNECT_ATTEMPTS", 10)
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout, err := getEnvDuration("DB_CONNECT_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if connectAttempts < 1 || connectTimeout <= 0 {
+		return nil, fmt.Errorf("DB_CONNECT_ATTEMPTS must be >= 1 and DB_CONNECT_TIMEOUT must be positive")
+	}
+	slowQuery, err := getEnvDuration("SLOW_QUERY_THRESHOLD", 0)
+	if err != nil {
+		return nil, err
+	}
+	if slowQuery < 0 {
+		return nil, fmt.Errorf("SLOW_QUERY_THRESHOLD must not be negative")
+	}
+
+	rateLimit, err := getEnvInt("ACCOUNT_RATE_LIMIT", 0)
+	if err != nil {
+		return nil, err
+	}
+	rateWindow, err := getEnvDuration("ACCOUNT_RATE_WINDOW", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if rateLimit < 0 || rateWindow <= 0 {
+		return nil, fmt.Errorf("ACCOUNT_RATE_LIMIT must be >= 0 and ACCOUNT_RATE_WINDOW must be positive")
+	}
+	accountConcurrency, err := getEnvInt("ACCOUNT_CONCURRENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+	accountConcurrencyWait, err := getEnvDuration("ACCOUNT_CONCURRENCY_WAIT", 0)
+	if err != nil {
+		return nil, err
+	}
+	if accountConcurrency < 0 || accountConcurrencyWait < 0 {
+		return nil, fmt.Errorf("ACCOUNT_CONCURRENCY and ACCOUNT_CONCURRENCY_WAIT must not be negative")
+	}
+
+	requestTimeout, err := getEnvDuration("REQUEST_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	transferTimeout, err := getEnvDuration("TRANSFER_TIMEOUT", 3*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if requestTimeout < 0 || transferTimeout < 0 {
+		return nil, fmt.Errorf("REQUEST_TIMEOUT and TRANSFER_TIMEOUT must be >= 0")
+	}
+
+	maxAmount, err := getEnvInt64("MAX_TRANSFER_AMOUNT", 0)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := getEnvInt64List("BLOCKED_ACCOUNT_IDS")
+	if err != nil {
+		return nil, err
+	}
+	bidirectional, err := getEnvBool("BLOCKLIST_BIDIRECTIONAL", true)
+	if err != nil {
+		return nil, err
+	}
+	blocklistRefresh, err := getEnvDuration("BLOCKLIST_REFRESH_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if blocklistRefresh <= 0 {
+		return nil, fmt.Errorf("BLOCKLIST_REFRESH_INTERVAL must be positive")
+	}
+	statementWindow, err := getEnvDuration("STATEMENT_MAX_WINDOW", 366*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	effectiveWindow, err := getEnvDuration("EFFECTIVE_DATE_WINDOW", 90*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if effectiveWindow < 0 {
+		return nil, fmt.Errorf("EFFECTIVE_DATE_WINDOW must not be negative")
+	}
+	isolation := os.Getenv("TRANSFER_ISOLATION")
+	if isolation == "" {
+		isolation = "repeatable-read"
+	}
+	if isolation != "repeatable-read" && isolation != "serializable" {
+		return nil, fmt.Errorf("TRANSFER_ISOLATION must be repeatable-read or serializable")
+	}
+	contentionStatus, err := getEnvInt("CONTENTION_STATUS", 409)
+	if err != nil {
+		return nil, err
+	}
+	if contentionStatus != 409 && contentionStatus != 503 {
+		return nil, fmt.Errorf("CONTENTION_STATUS must be 409 or 503")
+	}
+	compression, err := getEnvBool("COMPRESSION_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	compressionMin, err := getEnvInt("COMPRESSION_MIN_BYTES", 1024)
+	if err != nil {
+		return nil, err
+	}
+	if compressionMin < 0 {
+		return nil, fmt.Errorf("COMPRESSION_MIN_BYTES must not be negative")
+	}
+	requireJSON, err := getEnvBool("REQUIRE_JSON_CONTENT_TYPE", true)
+	if err != nil {
+		return nil, err
+	}
+	jsonNaming := os.Getenv("JSON_NAMING")
+	if jsonNaming == "" {
+		jsonNaming = "snake"
+	}
+	if jsonNaming != "snake" && jsonNaming != "camel" {
+		return nil, fmt.Errorf("JSON_NAMING must be snake or camel")
+	}
+	receiptKey := os.Getenv("RECEIPT_SIGNING_KEY")
+	if receiptKey != "" && len(receiptKey) < 32 {
+		return nil, fmt.Errorf("RECEIPT_SIGNING_KEY must be at least 32 bytes")
+	}
+	maxHops, err := getEnvInt("MAX_CHAIN_HOPS", 50)
+	if err != nil {
+		return nil, err
+	}
+	maxChainAccounts, err := getEnvInt("MAX_CHAIN_ACCOUNTS", 50)
+	if err != nil {
+		return nil, err
+	}
+	if maxHops <= 0 || maxChainAccounts < 2 {
+		return nil, fmt.Errorf("MAX_CHAIN_HOPS must be positive and MAX_CHAIN_ACCOUNTS at least 2")
+	}
+	escrowAccount, err := getEnvInt64("ESCROW_ACCOUNT_ID", 0)
+	if err != nil {
+		return nil, err
+	}
+	treasuryAccount, err := getEnvInt64("TREASURY_ACCOUNT_ID", 0)
+	if err != nil {
+		return nil, err
+	}
+	adjustChunk, err := getEnvInt("BATCH_ADJUST_CHUNK", 500)
+	if err != nil {
+		return nil, err
+	}
+	if adjustChunk <= 0 {
+		return nil, fmt.Errorf("BATCH_ADJUST_CHUNK must be positive")
+	}
+	maxReason, err := getEnvInt("MAX_REASON_LENGTH", 500)
+	if err != nil {
+		return nil, err
+	}
+	if maxReason <= 0 {
+		return nil, fmt.Errorf("MAX_REASON_LENGTH must be positive")
+	}
+	equityAccount, err := getEnvInt64("OPENING_EQUITY_ACCOUNT_ID", 0)
+	if err != nil {
+		return nil, err
+	}
+	nodeID, err := getEnvInt64("NODE_ID", -1)
+	if err != nil {
+		return nil, err
+	}
+	idFloor, err := getEnvInt64("ACCOUNT_ID_FLOOR", 0)
+	if err != nil {
+		return nil, err
+	}
+	if idFloor < 0 {
+		return nil, fmt.Errorf("ACCOUNT_ID_FLOOR must not be negative")
+	}
+	cacheSize, err := getEnvInt("ACCOUNT_CACHE_SIZE", 0)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, err := getEnvDuration("ACCOUNT_CACHE_TTL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	metricsPrefix := os.Getenv("METRICS_PREFIX")
+	if metricsPrefix == "" {
+		metricsPrefix = "ledger"
+	}
+	processMetrics, err := getEnvBool("PROCESS_METRICS", false)
+	if err != nil {
+		return nil, err
+	}
+	driftInterval, err := getEnvDuration("DRIFT_CHECK_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	driftChunk, err := getEnvInt("DRIFT_CHECK_CHUNK", 500)
+	if err != nil {
+		return nil, err
+	}
+	if driftChunk <= 0 {
+		return nil, fmt.Errorf("DRIFT_CHECK_CHUNK must be positive")
+	}
+	recomputeChunk, err := getEnvInt("RECOMPUTE_CHUNK", 500)
+	if err != nil {
+		return nil, err
+	}
+	if recomputeChunk <= 0 {
+		return nil, fmt.Errorf("RECOMPUTE_CHUNK must be positive")
+	}
+	var idemOptional []string
+	if v := os.Getenv("IDEMPOTENCY_OPTIONAL_ENDPOINTS"); v != "" {
+		for _, e := range strings.Split(v, ",") {
+			idemOptional = append(idemOptional, strings.TrimSpace(e))
+		}
+	}
+	idemMaxBody, err := getEnvInt("IDEMPOTENCY_MAX_BODY_BYTES", 64<<10)
+	if err != nil {
+		return nil, err
+	}
+	if idemMaxBody < 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_MAX_BODY_BYTES must be >= 0")
+	}
+	idemFingerprint := os.Getenv("IDEMPOTENCY_FINGERPRINT")
+	if idemFingerprint == "" {
+		idemFingerprint = "raw"
+	}
+	idemRetention, err := getEnvDuration("IDEMPOTENCY_RETENTION", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	idemAhead, err := getEnvInt("IDEMPOTENCY_PARTITIONS_AHEAD", 7)
+	if err != nil {
+		return nil, err
+	}
+	idemPartitionInterval, err := getEnvDuration("IDEMPOTENCY_PARTITION_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if idemRetention < 0 || idemAhead < 1 || idemPartitionInterval <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_RETENTION must be >= 0, IDEMPOTENCY_PARTITIONS_AHEAD >= 1 and IDEMPOTENCY_PARTITION_INTERVAL positive")
+	}
+	idemMaxTTL, err := getEnvDuration("IDEMPOTENCY_MAX_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	idemReapInterval, err := getEnvDuration("IDEMPOTENCY_REAP_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if idemMaxTTL <= 0 || idemReapInterval <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_MAX_TTL and IDEMPOTENCY_REAP_INTERVAL must be positive")
+	}
+	idemMaxWait, err := getEnvDuration("IDEMPOTENCY_MAX_WAIT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if idemMaxWait < 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_MAX_WAIT must not be negative")
+	}
+	asyncWorkers, err := getEnvInt("ASYNC_WORKERS", 4)
+	if err != nil {
+		return nil, err
+	}
+	asyncPoll, err := getEnvDuration("ASYNC_POLL_INTERVAL", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	asyncBatch, err := getEnvInt("ASYNC_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	if asyncWorkers < 0 || asyncPoll <= 0 || asyncBatch <= 0 {
+		return nil, fmt.Errorf("ASYNC_WORKERS must be >= 0; ASYNC_POLL_INTERVAL and ASYNC_BATCH_SIZE must be positive")
+	}
+
 	return &Config{
 		DBSource: dbSource,
 		Port:     port,
 		Env:      env,
+
+		AdminPort:   adminPort,
+		BindAddress: bindAddress,
+
+		DBConnectAttempts:  connectAttempts,
+		DBConnectTimeout:   connectTimeout,
+		SlowQueryThreshold: slowQuery,
+
+		AccountRateLimit:  rateLimit,
+		AccountRateWindow: rateWindow,
+
+		AccountConcurrency:     accountConcurrency,
+		AccountConcurrencyWait: accountConcurrencyWait,
+
+		RequestTimeout:    requestTimeout,
+		TransferTimeout:   transferTimeout,
+		MaxTransferAmount: maxAmount,
+		MaxChainHops:      maxHops,
+		MaxChainAccounts:  maxChainAccounts,
+		BlockedAccountIDs: blocked,
+
+		BlocklistBidirectional:       bidirectional,
+		BlocklistRefresh:             blocklistRefresh,
+		StatementMaxWindow:           statementWindow,
+		EffectiveDateWindow:          effectiveWindow,
+		EscrowAccountID:              escrowAccount,
+		TreasuryAccountID:            treasuryAccount,
+		BatchAdjustChunk:             adjustChunk,
+		MaxReasonLength:              maxReason,
+		OpeningEquityAccountID:       equityAccount,
+		NodeID:                       nodeID,
+		AccountIDFloor:               idFloor,
+		AccountCacheSize:             cacheSize,
+		AccountCacheTTL:              cacheTTL,
+		MetricsPrefix:                metricsPrefix,
+		ProcessMetrics:               processMetrics,
+		DriftCheckInterval:           driftInterval,
+		DriftCheckChunk:              driftChunk,
+		RecomputeChunk:               recomputeChunk,
+		TransferIsolation:            isolation,
+		ContentionStatus:             contentionStatus,
+		Compression:                  compression,
+		CompressionMinBytes:          compressionMin,
+		RequireJSONContentType:       requireJSON,
+		ReceiptSigningKey:            receiptKey,
+		JSONNaming:                   jsonNaming,
+		IdempotencyOptional:          idemOptional,
+		IdempotencyMaxBodyBytes:      idemMaxBody,
+		IdempotencyFingerprint:       idemFingerprint,
+		IdempotencyRetention:         idemRetention,
+		IdempotencyPartitionsAhead:   idemAhead,
+		IdempotencyPartitionInterval: idemPartitionInterval,
+		IdempotencyMaxTTL:            idemMaxTTL,
+		IdempotencyReapInterval:      idemReapInterval,
+		IdempotencyMaxWait:           idemMaxWait,
+		AsyncWorkers:                 asyncWorkers,
+		AsyncPollInterval:            asyncPoll,
+		AsyncBatchSize:               asyncBatch,
 	}, nil
 }
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %v", key, err)
+	}
+	return n, nil
+}
+
+func getEnvInt64(key string, fallback int64) (int64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %v", key, err)
+	}
+	return n, nil
+}
+
+// getEnvInt64List parses a comma-separated list such as "1,2,3".
+func getEnvInt64List(key string) ([]int64, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return nil, nil
+	}
+	var out []int64
+	for _, part := range strings.Split(v, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma-separated list of integers: %v", key, err)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %v", key, err)
+	}
+	return b, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration (e.g. 500ms, 1m): %v", key, err)
+	}
+	return d, nil
+}
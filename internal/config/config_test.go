@@ -0,0 +1,73 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestBindAddress(t *testing.T) {
+	cases := []struct {
+		bind, port string
+		want       string // empty when Load must fail
+	}{
+		{bind: "", port: "", want: "0.0.0.0:8080"},
+		{bind: "127.0.0.1", port: "9000", want: "127.0.0.1:9000"},
+		{bind: "localhost", port: "9000", want: "localhost:9000"},
+		{bind: "::1", port: "9000", want: "[::1]:9000"},
+		{bind: "10.0.0.5", port: "", want: "10.0.0.5:8080"},
+		{bind: "example.com", port: "9000"},
+		{bind: "127.0.0.1:9000", port: "9000"},
+		{bind: "127.0.0.1", port: "70000"},
+		{bind: "127.0.0.1", port: "http"},
+	}
+	for _, tc := range cases {
+		t.Setenv("DB_SOURCE", "postgres://unused")
+		t.Setenv("BIND_ADDRESS", tc.bind)
+		t.Setenv("SERVER_PORT", tc.port)
+		cfg, err := Load()
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("BIND_ADDRESS=%q SERVER_PORT=%q accepted as %s", tc.bind, tc.port, cfg.ListenAddr(cfg.Port))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("BIND_ADDRESS=%q SERVER_PORT=%q: %v", tc.bind, tc.port, err)
+			continue
+		}
+		if got := cfg.ListenAddr(cfg.Port); got != tc.want {
+			t.Errorf("BIND_ADDRESS=%q SERVER_PORT=%q: listen on %s, want %s", tc.bind, tc.port, got, tc.want)
+		}
+	}
+}
+
+// A server built from the config listens only on the bound interface.
+func TestServerOnBindAddress(t *testing.T) {
+	t.Setenv("DB_SOURCE", "postgres://unused")
+	t.Setenv("BIND_ADDRESS", "127.0.0.1")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Addr: cfg.ListenAddr("0"), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	host, _, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil || host != "127.0.0.1" {
+		t.Fatalf("listening on %s, want 127.0.0.1", ln.Addr())
+	}
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got %d, want 200", resp.StatusCode)
+	}
+}
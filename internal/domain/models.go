@@ -2,47 +2,195 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// Account represents a user's balance in the ledger.
+// Account represents a user's balance in the ledger. Version counts edits
+// to Name, Metadata and Tags and guards them against lost updates.
+// BalanceVersion counts balance changes; it is the account's ETag and what
+// a transfer's If-Match is checked against.
 type Account struct {
-	ID        int64     `json:"id"`
-	Balance   int64     `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int64          `json:"id"`
+	Balance        int64          `json:"balance"`
+	MinBalance     int64          `json:"min_balance"`
+	Name           string         `json:"name,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Tags           []string       `json:"tags"`
+	Version        int64          `json:"version"`
+	BalanceVersion int64          `json:"balance_version"`
+	CreatedAt      time.Time      `json:"created_at"`
 }
 
+// AccountPage is one page of an account listing, in ID order. NextCursor is
+// empty on the last page.
+type AccountPage struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// CreateAccountRequest is the DTO for opening an account.
+type CreateAccountRequest struct {
+	InitialBalance int64          `json:"initial_balance"`
+	MinBalance     int64          `json:"min_balance,omitempty"` // reserve debits may not dip into
+	Name           string         `json:"name,omitempty"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+}
+
+// AccountUpdate edits an account's descriptive fields, never its balance.
+// Omitted fields are unchanged; an empty name, metadata object or tags
+// array clears it. Version must match the account's current version, which
+// also advances with every balance change.
+type AccountUpdate struct {
+	Name     *string        `json:"name"`
+	Metadata map[string]any `json:"metadata"`
+	Tags     []string       `json:"tags"`
+	Version  int64          `json:"version"`
+}
+
+// EffectiveDateLayout is the format of transfer effective dates.
+const EffectiveDateLayout = "2006-01-02"
+
 // TransferRequest is the DTO for incoming HTTP requests.
 type TransferRequest struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Type          string `json:"type,omitempty"` // defaults to TransferTypeTransfer
+
+	// EffectiveDate ("2006-01-02") back- or forward-dates the transfer for
+	// period reporting. Empty means today (UTC).
+	EffectiveDate string `json:"effective_date,omitempty"`
+
+	// Sweep is set by "amount": "all": the amount becomes the sender's whole
+	// balance, resolved under the account lock.
+	Sweep bool `json:"-"`
+
+	// IfSenderVersion, when positive, is the sender's balance version the
+	// client last saw (from the If-Match header). The transfer only runs if
+	// the version is unchanged under the lock.
+	IfSenderVersion int64 `json:"-"`
+}
+
+// ErrAmountFormat rejects an amount that is neither a JSON integer nor a
+// string of decimal digits.
+var ErrAmountFormat = errors.New(`amount must be an integer, optionally quoted, or "all"`)
+
+// UnmarshalJSON accepts the amount as a JSON number or as a quoted string
+// ("9007199254740993"), since many JSON clients lose precision above 2^53.
+// The string "all" requests a sweep.
+func (r *TransferRequest) UnmarshalJSON(data []byte) error {
+	type plain TransferRequest
+	aux := struct {
+		*plain
+		Amount json.RawMessage `json:"amount"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	raw := string(aux.Amount)
+	if raw == "" || raw == "null" {
+		r.Amount = 0
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(aux.Amount, &raw); err != nil {
+			return ErrAmountFormat
+		}
+		if raw == "all" {
+			r.Amount, r.Sweep = 0, true
+			return nil
+		}
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return ErrAmountFormat
+	}
+	r.Amount = n
+	return nil
+}
+
+// Transfer types classify transfers for reporting. The set is mirrored by
+// the transfers_type_check constraint.
+const (
+	TransferTypeTransfer   = "transfer"
+	TransferTypePayment    = "payment"
+	TransferTypeRefund     = "refund"
+	TransferTypeFee        = "fee"
+	TransferTypeAdjustment = "adjustment"
+)
+
+// ValidTransferType reports whether t is a known transfer type. The empty
+// string is valid and means TransferTypeTransfer.
+func ValidTransferType(t string) bool {
+	switch t {
+	case "", TransferTypeTransfer, TransferTypePayment, TransferTypeRefund, TransferTypeFee, TransferTypeAdjustment:
+		return true
+	}
+	return false
 }
 
 // Transfer represents the intent to move money.
 type Transfer struct {
 	ID            int64     `json:"id"`
+	PublicID      string    `json:"public_id"`
 	FromAccountID int64     `json:"from_account_id"`
 	ToAccountID   int64     `json:"to_account_id"`
 	Amount        int64     `json:"amount"`
+	Type          string    `json:"type"`
 	Status        string    `json:"status"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	EffectiveDate string    `json:"effective_date"` // period attribution; created_at is when it was recorded
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 // LedgerEntry represents one leg of a double-entry transaction.
 // The sum of Deltas for a given TransferID must always equal 0.
+// BalanceAfter is the account's balance immediately after this entry.
 type LedgerEntry struct {
-	ID         int64     `json:"id"`
-	TransferID int64     `json:"transfer_id"`
-	AccountID  int64     `json:"account_id"`
-	Delta      int64     `json:"delta"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	TransferID   int64     `json:"transfer_id"`
+	AccountID    int64     `json:"account_id"`
+	Delta        int64     `json:"delta"`
+	CreatedAt    time.Time `json:"created_at"`
+	BalanceAfter int64     `json:"balance_after"`
+
+	// TransferType is the parent transfer's type, joined in by the account
+	// statement and entries reads. Empty (and omitted) where the entry is
+	// already nested under its transfer.
+	TransferType string `json:"transfer_type,omitempty" db:"-"`
+	// EffectiveDate is likewise the parent transfer's, set by the statement.
+	EffectiveDate string `json:"effective_date,omitempty" db:"-"`
 }
 
 // TransferResponse is the canonical response structure for 201/200 OK.
 type TransferResponse struct {
 	Transfer Transfer      `json:"transfer"`
 	Entries  []LedgerEntry `json:"entries"`
+
+	// ReceiptSignature attests a completed transfer's fields when receipt
+	// signing is configured. It is added per response, never stored.
+	ReceiptSignature string `json:"receipt_signature,omitempty"`
+
+	// Replayed marks a response served from the idempotency cache.
+	Replayed bool `json:"-"`
+}
+
+// TransferBatchRequest asks for several transfers by internal ID at once.
+type TransferBatchRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// TransferBatch holds the transfers found, in request order, and the
+// requested IDs that matched nothing.
+type TransferBatch struct {
+	Transfers []TransferResponse `json:"transfers"`
+	NotFound  []int64            `json:"not_found"`
 }
 
 // IdempotencyPayload stores the response state for exact-once delivery.
@@ -51,3 +199,221 @@ type IdempotencyPayload struct {
 	ResponseBody   json.RawMessage `json:"response_body,omitempty"`
 	ResponseStatus int             `json:"response_status,omitempty"`
 }
+
+// BlockedPair is a compliance rule forbidding transfers from one account to another.
+type BlockedPair struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+// Statement is an account's activity over [From, To) bracketed by its
+// opening and closing balances.
+type Statement struct {
+	AccountID      int64         `json:"account_id"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	OpeningBalance int64         `json:"opening_balance"`
+	ClosingBalance int64         `json:"closing_balance"`
+	Entries        []LedgerEntry `json:"entries"`
+}
+
+// AccountEntries is an account's ledger, oldest entry first.
+type AccountEntries struct {
+	AccountID  int64         `json:"account_id"`
+	Entries    []LedgerEntry `json:"entries"`
+	NextCursor string        `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// EscrowRequest is the DTO for opening an escrow.
+type EscrowRequest struct {
+	BuyerAccountID  int64 `json:"buyer_account_id"`
+	SellerAccountID int64 `json:"seller_account_id"`
+	Amount          int64 `json:"amount"`
+}
+
+// Escrow tracks funds held in the system escrow account on behalf of a
+// buyer until they are released to the seller or refunded.
+type Escrow struct {
+	ID               int64     `json:"id"`
+	BuyerAccountID   int64     `json:"buyer_account_id"`
+	SellerAccountID  int64     `json:"seller_account_id"`
+	EscrowAccountID  int64     `json:"escrow_account_id"`
+	Amount           int64     `json:"amount"`
+	State            string    `json:"state"`
+	HoldTransferID   int64     `json:"hold_transfer_id"`
+	SettleTransferID *int64    `json:"settle_transfer_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TransferTrace is the support view of one transfer: the transfer and its
+// entries plus the idempotency key that created it and the escrow it moved
+// funds for, when they exist.
+type TransferTrace struct {
+	Transfer       Transfer           `json:"transfer"`
+	Entries        []LedgerEntry      `json:"entries"`
+	IdempotencyKey *IdempotencyRecord `json:"idempotency_key,omitempty"`
+	Escrow         *Escrow            `json:"escrow,omitempty"`
+}
+
+// IdempotencyRecord is an idempotency key row, without the stored body.
+type IdempotencyRecord struct {
+	Key            string     `json:"key"`
+	Operation      string     `json:"operation"`
+	Status         string     `json:"status"`
+	RequestHash    string     `json:"request_hash"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// EscrowResponse pairs the escrow with the transfer that moved its funds.
+type EscrowResponse struct {
+	Escrow   Escrow           `json:"escrow"`
+	Transfer TransferResponse `json:"transfer"`
+
+	// Replayed marks a response served from the idempotency cache.
+	Replayed bool `json:"-"`
+}
+
+// ChainRequest routes money through intermediaries. Hops execute in order
+// within one transaction; each hop may spend funds received by earlier hops.
+type ChainRequest struct {
+	Hops []TransferRequest `json:"hops"`
+}
+
+// ChainResponse lists the transfers created for each hop, in hop order.
+type ChainResponse struct {
+	TransferIDs []int64            `json:"transfer_ids"`
+	Transfers   []TransferResponse `json:"transfers"`
+
+	// Replayed marks a response served from the idempotency cache.
+	Replayed bool `json:"-"`
+}
+
+// TransferPage is one page of a transfer listing. NextCursor is empty on
+// the last page.
+type TransferPage struct {
+	Transfers  []Transfer `json:"transfers"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// AccountVerification compares an account's stored balance with the balance
+// implied by its opening balance plus ledger entries.
+type AccountVerification struct {
+	AccountID       int64 `json:"account_id"`
+	StoredBalance   int64 `json:"stored_balance"`
+	ComputedBalance int64 `json:"computed_balance"`
+	Drift           int64 `json:"drift"`
+	Consistent      bool  `json:"consistent"`
+}
+
+// ReconcileDiff is one page of drifted accounts. NextAfter, when non-zero,
+// is the after value for the next page.
+type ReconcileDiff struct {
+	Accounts  []AccountVerification `json:"accounts"`
+	NextAfter int64                 `json:"next_after,omitempty"`
+}
+
+// RecomputeResult reports a recompute-all pass. NextAfter, when non-zero,
+// is where the walk stopped; passing it back as after resumes it.
+type RecomputeResult struct {
+	Processed int64 `json:"processed"`
+	Corrected int64 `json:"corrected"`
+	NextAfter int64 `json:"next_after,omitempty"`
+}
+
+// AccountSummary aggregates an account's position and activity for
+// dashboards. Available is Balance less Held. Held is what holds keep back
+// from the balance; there are no holds yet, so it is always zero. MinBalance
+// is reported on its own and is not taken out of Available. The activity
+// totals cover completed entries, limited to the requested window if one
+// was given.
+type AccountSummary struct {
+	AccountID      int64      `json:"account_id"`
+	Balance        int64      `json:"balance"`
+	MinBalance     int64      `json:"min_balance"`
+	Held           int64      `json:"held"`
+	Available      int64      `json:"available"`
+	OutboundCount  int64      `json:"outbound_count"`
+	OutboundAmount int64      `json:"outbound_amount"`
+	InboundCount   int64      `json:"inbound_count"`
+	InboundAmount  int64      `json:"inbound_amount"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+}
+
+// Adjustment rule kinds.
+const (
+	AdjustFlat    = "flat"
+	AdjustPercent = "percent"
+)
+
+// AdjustmentRule computes one account's adjustment. A positive result
+// credits the account from the treasury (interest); a negative one debits it
+// to the treasury (fees).
+type AdjustmentRule struct {
+	Kind string `json:"kind"` // AdjustFlat or AdjustPercent
+	// Amount is the flat adjustment per account, in minor units.
+	Amount int64 `json:"amount,omitempty"`
+	// BasisPoints is the percentage of balance, in hundredths of a percent.
+	BasisPoints int64 `json:"basis_points,omitempty"`
+}
+
+// Apply returns the adjustment for an account holding balance. Percentages
+// truncate toward zero, so the treasury never pays out a fractional unit.
+func (r AdjustmentRule) Apply(balance int64) int64 {
+	if r.Kind == AdjustFlat {
+		return r.Amount
+	}
+	// Split the multiply so large balances can't overflow.
+	return balance/10000*r.BasisPoints + balance%10000*r.BasisPoints/10000
+}
+
+// BatchAdjustRequest applies Rule to every account carrying all of Tags (all
+// accounts when empty). RunID makes the run idempotent: repeating it skips
+// accounts already adjusted. Reason is required and is stored on every
+// transfer the run makes.
+type BatchAdjustRequest struct {
+	RunID  string         `json:"run_id"`
+	Reason string         `json:"reason"`
+	Rule   AdjustmentRule `json:"rule"`
+	Tags   []string       `json:"tags,omitempty"`
+	Type   string         `json:"type,omitempty"` // defaults to TransferTypeAdjustment
+}
+
+// BatchAdjustResult totals a run across every call made with its RunID.
+// Complete is false while accounts remain that were locked by other writers
+// when the run reached them; calling again with the same RunID picks them up.
+type BatchAdjustResult struct {
+	RunID            string `json:"run_id"`
+	Reason           string `json:"reason"`
+	AccountsAdjusted int64  `json:"accounts_adjusted"`
+	AccountsSkipped  int64  `json:"accounts_skipped"`
+	TotalCredited    int64  `json:"total_credited"`
+	TotalDebited     int64  `json:"total_debited"`
+	Complete         bool   `json:"complete"`
+	Replayed         bool   `json:"replayed"`
+}
+
+// OpeningBalance is one account's balance as carried over from the ledger
+// being migrated.
+type OpeningBalance struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// OpeningBalancesRequest imports opening balances as transfers from the
+// opening-balance equity account. ImportID makes the import idempotent.
+type OpeningBalancesRequest struct {
+	ImportID string           `json:"import_id"`
+	Balances []OpeningBalance `json:"balances"`
+}
+
+// OpeningBalancesResult totals an import. After every import the equity
+// account's balance is exactly minus the sum of all opening balances.
+type OpeningBalancesResult struct {
+	ImportID string `json:"import_id"`
+	Accounts int64  `json:"accounts"`
+	Total    int64  `json:"total"`
+	Replayed bool   `json:"replayed"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestAdjustmentRuleApply(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    AdjustmentRule
+		balance int64
+		want    int64
+	}{
+		{"flat credit", AdjustmentRule{Kind: AdjustFlat, Amount: 250}, 1000, 250},
+		{"flat fee ignores balance", AdjustmentRule{Kind: AdjustFlat, Amount: -75}, 0, -75},
+		{"percent interest", AdjustmentRule{Kind: AdjustPercent, BasisPoints: 150}, 20000, 300},
+		{"percent fee", AdjustmentRule{Kind: AdjustPercent, BasisPoints: -250}, 20000, -500},
+		{"percent truncates interest", AdjustmentRule{Kind: AdjustPercent, BasisPoints: 1}, 9999, 0},
+		{"percent truncates fee toward zero", AdjustmentRule{Kind: AdjustPercent, BasisPoints: -3}, 12345, -3},
+		{"full balance", AdjustmentRule{Kind: AdjustPercent, BasisPoints: 10000}, 4321, 4321},
+		{"no overflow near max", AdjustmentRule{Kind: AdjustPercent, BasisPoints: 10000}, 1 << 62, 1 << 62},
+	}
+	for _, tc := range cases {
+		if got := tc.rule.Apply(tc.balance); got != tc.want {
+			t.Errorf("%s: Apply(%d) = %d, want %d", tc.name, tc.balance, got, tc.want)
+		}
+	}
+}
+
+func TestTransferRequestAmount(t *testing.T) {
+	cases := []struct {
+		amount string
+		want   int64
+		sweep  bool
+		err    error
+	}{
+		{amount: `100`, want: 100},
+		{amount: `"100"`, want: 100},
+		{amount: `"9007199254740993"`, want: 9007199254740993}, // 2^53 + 1, lost as a float64
+		{amount: `9007199254740993`, want: 9007199254740993},
+		{amount: `"-5"`, want: -5}, // sign is the handler's to reject, with its own message
+		{amount: `"all"`, sweep: true},
+		{amount: `null`},
+		{amount: `"12.5"`, err: ErrAmountFormat},
+		{amount: `12.5`, err: ErrAmountFormat},
+		{amount: `"1e3"`, err: ErrAmountFormat},
+		{amount: `"ten"`, err: ErrAmountFormat},
+		{amount: `""`, err: ErrAmountFormat},
+		{amount: `"9223372036854775808"`, err: ErrAmountFormat}, // overflows int64
+		{amount: `true`, err: ErrAmountFormat},
+	}
+	for _, tc := range cases {
+		t.Run(tc.amount, func(t *testing.T) {
+			var r TransferRequest
+			err := json.Unmarshal([]byte(`{"from_account_id":1,"to_account_id":2,"amount":`+tc.amount+`}`), &r)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("err = %v, want %v", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Amount != tc.want || r.Sweep != tc.sweep || r.FromAccountID != 1 || r.ToAccountID != 2 {
+				t.Errorf("decoded %+v, want amount %d, sweep %v", r, tc.want, tc.sweep)
+			}
+		})
+	}
+}
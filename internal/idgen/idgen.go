@@ -0,0 +1,66 @@
+package idgen
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator hands out unique 64-bit IDs.
+type IDGenerator interface {
+	NextID() (int64, error)
+}
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	maxNode      = 1<<nodeBits - 1
+	maxSequence  = 1<<sequenceBits - 1
+)
+
+// Epoch is the zero point of Snowflake timestamps; 41 bits of milliseconds
+// from here last until 2093.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates IDs laid out as 41 bits of milliseconds since Epoch,
+// 10 bits of node ID and a 12-bit per-millisecond sequence. IDs from one
+// node are strictly increasing, even if the wall clock steps backwards.
+type Snowflake struct {
+	node int64
+
+	mu     sync.Mutex
+	lastMs int64
+	seq    int64
+	now    func() time.Time
+}
+
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > maxNode {
+		return nil, fmt.Errorf("node ID must be between 0 and %d, got %d", maxNode, node)
+	}
+	return &Snowflake{node: node, now: time.Now}, nil
+}
+
+func (g *Snowflake) NextID() (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(Epoch).Milliseconds()
+	if ms < 0 {
+		return 0, fmt.Errorf("clock is before the ID epoch")
+	}
+
+	switch {
+	case ms > g.lastMs:
+		g.lastMs, g.seq = ms, 0
+	case g.seq < maxSequence:
+		// Same millisecond, or the clock went backwards: stay on lastMs.
+		g.seq++
+	default:
+		// Sequence exhausted: borrow the next millisecond rather than spin.
+		g.lastMs++
+		g.seq = 0
+	}
+
+	return g.lastMs<<(nodeBits+sequenceBits) | g.node<<sequenceBits | g.seq, nil
+}
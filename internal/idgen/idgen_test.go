@@ -0,0 +1,86 @@
+package idgen
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnowflakeUniqueUnderConcurrency(t *testing.T) {
+	const workers, perWorker = 16, 5000
+	gens := []*Snowflake{}
+	for node := int64(0); node < 2; node++ {
+		g, err := NewSnowflake(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gens = append(gens, g)
+	}
+
+	ids := make(chan int64, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(g *Snowflake) {
+			defer wg.Done()
+			last := int64(-1)
+			for i := 0; i < perWorker; i++ {
+				id, err := g.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if id <= last {
+					t.Errorf("IDs from one goroutine went backwards: %d after %d", id, last)
+				}
+				last = id
+				ids <- id
+			}
+		}(gens[w%len(gens)])
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool, workers*perWorker)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSnowflakeClockEdgeCases(t *testing.T) {
+	clock := Epoch.Add(time.Hour)
+	g, _ := NewSnowflake(1)
+	g.now = func() time.Time { return clock }
+
+	first, _ := g.NextID()
+	clock = clock.Add(-time.Second) // NTP steps the clock back
+	second, _ := g.NextID()
+	if second <= first {
+		t.Errorf("ID after a clock step back = %d, want above %d", second, first)
+	}
+
+	// Exhaust the sequence within one millisecond; the next ID borrows the
+	// following millisecond instead of repeating.
+	var last int64
+	for i := 0; i <= maxSequence+1; i++ {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("ID %d not above %d at step %d", id, last, i)
+		}
+		last = id
+	}
+
+	g.now = func() time.Time { return Epoch.Add(-time.Millisecond) }
+	if _, err := g.NextID(); err == nil {
+		t.Error("no error for a clock before the epoch")
+	}
+	if _, err := NewSnowflake(maxNode + 1); err == nil {
+		t.Error("node ID out of range accepted")
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindow limits each key to a fixed number of events within a
+// rolling window. It keeps a log of event timestamps per key, so memory is
+// bounded by limit * active keys.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	events    map[int64][]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+		events: make(map[int64][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Allow records an event for key if it fits within the limit. When the key
+// is over the limit it returns false and how long until the oldest event
+// leaves the window.
+func (l *SlidingWindow) Allow(key int64) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+	l.sweep(now, cutoff)
+
+	log := prune(l.events[key], cutoff)
+	if len(log) >= l.limit {
+		l.events[key] = log
+		return false, log[0].Sub(cutoff)
+	}
+	l.events[key] = append(log, now)
+	return true, 0
+}
+
+// sweep drops keys that have gone idle so the map doesn't grow without bound.
+func (l *SlidingWindow) sweep(now, cutoff time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for k, log := range l.events {
+		if log = prune(log, cutoff); len(log) == 0 {
+			delete(l.events, k)
+		} else {
+			l.events[k] = log
+		}
+	}
+}
+
+func prune(log []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(log) && !log[i].After(cutoff) {
+		i++
+	}
+	return log[i:]
+}
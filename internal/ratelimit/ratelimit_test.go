@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	const n = 5
+	clock := time.Unix(1_000_000, 0)
+	l := NewSlidingWindow(n, time.Minute)
+	l.now = func() time.Time { return clock }
+
+	for i := 0; i < n; i++ {
+		if ok, _ := l.Allow(1); !ok {
+			t.Fatalf("transfer %d of %d rejected", i+1, n)
+		}
+		clock = clock.Add(time.Second)
+	}
+	ok, retry := l.Allow(1)
+	if ok {
+		t.Fatalf("transfer %d within the window allowed", n+1)
+	}
+	// The first event, at t=0, leaves the window at t=60s; it is now t=5s.
+	if retry != 55*time.Second {
+		t.Errorf("retry after %s, want 55s", retry)
+	}
+	if ok, _ := l.Allow(2); !ok {
+		t.Error("another account was limited too")
+	}
+
+	clock = clock.Add(retry)
+	if ok, _ := l.Allow(1); !ok {
+		t.Error("still limited after the oldest transfer left the window")
+	}
+	if ok, _ := l.Allow(1); ok {
+		t.Error("two slots freed where one transfer left the window")
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// KeyedSemaphore caps how many holders each key may have at once. A key's
+// entry is dropped as soon as its last holder leaves, so memory is bounded
+// by the keys with work in flight.
+type KeyedSemaphore struct {
+	limit int
+
+	mu   sync.Mutex
+	keys map[int64]*slot
+}
+
+type slot struct {
+	tokens chan struct{}
+	refs   int // holders plus waiters; the entry is deleted at zero
+}
+
+func NewKeyedSemaphore(limit int) *KeyedSemaphore {
+	return &KeyedSemaphore{limit: limit, keys: make(map[int64]*slot)}
+}
+
+// Acquire takes a slot on every key. Keys are taken in ascending order, so
+// two callers can't deadlock each holding a key the other is waiting for.
+// With wait false a full key fails at once; otherwise Acquire waits until
+// ctx is done. On success release must be called exactly once; on failure
+// nothing is held.
+func (s *KeyedSemaphore) Acquire(ctx context.Context, wait bool, keys ...int64) (release func(), ok bool) {
+	sorted := append([]int64(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var held []int64
+	releaseAll := func() {
+		for _, k := range held {
+			s.leave(k, true)
+		}
+	}
+	for i, k := range sorted {
+		if i > 0 && k == sorted[i-1] {
+			continue
+		}
+		if !s.take(ctx, k, wait) {
+			releaseAll()
+			return nil, false
+		}
+		held = append(held, k)
+	}
+	return releaseAll, true
+}
+
+func (s *KeyedSemaphore) take(ctx context.Context, key int64, wait bool) bool {
+	s.mu.Lock()
+	sl := s.keys[key]
+	if sl == nil {
+		sl = &slot{tokens: make(chan struct{}, s.limit)}
+		s.keys[key] = sl
+	}
+	sl.refs++
+	s.mu.Unlock()
+
+	select {
+	case sl.tokens <- struct{}{}:
+		return true
+	default:
+	}
+	if wait {
+		select {
+		case sl.tokens <- struct{}{}:
+			return true
+		case <-ctx.Done():
+		}
+	}
+	s.leave(key, false)
+	return false
+}
+
+// leave drops one reference to key, returning its token if it held one.
+func (s *KeyedSemaphore) leave(key int64, holding bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sl := s.keys[key]
+	if holding {
+		<-sl.tokens
+	}
+	if sl.refs--; sl.refs == 0 {
+		delete(s.keys, key)
+	}
+}
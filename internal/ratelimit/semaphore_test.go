@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Saturating one account sheds everything past the cap while other
+// accounts carry on, and the account's entry is gone once it is idle.
+func TestKeyedSemaphoreShedsExcess(t *testing.T) {
+	const limit = 3
+	s := NewKeyedSemaphore(limit)
+	ctx := context.Background()
+
+	var inFlight, peak, admitted, shed atomic.Int64
+	gate := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, ok := s.Acquire(ctx, false, 1)
+			if !ok {
+				shed.Add(1)
+				return
+			}
+			admitted.Add(1)
+			n := inFlight.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			<-gate // hold the slot until every request has tried
+			inFlight.Add(-1)
+			release()
+		}()
+	}
+	for admitted.Load()+shed.Load() < 20 {
+		time.Sleep(time.Millisecond)
+	}
+	if admitted.Load() != limit || shed.Load() != 20-limit {
+		t.Errorf("admitted %d, shed %d; want %d and %d", admitted.Load(), shed.Load(), limit, 20-limit)
+	}
+
+	// A different account is not affected by the hot one.
+	release, ok := s.Acquire(ctx, false, 2)
+	if !ok {
+		t.Fatal("an idle account was refused")
+	}
+	release()
+
+	close(gate)
+	wg.Wait()
+	if peak.Load() > limit {
+		t.Errorf("%d in flight at once, limit %d", peak.Load(), limit)
+	}
+	if n := len(s.keys); n != 0 {
+		t.Errorf("%d keys left after every holder released", n)
+	}
+}
+
+func TestKeyedSemaphoreWaits(t *testing.T) {
+	s := NewKeyedSemaphore(1)
+	release, _ := s.Acquire(context.Background(), false, 1)
+
+	// A waiter gets the slot once the holder releases it.
+	got := make(chan func())
+	go func() {
+		r, ok := s.Acquire(context.Background(), true, 1)
+		if ok {
+			got <- r
+		}
+		close(got)
+	}()
+	select {
+	case <-got:
+		t.Fatal("waiter admitted while the slot was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	r, ok := <-got
+	if !ok {
+		t.Fatal("waiter was not admitted after release")
+	}
+
+	// A waiter whose context ends gives up holding nothing.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := s.Acquire(ctx, true, 1); ok {
+		t.Fatal("admitted past a held slot")
+	}
+	r()
+	if n := len(s.keys); n != 0 {
+		t.Errorf("%d keys left after the waiter gave up", n)
+	}
+}
+
+// A multi-account acquire that fails on one account releases the ones it
+// already took.
+func TestKeyedSemaphoreAllOrNothing(t *testing.T) {
+	s := NewKeyedSemaphore(1)
+	hot, _ := s.Acquire(context.Background(), false, 5)
+	defer hot()
+
+	if _, ok := s.Acquire(context.Background(), false, 1, 5, 3); ok {
+		t.Fatal("acquired a full account")
+	}
+	for _, k := range []int64{1, 3} {
+		release, ok := s.Acquire(context.Background(), false, k)
+		if !ok {
+			t.Fatalf("account %d still held after the failed acquire", k)
+		}
+		release()
+	}
+	// Repeated keys count once.
+	release, ok := s.Acquire(context.Background(), false, 2, 2)
+	if !ok {
+		t.Fatal("a key listed twice blocked itself")
+	}
+	release()
+}
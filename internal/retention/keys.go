@@ -0,0 +1,33 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/worker"
+)
+
+// KeyReaper deletes idempotency keys whose client-requested TTL
+// (Idempotency-Key-TTL) has passed, ahead of their partition being dropped.
+type KeyReaper struct {
+	store *store.LedgerStore
+}
+
+func NewKeyReaper(s *store.LedgerStore) *KeyReaper {
+	return &KeyReaper{store: s}
+}
+
+// Run reaps expired keys every interval until ctx is canceled.
+func (k *KeyReaper) Run(ctx context.Context, interval time.Duration) {
+	poller := &worker.Poller[store.ExpiredKey]{
+		Name:        "idempotency key reaper",
+		Fetch:       k.store.ExpiredIdempotencyKeys,
+		Process:     k.store.DeleteExpiredIdempotencyKey,
+		Concurrency: 1,
+		BatchSize:   500,
+		Interval:    interval,
+		Drain:       true,
+	}
+	poller.Run(ctx)
+}
@@ -0,0 +1,71 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/store"
+	"github.com/punchamoorthee/ledgerops/internal/worker"
+)
+
+// IdempotencyPartitions keeps idempotency_keys partitioned: each pass
+// creates the daily partitions for the coming days and drops the ones past
+// the retention window. Dropping a partition is far cheaper than deleting
+// its rows and leaves nothing to vacuum.
+type IdempotencyPartitions struct {
+	store     *store.LedgerStore
+	ahead     int           // days of partitions to keep pre-created
+	retention time.Duration // 0 keeps every partition
+}
+
+func NewIdempotencyPartitions(s *store.LedgerStore, ahead int, retention time.Duration) *IdempotencyPartitions {
+	return &IdempotencyPartitions{store: s, ahead: ahead, retention: retention}
+}
+
+// Run maintains the partitions every interval until ctx is canceled.
+//
+// To check dropping by hand: create an old partition, e.g.
+//
+//	CREATE TABLE idempotency_keys_p20000101 PARTITION OF idempotency_keys
+//	  FOR VALUES FROM ('2000-01-01') TO ('2000-01-02');
+//
+// then start the API with IDEMPOTENCY_PARTITION_INTERVAL=5s and watch for
+// "dropped idempotency_keys_p20000101" in the log and the table disappearing
+// from \d+ idempotency_keys.
+func (p *IdempotencyPartitions) Run(ctx context.Context, interval time.Duration) {
+	poller := &worker.Poller[string]{
+		Name:        "idempotency partitions",
+		Fetch:       p.expired,
+		Process:     p.drop,
+		Concurrency: 1,
+		BatchSize:   100,
+		Interval:    interval,
+		Drain:       true,
+	}
+	poller.Run(ctx)
+}
+
+// expired makes sure upcoming partitions exist, then returns the ones to drop.
+func (p *IdempotencyPartitions) expired(ctx context.Context, limit int) ([]string, error) {
+	now := time.Now()
+	if err := p.store.EnsureIdempotencyPartitions(ctx, now, p.ahead); err != nil {
+		return nil, err
+	}
+	if p.retention <= 0 {
+		return nil, nil
+	}
+	names, err := p.store.ExpiredIdempotencyPartitions(ctx, now.Add(-p.retention))
+	if err != nil {
+		return nil, err
+	}
+	return names[:min(len(names), limit)], nil
+}
+
+func (p *IdempotencyPartitions) drop(ctx context.Context, name string) error {
+	if err := p.store.DropIdempotencyPartition(ctx, name); err != nil {
+		return err
+	}
+	log.Printf("idempotency partitions: dropped %s", name)
+	return nil
+}
@@ -0,0 +1,265 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestAccountNameAndPatch(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	id, err := s.CreateAccount(ctx, domain.CreateAccountRequest{InitialBalance: 100, Name: "Alice's checking", Metadata: map[string]any{"owner": "alice", "tier": 2.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := s.GetAccount(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Name != "Alice's checking" || acc.Metadata["owner"] != "alice" || acc.Metadata["tier"] != 2.0 {
+		t.Fatalf("created %+v", acc)
+	}
+
+	renamed := "Joint checking"
+	upd, err := s.UpdateAccount(ctx, id, domain.AccountUpdate{Name: &renamed, Version: acc.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upd.Name != renamed || upd.Version != acc.Version+1 || upd.Balance != 100 || upd.Metadata["owner"] != "alice" {
+		t.Errorf("after rename: %+v; want new name, next version, balance and metadata untouched", upd)
+	}
+
+	if _, err := s.UpdateAccount(ctx, id, domain.AccountUpdate{Name: &renamed, Version: acc.Version}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("stale version: err = %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.UpdateAccount(ctx, id+1, domain.AccountUpdate{Name: &renamed, Version: 1}); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("missing account: err = %v, want ErrAccountNotFound", err)
+	}
+
+	empty := ""
+	cleared, err := s.UpdateAccount(ctx, id, domain.AccountUpdate{Name: &empty, Metadata: map[string]any{}, Version: upd.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleared.Name != "" || cleared.Metadata != nil {
+		t.Errorf("after clearing: %+v", cleared)
+	}
+	AssertBalance(t, s, id, 100)
+}
+
+// Balance changes move the balance version that If-Match checks, never the
+// version a PATCH is conditioned on, so a transfer or recompute landing
+// between a client's read and its PATCH doesn't fail the edit.
+func TestBalanceChangesLeavePatchVersion(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 0)
+	before, err := s.GetAccount(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}, "k", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(ctx, "UPDATE accounts SET balance = 0 WHERE id = $1", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.recomputeBalance(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	after, err := s.GetAccount(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Version != before.Version || after.BalanceVersion != before.BalanceVersion+2 {
+		t.Errorf("version %d -> %d, balance version %d -> %d; want the first unchanged and the second up by two",
+			before.Version, after.Version, before.BalanceVersion, after.BalanceVersion)
+	}
+
+	renamed := "Savings"
+	if _, err := s.UpdateAccount(ctx, a, domain.AccountUpdate{Name: &renamed, Version: before.Version}); err != nil {
+		t.Errorf("PATCH at the version read before the transfer: %v", err)
+	}
+	AssertBalance(t, s, a, 90)
+}
+
+func TestMinimumBalanceReserve(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, err := s.CreateAccount(ctx, domain.CreateAccountRequest{InitialBalance: 1000, MinBalance: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := SeedAccount(t, s, 0)
+	if acc, err := s.GetAccount(ctx, a); err != nil || acc.MinBalance != 200 {
+		t.Fatalf("GET: %+v, %v; want min_balance 200", acc, err)
+	}
+
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 800}, "to-reserve", "h"); err != nil {
+		t.Fatalf("debit down to the reserve: %v", err)
+	}
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 1}, "below-reserve", "h"); !errors.Is(err, ErrBelowMinimumBalance) {
+		t.Fatalf("debit below the reserve: err = %v, want ErrBelowMinimumBalance", err)
+	}
+	AssertBalance(t, s, a, 200)
+	AssertBalance(t, s, b, 800)
+
+	// The reserve only limits debits; credits still land.
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: b, ToAccountID: a, Amount: 50}, "credit", "h"); err != nil {
+		t.Fatal(err)
+	}
+	AssertBalance(t, s, a, 250)
+	AssertInvariants(t, s)
+}
+
+func TestAccountIDFloor(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	const floor = 1_000_000
+
+	if err := s.RaiseAccountIDFloor(ctx, floor); err != nil {
+		t.Fatal(err)
+	}
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 0)
+	if a < floor || b <= a {
+		t.Fatalf("IDs %d, %d; want ascending from %d", a, b, floor)
+	}
+	if _, err := s.GetAccount(ctx, a); err != nil {
+		t.Fatalf("GetAccount(%d): %v", a, err)
+	}
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 40}, "k1", "h1"); err != nil {
+		t.Fatal(err)
+	}
+	AssertBalance(t, s, b, 40)
+
+	// A lower floor, as on a restart with the setting removed or lowered,
+	// never moves the sequence back into used IDs.
+	if err := s.RaiseAccountIDFloor(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if c := SeedAccount(t, s, 0); c <= b {
+		t.Errorf("after lowering the floor got ID %d, want above %d", c, b)
+	}
+	AssertInvariants(t, s)
+}
+
+// A FOR SHARE read holds the balance still for the rest of its transaction:
+// other readers go ahead, but a transfer's FOR UPDATE NOWAIT is refused
+// until it ends. A plain read holds nothing.
+func TestSharedReadBlocksTransfers(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}
+
+	plain, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAccounts(ctx, plain, readPlain, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExecTransfer(ctx, req, "under-plain", "h"); err != nil {
+		t.Fatalf("transfer during a plain read: %v", err)
+	}
+	plain.Rollback(ctx)
+
+	report, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer report.Rollback(ctx)
+	accs, err := readAccounts(ctx, report, readShared, a)
+	if err != nil || len(accs) != 1 || accs[0].Balance != 900 {
+		t.Fatalf("shared read: %+v, %v", accs, err)
+	}
+
+	other, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAccounts(ctx, other, readShared, a); err != nil {
+		t.Errorf("second shared read: %v", err)
+	}
+	other.Rollback(ctx)
+
+	if _, err := s.ExecTransfer(ctx, req, "under-share", "h"); !errors.Is(err, ErrLockContention) {
+		t.Fatalf("transfer during a shared read: err = %v, want ErrLockContention", err)
+	}
+	if err := report.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExecTransfer(ctx, req, "under-share", "h"); err != nil {
+		t.Fatalf("transfer after the report ended: %v", err)
+	}
+	AssertBalance(t, s, a, 800)
+	AssertInvariants(t, s)
+}
+
+func TestListAccountsByTag(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	create := func(tags ...string) int64 {
+		t.Helper()
+		id, err := s.CreateAccount(ctx, domain.CreateAccountRequest{Tags: tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	payroll := create("payroll")
+	merchantEU := create("merchant", "eu")
+	merchantUS := create("merchant", "us")
+	untagged := create()
+
+	list := func(tags ...string) []int64 {
+		t.Helper()
+		page, err := s.ListAccounts(ctx, tags, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, a := range page.Accounts {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	cases := []struct {
+		tags []string
+		want []int64
+	}{
+		{tags: nil, want: []int64{payroll, merchantEU, merchantUS, untagged}},
+		{tags: []string{"payroll"}, want: []int64{payroll}},
+		{tags: []string{"merchant"}, want: []int64{merchantEU, merchantUS}},
+		{tags: []string{"merchant", "eu"}, want: []int64{merchantEU}},
+		{tags: []string{"payroll", "eu"}, want: nil},
+		{tags: []string{"refunds"}, want: nil},
+	}
+	for _, tc := range cases {
+		if got := list(tc.tags...); !slices.Equal(got, tc.want) {
+			t.Errorf("tags %v: got %v, want %v", tc.tags, got, tc.want)
+		}
+	}
+
+	acc, err := s.GetAccount(ctx, untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Tags == nil || len(acc.Tags) != 0 {
+		t.Errorf("untagged account has tags %#v, want an empty list", acc.Tags)
+	}
+	if _, err := s.UpdateAccount(ctx, untagged, domain.AccountUpdate{Tags: []string{"payroll"}, Version: acc.Version}); err != nil {
+		t.Fatal(err)
+	}
+	if got := list("payroll"); !slices.Equal(got, []int64{payroll, untagged}) {
+		t.Errorf("after tagging: got %v, want %v", got, []int64{payroll, untagged})
+	}
+}
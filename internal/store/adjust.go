@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// BatchAdjust applies req.Rule to every matching account as a balanced
+// transfer against treasuryID, chunk accounts per transaction, recording
+// req.Reason on the run and on each transfer. Rows another writer holds are
+// skipped (SKIP LOCKED) rather than waited on; the run is left incomplete
+// and repeating the same RunID finishes it. Reusing a RunID with a
+// different request fails with ErrKeyMismatch.
+func (s *LedgerStore) BatchAdjust(ctx context.Context, treasuryID int64, req domain.BatchAdjustRequest, reqHash string, chunk int) (*domain.BatchAdjustResult, error) {
+	if req.Type == "" {
+		req.Type = domain.TransferTypeAdjustment
+	}
+	if !domain.ValidTransferType(req.Type) {
+		return nil, ErrInvalidType
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+
+	tag, err := s.db.Exec(ctx,
+		"INSERT INTO adjustment_runs (run_id, request_hash, reason) VALUES ($1, $2, $3) ON CONFLICT (run_id) DO NOTHING",
+		req.RunID, reqHash, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		var hash, status string
+		if err := s.db.QueryRow(ctx,
+			"SELECT request_hash, status FROM adjustment_runs WHERE run_id = $1", req.RunID).Scan(&hash, &status); err != nil {
+			return nil, err
+		}
+		if hash != reqHash {
+			return nil, ErrKeyMismatch
+		}
+		if status == "completed" {
+			res, err := s.adjustmentTotals(ctx, req.RunID)
+			if err != nil {
+				return nil, err
+			}
+			res.Replayed = true
+			return res, nil
+		}
+	}
+
+	var after int64
+	for {
+		last, err := retrySerialization(ctx, func() (int64, error) {
+			return s.adjustChunk(ctx, treasuryID, req, after, chunk)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if last == 0 {
+			break
+		}
+		after = last
+	}
+
+	var remaining bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM accounts a
+			WHERE a.tags @> $1 AND a.id <> $2
+			  AND NOT EXISTS (SELECT 1 FROM adjustment_run_items i WHERE i.run_id = $3 AND i.account_id = a.id))`,
+		req.Tags, treasuryID, req.RunID).Scan(&remaining)
+	if err != nil {
+		return nil, err
+	}
+	if !remaining {
+		if _, err := s.db.Exec(ctx,
+			"UPDATE adjustment_runs SET status = 'completed', completed_at = now() WHERE run_id = $1", req.RunID); err != nil {
+			return nil, err
+		}
+	}
+	return s.adjustmentTotals(ctx, req.RunID)
+}
+
+// adjustChunk adjusts up to chunk unvisited accounts above after in one
+// transaction and returns the highest ID it saw, or 0 when none were left.
+// The treasury is locked first and out of ID order; that can't deadlock
+// because neither lock here ever waits (NOWAIT, then SKIP LOCKED).
+func (s *LedgerStore) adjustChunk(ctx context.Context, treasuryID int64, req domain.BatchAdjustRequest, after int64, chunk int) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return 0, err
+	}
+	defer rollback(ctx, tx)
+
+	locked, err := lockAccounts(ctx, tx, treasuryID)
+	if err != nil {
+		return 0, err
+	}
+	treasury := locked[treasuryID]
+
+	rows, err := tx.Query(ctx, `
+		SELECT a.id, a.balance, a.min_balance FROM accounts a
+		WHERE a.tags @> $1 AND a.id > $2 AND a.id <> $3
+		  AND NOT EXISTS (SELECT 1 FROM adjustment_run_items i WHERE i.run_id = $4 AND i.account_id = a.id)
+		ORDER BY a.id
+		LIMIT $5
+		FOR UPDATE OF a SKIP LOCKED`,
+		req.Tags, after, treasuryID, req.RunID, chunk)
+	if err != nil {
+		return 0, err
+	}
+	type target struct {
+		id int64
+		lockedAccount
+	}
+	targets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (target, error) {
+		var t target
+		err := row.Scan(&t.id, &t.balance, &t.minBalance)
+		return t, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	touched := []int64{treasuryID}
+	var transferIDs []int64
+	for _, t := range targets {
+		amount := req.Rule.Apply(t.balance)
+		var transferID *int64
+		switch {
+		case amount > 0:
+			if err := treasury.checkDebit(amount); err != nil {
+				return 0, err
+			}
+			moved, err := s.moveFunds(ctx, tx, treasuryID, t.id, amount, req.Type, "")
+			if err != nil {
+				return 0, err
+			}
+			treasury.balance -= amount
+			transferID = &moved.Transfer.ID
+			transferIDs = append(transferIDs, moved.Transfer.ID)
+		case amount < 0:
+			// An account that can't cover its fee is skipped, not overdrawn.
+			if t.checkDebit(-amount) != nil {
+				amount = 0
+				break
+			}
+			moved, err := s.moveFunds(ctx, tx, t.id, treasuryID, -amount, req.Type, "")
+			if err != nil {
+				return 0, err
+			}
+			treasury.balance -= amount
+			transferID = &moved.Transfer.ID
+			transferIDs = append(transferIDs, moved.Transfer.ID)
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO adjustment_run_items (run_id, account_id, transfer_id, amount) VALUES ($1, $2, $3, $4)",
+			req.RunID, t.id, transferID, amount); err != nil {
+			return 0, err
+		}
+		touched = append(touched, t.id)
+	}
+	if len(transferIDs) > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE transfers SET reason = $1 WHERE id = ANY($2)", req.Reason, transferIDs); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := commit(ctx, tx); err != nil {
+		return 0, err
+	}
+	s.invalidateAccounts(touched...)
+	return targets[len(targets)-1].id, nil
+}
+
+func (s *LedgerStore) adjustmentTotals(ctx context.Context, runID string) (*domain.BatchAdjustResult, error) {
+	res := &domain.BatchAdjustResult{RunID: runID}
+	var status string
+	err := s.db.QueryRow(ctx, `
+		SELECT r.status, COALESCE(r.reason, ''),
+			COUNT(i.account_id) FILTER (WHERE i.transfer_id IS NOT NULL),
+			COUNT(i.account_id) FILTER (WHERE i.transfer_id IS NULL),
+			COALESCE(SUM(i.amount) FILTER (WHERE i.amount > 0), 0),
+			COALESCE(-SUM(i.amount) FILTER (WHERE i.amount < 0), 0)
+		FROM adjustment_runs r
+		LEFT JOIN adjustment_run_items i ON i.run_id = r.run_id
+		WHERE r.run_id = $1
+		GROUP BY r.status, r.reason`, runID).Scan(&status, &res.Reason, &res.AccountsAdjusted, &res.AccountsSkipped, &res.TotalCredited, &res.TotalDebited)
+	if err != nil {
+		return nil, err
+	}
+	res.Complete = status == "completed"
+	return res, nil
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestBatchAdjustOffsetsTreasury(t *testing.T) {
+	cases := []struct {
+		name     string
+		rule     domain.AdjustmentRule
+		balances []int64
+		want     []int64 // per-account adjustment
+	}{
+		{"flat interest", domain.AdjustmentRule{Kind: domain.AdjustFlat, Amount: 100}, []int64{0, 500, 12345}, []int64{100, 100, 100}},
+		{"flat fee skips who can't pay", domain.AdjustmentRule{Kind: domain.AdjustFlat, Amount: -100}, []int64{50, 500, 12345}, []int64{0, -100, -100}},
+		{"percent interest", domain.AdjustmentRule{Kind: domain.AdjustPercent, BasisPoints: 150}, []int64{0, 10000, 12345}, []int64{0, 150, 185}},
+		{"percent fee", domain.AdjustmentRule{Kind: domain.AdjustPercent, BasisPoints: -250}, []int64{0, 10000, 12345}, []int64{0, -250, -308}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewTestStore(t)
+			ctx := context.Background()
+			const treasuryStart = 1_000_000
+			treasury := SeedAccount(t, s, treasuryStart)
+			ids := make([]int64, len(tc.balances))
+			for i, b := range tc.balances {
+				id, err := s.CreateAccount(ctx, domain.CreateAccountRequest{InitialBalance: b, Tags: []string{"savings"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids[i] = id
+			}
+			SeedAccount(t, s, 777) // untagged, must be left alone
+
+			req := domain.BatchAdjustRequest{RunID: "run-1", Reason: "monthly run", Rule: tc.rule, Tags: []string{"savings"}}
+			res, err := s.BatchAdjust(ctx, treasury, req, "hash", 2)
+			if err != nil {
+				t.Fatalf("batch adjust: %v", err)
+			}
+			if !res.Complete || res.Reason != "monthly run" {
+				t.Errorf("result = %+v, want complete with the reason", res)
+			}
+
+			var net int64
+			for i, id := range ids {
+				AssertBalance(t, s, id, tc.balances[i]+tc.want[i])
+				net += tc.want[i]
+			}
+			AssertBalance(t, s, treasury, treasuryStart-net)
+			if res.TotalCredited-res.TotalDebited != net {
+				t.Errorf("credited %d - debited %d, want net %d", res.TotalCredited, res.TotalDebited, net)
+			}
+			AssertInvariants(t, s)
+
+			var unreasoned int
+			if err := s.db.QueryRow(ctx,
+				"SELECT COUNT(*) FROM transfers WHERE type = 'adjustment' AND reason IS DISTINCT FROM 'monthly run'").Scan(&unreasoned); err != nil {
+				t.Fatal(err)
+			}
+			if unreasoned != 0 {
+				t.Errorf("%d adjustment transfers without the run's reason", unreasoned)
+			}
+
+			again, err := s.BatchAdjust(ctx, treasury, req, "hash", 2)
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if !again.Replayed || again.TotalCredited != res.TotalCredited || again.TotalDebited != res.TotalDebited {
+				t.Errorf("replay = %+v, want the first run's totals", again)
+			}
+			AssertBalance(t, s, treasury, treasuryStart-net)
+		})
+	}
+}
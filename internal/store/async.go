@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// EnqueueTransfer accepts a transfer for asynchronous execution: it is
+// persisted as 'pending' with no ledger entries, and ProcessPendingTransfer
+// settles it later. Idempotency dedups here, at enqueue time.
+func (s *LedgerStore) EnqueueTransfer(ctx context.Context, req domain.TransferRequest, idempotencyKey, reqHash string) (*domain.TransferResponse, error) {
+	return retrySerialization(ctx, func() (*domain.TransferResponse, error) {
+		return s.enqueueTransfer(ctx, req, idempotencyKey, reqHash)
+	})
+}
+
+func (s *LedgerStore) enqueueTransfer(ctx context.Context, req domain.TransferRequest, idempotencyKey, reqHash string) (*domain.TransferResponse, error) {
+	if req.Amount <= 0 { // includes sweeps, which only run synchronously
+		return nil, ErrInvalidAmount
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return nil, ErrSelfTransfer
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	cached, err := s.idem.Reserve(ctx, tx, OpTransfer, idempotencyKey, reqHash)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		return replayTransfer(ctx, tx, idempotencyKey, cached)
+	}
+
+	// Reject unknown accounts now rather than queueing a certain failure.
+	var found int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM accounts WHERE id = ANY($1)",
+		[]int64{req.FromAccountID, req.ToAccountID}).Scan(&found)
+	if err != nil {
+		return nil, err
+	}
+	if found != 2 {
+		return nil, ErrAccountNotFound
+	}
+
+	t, err := s.insertTransfer(ctx, tx, req.FromAccountID, req.ToAccountID, req.Amount, req.Type, req.EffectiveDate, "pending")
+	if err != nil {
+		return nil, err
+	}
+	resp := &domain.TransferResponse{Transfer: *t, Entries: []domain.LedgerEntry{}}
+	if err := s.idem.Complete(ctx, tx, idempotencyKey, 202, resp, t.ID); err != nil {
+		return nil, err
+	}
+	return resp, commit(ctx, tx)
+}
+
+// PendingTransferIDs returns up to limit queued transfers, oldest first.
+// The IDs are only candidates: ProcessPendingTransfer claims each one.
+func (s *LedgerStore) PendingTransferIDs(ctx context.Context, limit int) ([]int64, error) {
+	rows, err := s.db.Query(ctx, "SELECT id FROM transfers WHERE status = 'pending' ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+// PendingTransferCount reports the async queue depth.
+func (s *LedgerStore) PendingTransferCount(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transfers WHERE status = 'pending'").Scan(&n)
+	return n, err
+}
+
+// ProcessPendingTransfer settles one queued transfer. SKIP LOCKED lets
+// concurrent workers share the queue: a transfer another worker holds, or
+// that is no longer pending, is skipped and reported as not processed.
+// Lock contention on the accounts leaves the transfer queued for a later
+// attempt; business failures mark it 'failed' with a reason.
+func (s *LedgerStore) ProcessPendingTransfer(ctx context.Context, id int64) (*domain.Transfer, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	var t domain.Transfer
+	err = tx.QueryRow(ctx, `
+		SELECT id, public_id::text, from_account_id, to_account_id, amount, type, to_char(effective_date, 'YYYY-MM-DD'), created_at FROM transfers
+		WHERE id = $1 AND status = 'pending' FOR UPDATE SKIP LOCKED`, id,
+	).Scan(&t.ID, &t.PublicID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.Type, &t.EffectiveDate, &t.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := lockAccounts(ctx, tx, t.FromAccountID, t.ToAccountID)
+	if err == nil {
+		err = accounts[t.FromAccountID].checkDebit(t.Amount)
+	}
+	switch {
+	case errors.Is(err, ErrAccountNotFound):
+		t.FailureReason = "account_not_found"
+	case errors.Is(err, ErrFunds):
+		t.FailureReason = "insufficient_funds"
+	case errors.Is(err, ErrBelowMinimumBalance):
+		t.FailureReason = "below_minimum_balance"
+	case err != nil:
+		return nil, err
+	default:
+		if _, err := applyEntries(ctx, tx, t.ID, t.FromAccountID, t.ToAccountID, t.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	t.Status = "completed"
+	if t.FailureReason != "" {
+		t.Status = "failed"
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE transfers SET status = $1, failure_reason = NULLIF($2, ''), processed_at = now() WHERE id = $3",
+		t.Status, t.FailureReason, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	if t.Status == "completed" {
+		s.invalidateAccounts(t.FromAccountID, t.ToAccountID)
+	}
+	return &t, nil
+}
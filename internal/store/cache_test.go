@@ -0,0 +1,36 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/cache"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestTransferInvalidatesCachedAccounts(t *testing.T) {
+	s := NewTestStore(t, WithAccountCache(cache.NewLRU[int64, domain.Account](16, time.Hour)))
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 500), SeedAccount(t, s, 0)
+
+	for _, id := range []int64{a, b} {
+		if _, err := s.GetAccount(ctx, id); err != nil { // warm the cache
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 200}, "k", "h"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int64]int64{a: 300, b: 200} {
+		acc, err := s.GetAccount(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.Balance != want {
+			t.Errorf("account %d: cached read returned balance %d, want %d", id, acc.Balance, want)
+		}
+	}
+}
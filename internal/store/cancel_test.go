@@ -0,0 +1,74 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// Transfers canceled at assorted points, from before BEGIN to around
+// COMMIT, leave either the whole transfer or nothing: never an in-progress
+// key without its work, never a balance change without its key.
+func TestCanceledTransfersLeaveNoWedgedKeys(t *testing.T) {
+	s := NewTestStore(t)
+	const n = 60
+	ctx := context.Background()
+	accounts := []int64{SeedAccount(t, s, 100000), SeedAccount(t, s, 100000), SeedAccount(t, s, 100000)}
+	req := func(i int) domain.TransferRequest {
+		return domain.TransferRequest{FromAccountID: accounts[i%3], ToAccountID: accounts[(i+1)%3], Amount: 10}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Deadlines from 0 to ~3ms land at different steps of the
+			// transaction; which step is up to the scheduler.
+			cctx, cancel := context.WithTimeout(ctx, time.Duration(i%12)*250*time.Microsecond)
+			defer cancel()
+			s.ExecTransfer(cctx, req(i), fmt.Sprintf("cancel-%d", i), "h")
+		}()
+	}
+	wg.Wait()
+
+	var wedged, completed, transfers int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FILTER (WHERE status = 'in_progress'), COUNT(*) FILTER (WHERE status = 'completed') FROM idempotency_keys").Scan(&wedged, &completed); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transfers").Scan(&transfers); err != nil {
+		t.Fatal(err)
+	}
+	if wedged != 0 {
+		t.Errorf("%d keys left in progress", wedged)
+	}
+	if completed != transfers {
+		t.Errorf("%d completed keys but %d transfers", completed, transfers)
+	}
+	AssertInvariants(t, s)
+	t.Logf("%d of %d transfers committed before their deadline", transfers, n)
+
+	// Every key is usable: retries either replay or run for the first time,
+	// and each transfer lands exactly once.
+	for i := 0; i < n; i++ {
+		if _, err := s.ExecTransfer(ctx, req(i), fmt.Sprintf("cancel-%d", i), "h"); err != nil {
+			t.Fatalf("retry %d: %v", i, err)
+		}
+	}
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transfers").Scan(&transfers); err != nil {
+		t.Fatal(err)
+	}
+	if transfers != n {
+		t.Errorf("%d transfers after retries, want %d", transfers, n)
+	}
+	for _, id := range accounts {
+		AssertBalance(t, s, id, 100000) // each account sends and receives n/3 transfers of 10
+	}
+	AssertInvariants(t, s)
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+var ErrEmptyChain = errors.New("chain has no hops")
+
+// HopError pins a chain failure to the hop that caused it.
+type HopError struct {
+	Index int
+	Err   error
+}
+
+func (e *HopError) Error() string { return fmt.Sprintf("hop %d: %v", e.Index, e.Err) }
+func (e *HopError) Unwrap() error { return e.Err }
+
+// ExecChain executes every hop atomically: all accounts are locked up front
+// in ascending ID order, each hop is funded from the running in-transaction
+// balance, and any failure rolls back the whole chain.
+func (s *LedgerStore) ExecChain(ctx context.Context, req domain.ChainRequest, idempotencyKey, reqHash string) (*domain.ChainResponse, error) {
+	return retrySerialization(ctx, func() (*domain.ChainResponse, error) {
+		return s.execChain(ctx, req, idempotencyKey, reqHash)
+	})
+}
+
+func (s *LedgerStore) execChain(ctx context.Context, req domain.ChainRequest, idempotencyKey, reqHash string) (*domain.ChainResponse, error) {
+	if len(req.Hops) == 0 {
+		return nil, ErrEmptyChain
+	}
+	ids := make([]int64, 0, len(req.Hops)*2)
+	for i, hop := range req.Hops {
+		if hop.Amount <= 0 {
+			return nil, &HopError{Index: i, Err: ErrInvalidAmount}
+		}
+		if hop.FromAccountID == hop.ToAccountID {
+			return nil, &HopError{Index: i, Err: ErrSelfTransfer}
+		}
+		if !domain.ValidTransferType(hop.Type) {
+			return nil, &HopError{Index: i, Err: ErrInvalidType}
+		}
+		ids = append(ids, hop.FromAccountID, hop.ToAccountID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	cached, err := s.idem.Reserve(ctx, tx, OpChain, idempotencyKey, reqHash)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		var resp domain.ChainResponse
+		if err := json.Unmarshal(cached, &resp); err != nil {
+			return nil, err
+		}
+		resp.Replayed = true
+		return &resp, nil
+	}
+
+	accounts, err := lockAccounts(ctx, tx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.ChainResponse{}
+	for i, hop := range req.Hops {
+		if err := accounts[hop.FromAccountID].checkDebit(hop.Amount); err != nil {
+			return nil, &HopError{Index: i, Err: err}
+		}
+		t, err := s.moveFunds(ctx, tx, hop.FromAccountID, hop.ToAccountID, hop.Amount, hop.Type, hop.EffectiveDate)
+		if err != nil {
+			return nil, &HopError{Index: i, Err: err}
+		}
+		accounts[hop.FromAccountID].balance -= hop.Amount
+		accounts[hop.ToAccountID].balance += hop.Amount
+		resp.TransferIDs = append(resp.TransferIDs, t.Transfer.ID)
+		resp.Transfers = append(resp.Transfers, *t)
+	}
+
+	// The key points at the first transfer; the cached body has all of them.
+	if err := s.idem.Complete(ctx, tx, idempotencyKey, 201, resp, resp.TransferIDs...); err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(ids...)
+	return resp, nil
+}
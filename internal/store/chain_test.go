@@ -0,0 +1,53 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// A chain is all or nothing: when a middle hop can't be covered, the hops
+// before it are rolled back too and the key is left free for a retry.
+func TestChainMiddleHopUnderfunded(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b, c, d := SeedAccount(t, s, 100), SeedAccount(t, s, 0), SeedAccount(t, s, 0), SeedAccount(t, s, 0)
+	req := domain.ChainRequest{Hops: []domain.TransferRequest{
+		{FromAccountID: a, ToAccountID: b, Amount: 100},
+		{FromAccountID: b, ToAccountID: c, Amount: 150}, // b only has what a sent
+		{FromAccountID: c, ToAccountID: d, Amount: 100},
+	}}
+
+	_, err := s.ExecChain(ctx, req, "chain-1", "h1")
+	var hopErr *HopError
+	if !errors.As(err, &hopErr) || hopErr.Index != 1 || !errors.Is(err, ErrFunds) {
+		t.Fatalf("err = %v, want insufficient funds at hop 1", err)
+	}
+	for id, want := range map[int64]int64{a: 100, b: 0, c: 0, d: 0} {
+		AssertBalance(t, s, id, want)
+	}
+	var transfers int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transfers").Scan(&transfers); err != nil {
+		t.Fatal(err)
+	}
+	if transfers != 0 {
+		t.Errorf("%d transfers survived the rollback", transfers)
+	}
+	AssertInvariants(t, s)
+
+	req.Hops[1].Amount = 100
+	resp, err := s.ExecChain(ctx, req, "chain-1", "h2")
+	if err != nil {
+		t.Fatalf("retry with the same key: %v", err)
+	}
+	if len(resp.TransferIDs) != 3 {
+		t.Errorf("retry made %d transfers, want 3", len(resp.TransferIDs))
+	}
+	AssertBalance(t, s, a, 0)
+	AssertBalance(t, s, d, 100)
+	AssertInvariants(t, s)
+}
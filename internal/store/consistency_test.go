@@ -0,0 +1,109 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// envInt reads a positive count from env so CI can run a smaller version.
+func envInt(t *testing.T, key string, def int) int {
+	t.Helper()
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		t.Fatalf("%s=%q: want a positive integer", key, v)
+	}
+	return n
+}
+
+// TestConcurrentTransfersConserveMoney fires random transfers across a small
+// set of accounts from many goroutines, then checks that no money was made
+// or lost and every balance still matches its entries. Sizes come from
+// CONSISTENCY_WORKERS, CONSISTENCY_TRANSFERS (per worker) and
+// CONSISTENCY_ACCOUNTS.
+func TestConcurrentTransfersConserveMoney(t *testing.T) {
+	s := NewTestStore(t)
+	workers := envInt(t, "CONSISTENCY_WORKERS", 16)
+	perWorker := envInt(t, "CONSISTENCY_TRANSFERS", 200)
+	n := envInt(t, "CONSISTENCY_ACCOUNTS", 20)
+	if n < 2 {
+		t.Fatal("CONSISTENCY_ACCOUNTS must be at least 2")
+	}
+
+	const opening = 10_000
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = SeedAccount(t, s, opening)
+	}
+	initialTotal := int64(n) * opening
+
+	ctx := context.Background()
+	var completed, rejected atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWorker; i++ {
+				from := ids[rng.Intn(n)]
+				to := ids[rng.Intn(n-1)]
+				if to == from {
+					to = ids[n-1]
+				}
+				req := domain.TransferRequest{FromAccountID: from, ToAccountID: to, Amount: int64(rng.Intn(500) + 1)}
+				key := fmt.Sprintf("consistency-%d-%d", w, i)
+				err := execWithRetry(ctx, s, req, key)
+				switch {
+				case err == nil:
+					completed.Add(1)
+				case errors.Is(err, ErrFunds), errors.Is(err, ErrLockContention):
+					rejected.Add(1)
+				default:
+					errs <- fmt.Errorf("transfer %s: %w", key, err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	t.Logf("%d transfers completed, %d rejected", completed.Load(), rejected.Load())
+	if completed.Load() == 0 {
+		t.Fatal("no transfer completed")
+	}
+
+	var total int64
+	if err := s.db.QueryRow(ctx, "SELECT SUM(balance) FROM accounts").Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != initialTotal {
+		t.Errorf("SUM(balance) = %d, want %d", total, initialTotal)
+	}
+	var entries int64
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM ledger_entries").Scan(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if entries != 2*completed.Load() {
+		t.Errorf("%d ledger entries for %d transfers", entries, completed.Load())
+	}
+	AssertInvariants(t, s)
+}
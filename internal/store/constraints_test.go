@@ -0,0 +1,63 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The schema holds the line even for writes that bypass the store.
+func TestTransferConstraints(t *testing.T) {
+	s := NewTestStore(t)
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 100)
+	cases := []struct {
+		name     string
+		from, to int64
+		amount   int64
+	}{
+		{"zero amount", a, b, 0},
+		{"negative amount", a, b, -1},
+		{"self-transfer", a, a, 10},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.db.Exec(context.Background(),
+				"INSERT INTO transfers (from_account_id, to_account_id, amount, status) VALUES ($1, $2, $3, 'completed')", tc.from, tc.to, tc.amount)
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) || pgErr.Code != "23514" { // check_violation
+				t.Fatalf("err = %v, want a check violation", err)
+			}
+		})
+	}
+}
+
+// An unbalanced transfer written around writeLegs is caught by the deferred
+// check_ledger_invariant trigger at commit and surfaces as the typed error.
+func TestUnbalancedEntriesFailCommit(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 100)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rollback(ctx, tx)
+	var transferID int64
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO transfers (from_account_id, to_account_id, amount, status) VALUES ($1, $2, 10, 'completed') RETURNING id", a, b).Scan(&transferID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO ledger_entries (transfer_id, account_id, delta) VALUES ($1, $2, -10), ($1, $3, 9)", transferID, a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(ctx, tx); !errors.Is(err, ErrInvariantViolation) {
+		t.Fatalf("commit: err = %v, want ErrInvariantViolation", err)
+	}
+	AssertBalance(t, s, a, 100)
+	AssertInvariants(t, s)
+}
@@ -0,0 +1,176 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// Paging through an account's entries, each balance_after is the previous
+// one plus the entry's delta, and the last equals the stored balance. A
+// page size of two puts cursor boundaries mid-history, where the running
+// sum has to be seeded from the entries before the cursor.
+func TestEntriesRunningBalance(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 500), SeedAccount(t, s, 300)
+
+	moves := []struct{ from, to, amount int64 }{{a, b, 100}, {b, a, 40}, {a, b, 25}, {b, a, 5}, {a, b, 70}}
+	for i, m := range moves {
+		resp, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: m.from, ToAccountID: m.to, Amount: m.amount}, fmt.Sprintf("run-%d", i), "h")
+		if err != nil {
+			t.Fatalf("transfer %d: %v", i, err)
+		}
+		// A transfer's own entries carry the balances it left behind.
+		for _, e := range resp.Entries {
+			AssertBalance(t, s, e.AccountID, e.BalanceAfter)
+		}
+	}
+
+	for _, acct := range []struct{ id, opening int64 }{{a, 500}, {b, 300}} {
+		running, seen := acct.opening, 0
+		var after *Cursor
+		for {
+			page, err := s.GetEntries(ctx, acct.id, after, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range page.Entries {
+				running += e.Delta
+				if e.BalanceAfter != running {
+					t.Errorf("account %d entry %d: balance_after = %d, want %d", acct.id, e.ID, e.BalanceAfter, running)
+				}
+				seen++
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			if after, err = DecodeCursor(page.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seen != len(moves) {
+			t.Errorf("account %d: %d entries, want %d", acct.id, seen, len(moves))
+		}
+		AssertBalance(t, s, acct.id, running)
+	}
+}
+
+// Entries read through an account carry their parent transfer's type and
+// effective date, so a statement needs no join on the client.
+func TestEntriesEnrichedFromTransfer(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+
+	parents := map[int64]domain.Transfer{}
+	for i, req := range []domain.TransferRequest{
+		{FromAccountID: a, ToAccountID: b, Amount: 100, Type: domain.TransferTypePayment},
+		{FromAccountID: a, ToAccountID: b, Amount: 5, Type: domain.TransferTypeFee, EffectiveDate: "2024-03-31"},
+		{FromAccountID: b, ToAccountID: a, Amount: 20, Type: domain.TransferTypeRefund},
+	} {
+		resp, err := s.ExecTransfer(ctx, req, fmt.Sprintf("typed-%d", i), "h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parents[resp.Transfer.ID] = resp.Transfer
+	}
+
+	page, err := s.GetEntries(ctx, a, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range page.Entries {
+		if want := parents[e.TransferID].Type; e.TransferType != want {
+			t.Errorf("entries: entry %d type %q, transfer %d is %q", e.ID, e.TransferType, e.TransferID, want)
+		}
+	}
+
+	st, err := s.GetStatement(ctx, a, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Entries) != 3 {
+		t.Fatalf("statement has %d entries, want 3", len(st.Entries))
+	}
+	for _, e := range st.Entries {
+		parent := parents[e.TransferID]
+		if e.TransferType != parent.Type || e.EffectiveDate != parent.EffectiveDate {
+			t.Errorf("statement: entry %d has %q/%s, transfer %d has %q/%s", e.ID, e.TransferType, e.EffectiveDate, e.TransferID, parent.Type, parent.EffectiveDate)
+		}
+	}
+}
+
+// A hot account's history comes back in bounded pages however long it is:
+// no page exceeds the limit, and following the cursors visits every entry
+// once, in ID order.
+func TestEntriesPagesAreBounded(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 5000), SeedAccount(t, s, 0)
+
+	// Write the history in bulk; one ExecTransfer per entry would dominate
+	// the test's runtime.
+	const transfers = 1200
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `
+		WITH t AS (
+			INSERT INTO transfers (from_account_id, to_account_id, amount, status)
+			SELECT $1, $2, 1, 'completed' FROM generate_series(1, $3)
+			RETURNING id
+		)
+		INSERT INTO ledger_entries (transfer_id, account_id, delta)
+		SELECT id, $1, -1 FROM t UNION ALL SELECT id, $2, 1 FROM t`, a, b, transfers); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance + CASE id WHEN $1 THEN -$3::bigint ELSE $3::bigint END WHERE id IN ($1, $2)", a, b, transfers); err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	const limit = 200
+	var after *Cursor
+	var lastID int64
+	pages, seen := 0, 0
+	for {
+		page, err := s.GetEntries(ctx, a, after, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if len(page.Entries) > limit {
+			t.Fatalf("page %d has %d entries, limit %d", pages, len(page.Entries), limit)
+		}
+		for _, e := range page.Entries {
+			if e.ID <= lastID {
+				t.Fatalf("entry %d after %d", e.ID, lastID)
+			}
+			lastID = e.ID
+			seen++
+		}
+		if page.NextCursor == "" {
+			if n := len(page.Entries); n == 0 || page.Entries[n-1].BalanceAfter != 5000-transfers {
+				t.Errorf("last page ends at %+v, want balance_after %d", page.Entries, 5000-transfers)
+			}
+			break
+		}
+		if after, err = DecodeCursor(page.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if seen != transfers || pages != transfers/limit {
+		t.Errorf("%d entries over %d pages, want %d over %d", seen, pages, transfers, transfers/limit)
+	}
+	AssertInvariants(t, s)
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+var (
+	ErrEscrowNotFound = errors.New("escrow not found")
+	ErrEscrowNotHeld  = errors.New("escrow already settled")
+)
+
+// CreateEscrow moves the amount from the buyer into escrowAccountID and
+// records the escrow as held.
+func (s *LedgerStore) CreateEscrow(ctx context.Context, escrowAccountID int64, req domain.EscrowRequest, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	return retrySerialization(ctx, func() (*domain.EscrowResponse, error) {
+		return s.createEscrow(ctx, escrowAccountID, req, idempotencyKey, reqHash)
+	})
+}
+
+func (s *LedgerStore) createEscrow(ctx context.Context, escrowAccountID int64, req domain.EscrowRequest, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if req.BuyerAccountID == req.SellerAccountID || req.BuyerAccountID == escrowAccountID || req.SellerAccountID == escrowAccountID {
+		return nil, ErrSelfTransfer
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	if cached, err := s.idem.Reserve(ctx, tx, OpEscrowCreate, idempotencyKey, reqHash); err != nil || cached != nil {
+		return replayEscrow(cached, err)
+	}
+
+	accounts, err := lockAccounts(ctx, tx, req.BuyerAccountID, escrowAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := accounts[req.BuyerAccountID].checkDebit(req.Amount); err != nil {
+		return nil, err
+	}
+	// The seller isn't touched until release, but it must exist.
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM accounts WHERE id = $1)", req.SellerAccountID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrAccountNotFound
+	}
+
+	hold, err := s.moveFunds(ctx, tx, req.BuyerAccountID, escrowAccountID, req.Amount, domain.TransferTypePayment, "")
+	if err != nil {
+		return nil, err
+	}
+
+	e := domain.Escrow{
+		BuyerAccountID:  req.BuyerAccountID,
+		SellerAccountID: req.SellerAccountID,
+		EscrowAccountID: escrowAccountID,
+		Amount:          req.Amount,
+		State:           "held",
+		HoldTransferID:  hold.Transfer.ID,
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO escrows (buyer_account_id, seller_account_id, escrow_account_id, amount, state, hold_transfer_id)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`,
+		e.BuyerAccountID, e.SellerAccountID, e.EscrowAccountID, e.Amount, e.State, e.HoldTransferID,
+	).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.EscrowResponse{Escrow: e, Transfer: *hold}
+	if err := s.idem.Complete(ctx, tx, idempotencyKey, 201, resp, hold.Transfer.ID); err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(req.BuyerAccountID, escrowAccountID)
+	return resp, nil
+}
+
+// ReleaseEscrow pays a held escrow out to the seller.
+func (s *LedgerStore) ReleaseEscrow(ctx context.Context, id int64, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	return s.settleEscrow(ctx, id, "released", OpEscrowRelease, idempotencyKey, reqHash)
+}
+
+// RefundEscrow returns a held escrow to the buyer.
+func (s *LedgerStore) RefundEscrow(ctx context.Context, id int64, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	return s.settleEscrow(ctx, id, "refunded", OpEscrowRefund, idempotencyKey, reqHash)
+}
+
+func (s *LedgerStore) settleEscrow(ctx context.Context, id int64, state, op, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	return retrySerialization(ctx, func() (*domain.EscrowResponse, error) {
+		return s.settleEscrowOnce(ctx, id, state, op, idempotencyKey, reqHash)
+	})
+}
+
+func (s *LedgerStore) settleEscrowOnce(ctx context.Context, id int64, state, op, idempotencyKey, reqHash string) (*domain.EscrowResponse, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	if cached, err := s.idem.Reserve(ctx, tx, op, idempotencyKey, reqHash); err != nil || cached != nil {
+		return replayEscrow(cached, err)
+	}
+
+	// The escrow row lock serializes competing release/refund calls; the
+	// loser sees the settled state and is rejected.
+	var e domain.Escrow
+	err = tx.QueryRow(ctx, `
+		SELECT id, buyer_account_id, seller_account_id, escrow_account_id, amount, state, hold_transfer_id, created_at
+		FROM escrows WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&e.ID, &e.BuyerAccountID, &e.SellerAccountID, &e.EscrowAccountID, &e.Amount, &e.State, &e.HoldTransferID, &e.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrEscrowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.State != "held" {
+		return nil, ErrEscrowNotHeld
+	}
+
+	payee, typ := e.SellerAccountID, domain.TransferTypePayment
+	if state == "refunded" {
+		payee, typ = e.BuyerAccountID, domain.TransferTypeRefund
+	}
+	accounts, err := lockAccounts(ctx, tx, e.EscrowAccountID, payee)
+	if err != nil {
+		return nil, err
+	}
+	if err := accounts[e.EscrowAccountID].checkDebit(e.Amount); err != nil {
+		return nil, err
+	}
+	settle, err := s.moveFunds(ctx, tx, e.EscrowAccountID, payee, e.Amount, typ, "")
+	if err != nil {
+		return nil, err
+	}
+
+	e.State = state
+	e.SettleTransferID = &settle.Transfer.ID
+	err = tx.QueryRow(ctx,
+		"UPDATE escrows SET state = $1, settle_transfer_id = $2, updated_at = now() WHERE id = $3 RETURNING updated_at",
+		e.State, settle.Transfer.ID, e.ID).Scan(&e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.EscrowResponse{Escrow: e, Transfer: *settle}
+	if err := s.idem.Complete(ctx, tx, idempotencyKey, 200, resp, settle.Transfer.ID); err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(e.EscrowAccountID, payee)
+	return resp, nil
+}
+
+// replayEscrow turns the result of Reserve into an early return: either
+// the reservation error or the cached response.
+func replayEscrow(cached json.RawMessage, err error) (*domain.EscrowResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	var resp domain.EscrowResponse
+	if err := json.Unmarshal(cached, &resp); err != nil {
+		return nil, err
+	}
+	resp.Replayed = true
+	return &resp, nil
+}
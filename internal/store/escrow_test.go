@@ -0,0 +1,73 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestEscrowReleaseAndRefund(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	escrow := SeedAccount(t, s, 0)
+	buyer, seller := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	hold := func(key string, amount int64) int64 {
+		t.Helper()
+		resp, err := s.CreateEscrow(ctx, escrow, domain.EscrowRequest{BuyerAccountID: buyer, SellerAccountID: seller, Amount: amount}, key, key)
+		if err != nil {
+			t.Fatalf("hold %s: %v", key, err)
+		}
+		if resp.Escrow.State != "held" {
+			t.Errorf("hold %s: state %q", key, resp.Escrow.State)
+		}
+		return resp.Escrow.ID
+	}
+
+	released, refunded := hold("hold-1", 300), hold("hold-2", 200)
+	AssertBalance(t, s, buyer, 500)
+	AssertBalance(t, s, escrow, 500)
+
+	resp, err := s.ReleaseEscrow(ctx, released, "release-1", "release-1")
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if resp.Escrow.State != "released" || resp.Escrow.SettleTransferID == nil || resp.Transfer.Transfer.ToAccountID != seller {
+		t.Errorf("release = %+v", resp.Escrow)
+	}
+	resp, err = s.RefundEscrow(ctx, refunded, "refund-2", "refund-2")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if resp.Escrow.State != "refunded" || resp.Transfer.Transfer.ToAccountID != buyer {
+		t.Errorf("refund = %+v", resp.Escrow)
+	}
+	AssertBalance(t, s, buyer, 700)
+	AssertBalance(t, s, seller, 300)
+	AssertBalance(t, s, escrow, 0)
+
+	// Settled is final, whichever way it went.
+	if _, err := s.RefundEscrow(ctx, released, "refund-1", "refund-1"); !errors.Is(err, ErrEscrowNotHeld) {
+		t.Errorf("refund after release: err = %v, want ErrEscrowNotHeld", err)
+	}
+	if _, err := s.ReleaseEscrow(ctx, refunded, "release-2", "release-2"); !errors.Is(err, ErrEscrowNotHeld) {
+		t.Errorf("release after refund: err = %v, want ErrEscrowNotHeld", err)
+	}
+	if _, err := s.ReleaseEscrow(ctx, 999, "release-x", "release-x"); !errors.Is(err, ErrEscrowNotFound) {
+		t.Errorf("release of unknown escrow: err = %v, want ErrEscrowNotFound", err)
+	}
+	AssertInvariants(t, s)
+}
+
+func TestEscrowRejectsEscrowAccountAsParty(t *testing.T) {
+	s := NewTestStore(t)
+	escrow, buyer := SeedAccount(t, s, 0), SeedAccount(t, s, 1000)
+	_, err := s.CreateEscrow(context.Background(), escrow, domain.EscrowRequest{BuyerAccountID: buyer, SellerAccountID: escrow, Amount: 10}, "k", "k")
+	if !errors.Is(err, ErrSelfTransfer) {
+		t.Fatalf("err = %v, want ErrSelfTransfer", err)
+	}
+	AssertBalance(t, s, buyer, 1000)
+}
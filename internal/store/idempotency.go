@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+var (
+	ErrIdempotencyOperationMismatch = errors.New("idempotency key belongs to a different operation")
+	ErrKeyNotFound                  = errors.New("no transfer for idempotency key")
+	ErrKeyInProgress                = errors.New("idempotency key request still in progress")
+)
+
+// Operations that reserve idempotency keys.
+const (
+	OpTransfer      = "transfer"
+	OpChain         = "chain"
+	OpEscrowCreate  = "escrow.create"
+	OpEscrowRelease = "escrow.release"
+	OpEscrowRefund  = "escrow.refund"
+)
+
+// IdempotencyStore records idempotency keys and the responses they replay.
+// Reserve and Complete run inside the caller's write transaction: the key
+// must commit or roll back together with the work it guards, which is what
+// makes retries exactly-once. Lookup is a read outside any transaction for
+// implementations that can answer replays cheaply (for example a Redis
+// cache in front of PostgresIdempotency); a nil result only means "not
+// known here", and the transactional Reserve stays the source of truth.
+type IdempotencyStore interface {
+	// Reserve claims key for op, or returns the stored response to replay.
+	Reserve(ctx context.Context, tx pgx.Tx, op, key, reqHash string) (json.RawMessage, error)
+	// Complete stores the response for a key reserved in tx.
+	Complete(ctx context.Context, tx pgx.Tx, key string, status int, resp any, transferIDs ...int64) error
+	// Lookup returns the completed response for key, if known.
+	Lookup(ctx context.Context, op, key, reqHash string) (json.RawMessage, error)
+}
+
+// PostgresIdempotency keeps keys in the idempotency_keys table. It is the
+// default IdempotencyStore.
+type PostgresIdempotency struct {
+	db            *pgxpool.Pool
+	maxStoredBody int // responses above this are not stored; 0 means no cap
+}
+
+func NewPostgresIdempotency(db *pgxpool.Pool, maxStoredBody int) *PostgresIdempotency {
+	return &PostgresIdempotency{db: db, maxStoredBody: maxStoredBody}
+}
+
+type keyTTLKey struct{}
+
+// WithKeyTTL asks that a key reserved under ctx expire after ttl instead of
+// living for the default retention. Expired keys are reaped early and no
+// longer dedupe.
+func WithKeyTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, keyTTLKey{}, ttl)
+}
+
+// keyExpiry returns the expiry for a key reserved now, or nil for the
+// default retention.
+func keyExpiry(ctx context.Context) *time.Time {
+	ttl, ok := ctx.Value(keyTTLKey{}).(time.Duration)
+	if !ok {
+		return nil
+	}
+	t := time.Now().Add(ttl)
+	return &t
+}
+
+// Reserve claims an idempotency key for op inside tx. If the key already
+// holds a completed response for op, that response is returned for replay and
+// nothing is reserved. Otherwise an "in_progress" marker is inserted; it commits or
+// rolls back together with the caller's work. No path commits the marker on
+// its own: a request canceled before commit leaves neither a wedged key nor
+// a partial balance change behind.
+func (p *PostgresIdempotency) Reserve(ctx context.Context, tx pgx.Tx, op, key, reqHash string) (json.RawMessage, error) {
+	var storedStatus string
+	var storedBody json.RawMessage
+	var storedHash string
+	var storedOp string
+	var omitted []int64
+	var expired bool
+
+	// The key alone isn't unique across daily partitions, so two first
+	// requests either side of UTC midnight could both insert it. Holding a
+	// per-key lock until tx ends makes the lookup and insert one step.
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key); err != nil {
+		return nil, err
+	}
+
+	err := tx.QueryRow(ctx,
+		"SELECT status, response_body, request_hash, operation, transfer_ids, COALESCE(expires_at < now(), false) FROM idempotency_keys WHERE key = $1 ORDER BY created_on LIMIT 1",
+		key).Scan(&storedStatus, &storedBody, &storedHash, &storedOp, &omitted, &expired)
+
+	if err == nil && expired {
+		// Past its TTL but not yet reaped: the key is free again.
+		if _, err := tx.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND expires_at < now()", key); err != nil {
+			return nil, err
+		}
+		err = pgx.ErrNoRows
+	}
+
+	if err == nil {
+		// Key exists
+		if storedOp != op {
+			return nil, ErrIdempotencyOperationMismatch
+		}
+		if storedHash != reqHash {
+			return nil, ErrKeyMismatch
+		}
+		if storedStatus == "in_progress" {
+			return nil, ErrConflict
+		}
+		if storedBody == nil && omitted != nil {
+			return rebuildResponse(ctx, tx, op, omitted)
+		}
+		return storedBody, nil
+	} else if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	// Insert "in_progress" marker
+	_, err = tx.Exec(ctx,
+		"INSERT INTO idempotency_keys (key, request_hash, status, operation, expires_at) VALUES ($1, $2, 'in_progress', $3, $4)",
+		key, reqHash, op, keyExpiry(ctx))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
+			return nil, ErrConflict
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Complete stores the response for a reserved key so retries replay it.
+// transferIDs are the transfers the response describes; when the body is
+// over maxStoredBody only they are kept, and the replay is rebuilt from
+// the transfer records.
+func (p *PostgresIdempotency) Complete(ctx context.Context, tx pgx.Tx, key string, status int, resp any, transferIDs ...int64) error {
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	var omitted []int64
+	if p.maxStoredBody > 0 && len(respBytes) > p.maxStoredBody && rebuildable(resp) {
+		log.Printf("idempotency key %q: %d-byte response not stored, replays rebuild it from transfers %v", key, len(respBytes), transferIDs)
+		respBytes, omitted = nil, transferIDs
+	}
+	tag, err := tx.Exec(ctx,
+		"UPDATE idempotency_keys SET status = 'completed', transfer_id = $1, response_status = $2, response_body = $3, transfer_ids = $4 WHERE key = $5 AND status = 'in_progress'",
+		transferIDs[0], status, respBytes, omitted, key)
+	if err != nil {
+		return err
+	}
+	// The marker must have been reserved in this same transaction, so it
+	// commits (or rolls back) atomically with the work it describes.
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("idempotency key %q: no in-progress reservation to complete", key)
+	}
+	return nil
+}
+
+// Lookup reads a completed, unexpired key outside any transaction. Reserve
+// already answers replays inside the transfer transaction, so the ledger
+// doesn't call this for Postgres; it exists for cache implementations that
+// delegate to it on a miss.
+func (p *PostgresIdempotency) Lookup(ctx context.Context, op, key, reqHash string) (json.RawMessage, error) {
+	var storedBody json.RawMessage
+	var storedHash, storedOp string
+	var omitted []int64
+	err := p.db.QueryRow(ctx, `
+		SELECT response_body, request_hash, operation, transfer_ids FROM idempotency_keys
+		WHERE key = $1 AND status = 'completed' AND (expires_at IS NULL OR expires_at >= now())
+		ORDER BY created_on LIMIT 1`, key).Scan(&storedBody, &storedHash, &storedOp, &omitted)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if storedOp != op {
+		return nil, ErrIdempotencyOperationMismatch
+	}
+	if storedHash != reqHash {
+		return nil, ErrKeyMismatch
+	}
+	if storedBody == nil && omitted != nil {
+		return rebuildResponse(ctx, p.db, op, omitted)
+	}
+	return storedBody, nil
+}
+
+func rebuildable(resp any) bool {
+	switch resp.(type) {
+	case *domain.TransferResponse, *domain.ChainResponse:
+		return true
+	}
+	return false
+}
+
+// rebuildResponse re-derives a replay body that was too large to store.
+// Only operations whose response is fully determined by their transfers
+// can be rebuilt; escrow responses are small, fixed-size and always stored.
+func rebuildResponse(ctx context.Context, q querier, op string, transferIDs []int64) (json.RawMessage, error) {
+	var transfers []domain.TransferResponse
+	for _, id := range transferIDs {
+		t, err := loadTransfer(ctx, q, "id = $1", id)
+		if err != nil {
+			return nil, fmt.Errorf("rebuild idempotent response: transfer %d: %w", id, err)
+		}
+		transfers = append(transfers, *t)
+	}
+
+	switch op {
+	case OpTransfer:
+		return json.Marshal(transfers[0])
+	case OpChain:
+		return json.Marshal(domain.ChainResponse{TransferIDs: transferIDs, Transfers: transfers})
+	default:
+		return nil, fmt.Errorf("rebuild idempotent response: unsupported operation %q", op)
+	}
+}
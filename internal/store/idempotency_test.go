@@ -0,0 +1,226 @@
+//go:build integration
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// A key belongs to the operation that first reserved it; another operation
+// presenting it is refused instead of being handed a foreign response.
+func TestIdempotencyKeyScopedToOperation(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b, escrow := SeedAccount(t, s, 1000), SeedAccount(t, s, 0), SeedAccount(t, s, 0)
+	hop := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}
+
+	if _, err := s.ExecTransfer(ctx, hop, "shared", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExecChain(ctx, domain.ChainRequest{Hops: []domain.TransferRequest{hop}}, "shared", "h"); !errors.Is(err, ErrIdempotencyOperationMismatch) {
+		t.Errorf("chain reusing a transfer key: err = %v, want ErrIdempotencyOperationMismatch", err)
+	}
+	if _, err := s.CreateEscrow(ctx, escrow, domain.EscrowRequest{BuyerAccountID: a, SellerAccountID: b, Amount: 10}, "shared", "h"); !errors.Is(err, ErrIdempotencyOperationMismatch) {
+		t.Errorf("escrow reusing a transfer key: err = %v, want ErrIdempotencyOperationMismatch", err)
+	}
+	if _, err := s.ExecTransfer(ctx, hop, "shared", "other"); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("same operation, different request: err = %v, want ErrKeyMismatch", err)
+	}
+	AssertBalance(t, s, a, 990)
+	AssertBalance(t, s, escrow, 0)
+}
+
+// Over the body limit only the transfer IDs are kept, and a replay rebuilt
+// from the transfer records is indistinguishable from the original.
+func TestOversizedResponseReplaysFromTransfers(t *testing.T) {
+	s := NewTestStore(t, WithIdempotencyBodyLimit(64))
+	ctx := context.Background()
+	a, b, c := SeedAccount(t, s, 1000), SeedAccount(t, s, 0), SeedAccount(t, s, 0)
+
+	stored := func(key string) (body []byte, ids []int64) {
+		t.Helper()
+		if err := s.db.QueryRow(ctx, "SELECT response_body, transfer_ids FROM idempotency_keys WHERE key = $1", key).Scan(&body, &ids); err != nil {
+			t.Fatal(err)
+		}
+		return body, ids
+	}
+
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}
+	first, err := s.ExecTransfer(ctx, req, "big-transfer", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, ids := stored("big-transfer"); body != nil || len(ids) != 1 || ids[0] != first.Transfer.ID {
+		t.Fatalf("stored body %q, transfer_ids %v; want no body and [%d]", body, ids, first.Transfer.ID)
+	}
+	replay, err := s.ExecTransfer(ctx, req, "big-transfer", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, first, replay)
+
+	chain := domain.ChainRequest{Hops: []domain.TransferRequest{{FromAccountID: a, ToAccountID: b, Amount: 10}, {FromAccountID: b, ToAccountID: c, Amount: 10}}}
+	firstChain, err := s.ExecChain(ctx, chain, "big-chain", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, ids := stored("big-chain"); body != nil || len(ids) != 2 {
+		t.Fatalf("stored body %q, transfer_ids %v; want no body and two IDs", body, ids)
+	}
+	replayChain, err := s.ExecChain(ctx, chain, "big-chain", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameJSON(t, firstChain, replayChain)
+
+	AssertBalance(t, s, a, 890)
+	AssertBalance(t, s, c, 10)
+	AssertInvariants(t, s)
+}
+
+// A stored response written before the body carried the transfer's IDs
+// still replays with them, reloaded from the transfer itself.
+func TestReplayRestoresMissingIDs(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}
+	first, err := s.ExecTransfer(ctx, req, "old-body", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, strip := range []string{"{transfer,public_id}", "{transfer,id}"} {
+		if _, err := s.db.Exec(ctx, "UPDATE idempotency_keys SET response_body = response_body #- $2::text[] WHERE key = $1", "old-body", strip); err != nil {
+			t.Fatal(err)
+		}
+		replay, err := s.ExecTransfer(ctx, req, "old-body", "h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !replay.Replayed || replay.Transfer.ID != first.Transfer.ID || replay.Transfer.PublicID != first.Transfer.PublicID {
+			t.Errorf("without %s: replayed %v as transfer %d (%q), want %d (%q)", strip,
+				replay.Replayed, replay.Transfer.ID, replay.Transfer.PublicID, first.Transfer.ID, first.Transfer.PublicID)
+		}
+	}
+	AssertBalance(t, s, a, 900)
+}
+
+func assertSameJSON(t *testing.T, want, got any) {
+	t.Helper()
+	w, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w, g) {
+		t.Errorf("replay differs from the original:\n got %s\nwant %s", g, w)
+	}
+}
+
+func TestKeyTTLReaping(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}
+
+	for key, ttl := range map[string]time.Duration{"short": time.Millisecond, "long": 24 * time.Hour} {
+		if _, err := s.ExecTransfer(WithKeyTTL(ctx, ttl), req, key, "h"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.ExecTransfer(ctx, req, "default", "h"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	expired, err := s.ExpiredIdempotencyKeys(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].Key != "short" {
+		t.Fatalf("expired = %+v, want only the short-TTL key", expired)
+	}
+	if err := s.DeleteExpiredIdempotencyKey(ctx, expired[0]); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := s.db.Query(ctx, "SELECT key FROM idempotency_keys ORDER BY key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"default", "long"}) {
+		t.Errorf("keys after reaping = %v, want [default long]", keys)
+	}
+}
+
+// At Serializable a racing duplicate can fail with 40001 rather than a
+// unique violation. Either way the race must resolve to one creation, with
+// every other request replaying it or told the key is in progress.
+func TestSerializableDuplicateKeys(t *testing.T) {
+	s := NewTestStore(t, WithIsolation(pgx.Serializable))
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}
+
+	const racers = 32
+	var created, replayed, inProgress atomic.Int64
+	errs := make(chan error, racers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			resp, err := s.ExecTransfer(ctx, req, "same-key", "h")
+			switch {
+			case errors.Is(err, ErrConflict):
+				inProgress.Add(1)
+			case err != nil:
+				errs <- err
+			case resp.Replayed:
+				replayed.Add(1)
+			default:
+				created.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("racer failed: %v", err)
+	}
+
+	if created.Load() != 1 {
+		t.Fatalf("%d creations (%d replays, %d in progress), want exactly 1", created.Load(), replayed.Load(), inProgress.Load())
+	}
+	var transfers int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transfers").Scan(&transfers); err != nil {
+		t.Fatal(err)
+	}
+	if transfers != 1 {
+		t.Errorf("%d transfers, want 1", transfers)
+	}
+	AssertBalance(t, s, a, 900)
+	AssertBalance(t, s, b, 100)
+	AssertInvariants(t, s)
+}
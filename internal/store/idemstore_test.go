@@ -0,0 +1,135 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// memIdempotency is an in-memory IdempotencyStore recording how the store
+// drives it. It ignores tx, so unlike PostgresIdempotency a reservation
+// survives a rollback; the tests below only roll back before Reserve.
+type memIdempotency struct {
+	mu       sync.Mutex
+	keys     map[string]*memKey
+	reserves []string // "op key" per Reserve call
+	txs      []pgx.Tx // tx passed to each Reserve and Complete call
+}
+
+type memKey struct {
+	op, hash    string
+	status      int
+	body        json.RawMessage
+	transferIDs []int64
+}
+
+func newMemIdempotency() *memIdempotency {
+	return &memIdempotency{keys: make(map[string]*memKey)}
+}
+
+func (m *memIdempotency) Reserve(_ context.Context, tx pgx.Tx, op, key, reqHash string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reserves = append(m.reserves, op+" "+key)
+	m.txs = append(m.txs, tx)
+	k, ok := m.keys[key]
+	switch {
+	case !ok:
+		m.keys[key] = &memKey{op: op, hash: reqHash}
+		return nil, nil
+	case k.op != op:
+		return nil, ErrIdempotencyOperationMismatch
+	case k.hash != reqHash:
+		return nil, ErrKeyMismatch
+	case k.body == nil:
+		return nil, ErrConflict
+	}
+	return k.body, nil
+}
+
+func (m *memIdempotency) Complete(_ context.Context, tx pgx.Tx, key string, status int, resp any, transferIDs ...int64) error {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.txs = append(m.txs, tx)
+	k := m.keys[key]
+	k.status, k.body, k.transferIDs = status, body, transferIDs
+	return nil
+}
+
+func (m *memIdempotency) Lookup(_ context.Context, op, key, reqHash string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.keys[key]; ok && k.op == op && k.hash == reqHash {
+		return k.body, nil
+	}
+	return nil, nil
+}
+
+// The store goes through whatever IdempotencyStore it is given: it reserves
+// and completes inside the transfer's own transaction, replays what the
+// backend returns, and surfaces the backend's refusals unchanged.
+func TestIdempotencyStoreIsPluggable(t *testing.T) {
+	idem := newMemIdempotency()
+	s := NewTestStore(t, WithIdempotencyStore(idem))
+	ctx := context.Background()
+	a, b, c := SeedAccount(t, s, 1000), SeedAccount(t, s, 0), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}
+
+	first, err := s.ExecTransfer(ctx, req, "k1", "h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idem.txs) != 2 || idem.txs[0] == nil || idem.txs[0] != idem.txs[1] {
+		t.Fatalf("Reserve and Complete ran in txs %v, want one shared transaction", idem.txs)
+	}
+	stored := idem.keys["k1"]
+	if stored.status != 201 || len(stored.transferIDs) != 1 || stored.transferIDs[0] != first.Transfer.ID {
+		t.Errorf("completed key %+v, want 201 for transfer %d", stored, first.Transfer.ID)
+	}
+
+	replay, err := s.ExecTransfer(ctx, req, "k1", "h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replay.Replayed || replay.Transfer.ID != first.Transfer.ID {
+		t.Errorf("replay %+v, want transfer %d replayed", replay, first.Transfer.ID)
+	}
+	AssertBalance(t, s, a, 900)
+
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 5}, "k1", "h2"); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("reused key with another body: err = %v, want ErrKeyMismatch", err)
+	}
+	chain := domain.ChainRequest{Hops: []domain.TransferRequest{{FromAccountID: a, ToAccountID: b, Amount: 10}, {FromAccountID: b, ToAccountID: c, Amount: 10}}}
+	if _, err := s.ExecChain(ctx, chain, "k1", "h1"); !errors.Is(err, ErrIdempotencyOperationMismatch) {
+		t.Errorf("transfer key reused for a chain: err = %v, want ErrIdempotencyOperationMismatch", err)
+	}
+	if _, err := s.ExecChain(ctx, chain, "k2", "h3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := idem.reserves[len(idem.reserves)-1]; got != OpChain+" k2" {
+		t.Errorf("chain reserved %q, want %q", got, OpChain+" k2")
+	}
+
+	// The Postgres table was never touched.
+	var rows int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM idempotency_keys").Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 0 {
+		t.Errorf("%d rows in idempotency_keys, want 0", rows)
+	}
+	AssertBalance(t, s, a, 890)
+	AssertBalance(t, s, c, 10)
+	AssertInvariants(t, s)
+}
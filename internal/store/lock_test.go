@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// lockTx answers lockAccounts' single query from a fixed set of accounts and
+// records the IDs it was asked for. Only Query is implemented.
+type lockTx struct {
+	pgx.Tx
+	balances map[int64]int64
+	queryErr error // returned by Query
+	rowsErr  error // returned by Rows.Err after the scan
+	asked    []int64
+}
+
+func (tx *lockTx) Query(_ context.Context, _ string, args ...any) (pgx.Rows, error) {
+	tx.asked = args[0].([]int64)
+	if tx.queryErr != nil {
+		return nil, tx.queryErr
+	}
+	rows := &lockRows{err: tx.rowsErr}
+	for _, id := range tx.asked {
+		if b, ok := tx.balances[id]; ok {
+			rows.ids = append(rows.ids, id)
+			rows.balances = append(rows.balances, b)
+		}
+	}
+	return rows, nil
+}
+
+type lockRows struct {
+	pgx.Rows
+	ids      []int64
+	balances []int64
+	pos      int
+	err      error
+}
+
+func (r *lockRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.ids)
+}
+
+func (r *lockRows) Scan(dest ...any) error {
+	*dest[0].(*int64) = r.ids[r.pos-1]
+	*dest[1].(*int64) = r.balances[r.pos-1]
+	*dest[2].(*int64) = 0
+	return nil
+}
+
+func (r *lockRows) Close()     {}
+func (r *lockRows) Err() error { return r.err }
+
+func TestLockAccountsOrder(t *testing.T) {
+	tx := &lockTx{balances: map[int64]int64{3: 30, 7: 70, 9: 90}}
+	got, err := lockAccounts(context.Background(), tx, 9, 3, 7, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tx.asked, []int64{3, 7, 9}) {
+		t.Errorf("locked %v, want ascending and de-duplicated [3 7 9]", tx.asked)
+	}
+	for id, want := range tx.balances {
+		if got[id] == nil || got[id].balance != want {
+			t.Errorf("account %d: got %+v, want balance %d", id, got[id], want)
+		}
+	}
+}
+
+func TestLockAccountsErrors(t *testing.T) {
+	busy := &pgconn.PgError{Code: "55P03"}
+	broken := errors.New("connection reset")
+	cases := []struct {
+		name string
+		tx   *lockTx
+		want error
+	}{
+		{"missing account", &lockTx{balances: map[int64]int64{1: 10}}, ErrAccountNotFound},
+		{"lock unavailable on query", &lockTx{queryErr: busy}, ErrLockContention},
+		{"lock unavailable while reading", &lockTx{balances: map[int64]int64{1: 10}, rowsErr: busy}, ErrLockContention},
+		{"query error", &lockTx{queryErr: broken}, broken},
+		{"read error", &lockTx{balances: map[int64]int64{1: 10}, rowsErr: broken}, broken},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := lockAccounts(context.Background(), tc.tx, 1, 2)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			// A failed lock is never reported as a missing account.
+			if tc.want != ErrAccountNotFound && errors.Is(err, ErrAccountNotFound) {
+				t.Errorf("err = %v classified as not found", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// ErrAlreadyOpened means an account in the import already has an opening
+// balance from an earlier import.
+var ErrAlreadyOpened = errors.New("account already has an opening balance")
+
+// ImportOpeningBalances credits each account its opening balance with a
+// transfer from equityID, all in one transaction, so the ledger invariant
+// holds from the first entry. The equity account is marked allow_negative
+// on first use since it carries the other side of every opening balance.
+// Repeating an ImportID replays its totals; reusing one with a different
+// request fails with ErrKeyMismatch.
+func (s *LedgerStore) ImportOpeningBalances(ctx context.Context, equityID int64, req domain.OpeningBalancesRequest, reqHash string) (*domain.OpeningBalancesResult, error) {
+	return retrySerialization(ctx, func() (*domain.OpeningBalancesResult, error) {
+		return s.importOpeningBalances(ctx, equityID, req, reqHash)
+	})
+}
+
+func (s *LedgerStore) importOpeningBalances(ctx context.Context, equityID int64, req domain.OpeningBalancesRequest, reqHash string) (*domain.OpeningBalancesResult, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	// A concurrent import with the same ID blocks here until the first
+	// commits, then takes the replay path.
+	tag, err := tx.Exec(ctx,
+		"INSERT INTO opening_balance_imports (import_id, request_hash) VALUES ($1, $2) ON CONFLICT (import_id) DO NOTHING",
+		req.ImportID, reqHash)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		var hash string
+		if err := tx.QueryRow(ctx,
+			"SELECT request_hash FROM opening_balance_imports WHERE import_id = $1", req.ImportID).Scan(&hash); err != nil {
+			return nil, err
+		}
+		if hash != reqHash {
+			return nil, ErrKeyMismatch
+		}
+		res, err := openingTotals(ctx, tx, req.ImportID)
+		if err != nil {
+			return nil, err
+		}
+		res.Replayed = true
+		return res, nil
+	}
+
+	ids := make([]int64, 0, len(req.Balances)+1)
+	ids = append(ids, equityID)
+	for _, b := range req.Balances {
+		ids = append(ids, b.AccountID)
+	}
+	if _, err := lockAccounts(ctx, tx, ids...); err != nil {
+		return nil, err
+	}
+	var opened bool
+	if err := tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM opening_balances WHERE account_id = ANY($1))", ids[1:]).Scan(&opened); err != nil {
+		return nil, err
+	}
+	if opened {
+		return nil, ErrAlreadyOpened
+	}
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET allow_negative = true WHERE id = $1", equityID); err != nil {
+		return nil, err
+	}
+
+	for _, b := range req.Balances {
+		moved, err := s.moveFunds(ctx, tx, equityID, b.AccountID, b.Amount, domain.TransferTypeAdjustment, "")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO opening_balances (account_id, import_id, transfer_id, amount) VALUES ($1, $2, $3, $4)",
+			b.AccountID, req.ImportID, moved.Transfer.ID, b.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := openingTotals(ctx, tx, req.ImportID)
+	if err != nil {
+		return nil, err
+	}
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(ids...)
+	return res, nil
+}
+
+func openingTotals(ctx context.Context, tx pgx.Tx, importID string) (*domain.OpeningBalancesResult, error) {
+	res := &domain.OpeningBalancesResult{ImportID: importID}
+	err := tx.QueryRow(ctx,
+		"SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM opening_balances WHERE import_id = $1",
+		importID).Scan(&res.Accounts, &res.Total)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
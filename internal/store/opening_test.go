@@ -0,0 +1,68 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestOpeningBalancesOffsetEquity(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	equity := SeedAccount(t, s, 0)
+	amounts := []int64{1500, 20, 999_999, 1}
+	var sum int64
+	req := domain.OpeningBalancesRequest{ImportID: "run-1"}
+	for _, a := range amounts {
+		req.Balances = append(req.Balances, domain.OpeningBalance{AccountID: SeedAccount(t, s, 0), Amount: a})
+		sum += a
+	}
+
+	res, err := s.ImportOpeningBalances(ctx, equity, req, "h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Accounts != int64(len(amounts)) || res.Total != sum || res.Replayed {
+		t.Errorf("result = %+v, want %d accounts totalling %d", res, len(amounts), sum)
+	}
+	for _, b := range req.Balances {
+		AssertBalance(t, s, b.AccountID, b.Amount)
+	}
+	AssertBalance(t, s, equity, -sum)
+	AssertInvariants(t, s)
+
+	// The same run replays without posting again.
+	res, err = s.ImportOpeningBalances(ctx, equity, req, "h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Replayed || res.Total != sum {
+		t.Errorf("replay = %+v", res)
+	}
+	AssertBalance(t, s, equity, -sum)
+
+	if _, err := s.ImportOpeningBalances(ctx, equity, req, "h2"); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("reused import_id: err = %v, want ErrKeyMismatch", err)
+	}
+
+	// A second run can open new accounts but not reopen old ones.
+	fresh := SeedAccount(t, s, 0)
+	again := domain.OpeningBalancesRequest{ImportID: "run-2", Balances: []domain.OpeningBalance{
+		{AccountID: fresh, Amount: 50},
+		{AccountID: req.Balances[0].AccountID, Amount: 50},
+	}}
+	if _, err := s.ImportOpeningBalances(ctx, equity, again, "h3"); !errors.Is(err, ErrAlreadyOpened) {
+		t.Errorf("reopening: err = %v, want ErrAlreadyOpened", err)
+	}
+	AssertBalance(t, s, fresh, 0)
+	again.ImportID, again.Balances = "run-3", again.Balances[:1]
+	if _, err := s.ImportOpeningBalances(ctx, equity, again, "h4"); err != nil {
+		t.Fatal(err)
+	}
+	AssertBalance(t, s, equity, -(sum + 50))
+	AssertInvariants(t, s)
+}
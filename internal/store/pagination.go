@@ -0,0 +1,53 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset pagination position: the (created_at, id) of the last
+// row on the previous page. Listings order by created_at DESC, id DESC, and
+// id breaks ties between rows sharing a timestamp.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Encode renders the cursor as an opaque, URL-safe token.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a token produced by Encode.
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: at, ID: id}, nil
+}
+
+// Predicate returns the WHERE clause selecting rows after the cursor, with
+// placeholders numbered from firstArg, and the arguments to bind.
+func (c Cursor) Predicate(firstArg int) (string, []any) {
+	return fmt.Sprintf("(created_at, id) < ($%d, $%d)", firstArg, firstArg+1), []any{c.CreatedAt, c.ID}
+}
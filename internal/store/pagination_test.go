@@ -0,0 +1,34 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.FixedZone("X", 3600)), ID: 42}
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, token := range []string{"", "!!!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXwx", "MjAyNi0wMS0wMVQwMDowMDowMFp8eA"} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) err = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestCursorPredicate(t *testing.T) {
+	c := Cursor{CreatedAt: time.Unix(0, 0), ID: 7}
+	cond, args := c.Predicate(3)
+	if cond != "(created_at, id) < ($3, $4)" || len(args) != 2 || args[1] != int64(7) {
+		t.Errorf("Predicate(3) = %q %v", cond, args)
+	}
+}
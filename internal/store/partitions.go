@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// idempotency_keys is range-partitioned by UTC day; each partition is named
+// after the day it holds.
+const (
+	idempotencyPartitionPrefix = "idempotency_keys_p"
+	idempotencyPartitionLayout = "20060102"
+)
+
+// EnsureIdempotencyPartitions creates the daily partitions for the day of
+// now and the following ahead days. Existing partitions are left alone.
+func (s *LedgerStore) EnsureIdempotencyPartitions(ctx context.Context, now time.Time, ahead int) error {
+	day := now.UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= ahead; i++ {
+		from := day.AddDate(0, 0, i)
+		name := pgx.Identifier{idempotencyPartitionPrefix + from.Format(idempotencyPartitionLayout)}.Sanitize()
+		_, err := s.db.Exec(ctx, fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF idempotency_keys FOR VALUES FROM ('%s') TO ('%s')",
+			name, from.Format(time.DateOnly), from.AddDate(0, 0, 1).Format(time.DateOnly)))
+		if err != nil {
+			return fmt.Errorf("create partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ExpiredIdempotencyPartitions lists the daily partitions whose whole day
+// ended at or before cutoff, oldest first.
+func (s *LedgerStore) ExpiredIdempotencyPartitions(ctx context.Context, cutoff time.Time) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'idempotency_keys'::regclass AND c.relname LIKE $1
+		ORDER BY c.relname`, idempotencyPartitionPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []string
+	for _, name := range names {
+		day, err := time.Parse(idempotencyPartitionLayout, strings.TrimPrefix(name, idempotencyPartitionPrefix))
+		if err != nil {
+			continue // not one of ours
+		}
+		if !day.AddDate(0, 0, 1).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	return expired, nil
+}
+
+// DropIdempotencyPartition drops one partition and every key in it.
+func (s *LedgerStore) DropIdempotencyPartition(ctx context.Context, name string) error {
+	if !strings.HasPrefix(name, idempotencyPartitionPrefix) {
+		return fmt.Errorf("%q is not an idempotency partition", name)
+	}
+	_, err := s.db.Exec(ctx, "DROP TABLE IF EXISTS "+pgx.Identifier{name}.Sanitize())
+	return err
+}
+
+// ExpiredKey identifies an idempotency key row past its client-requested TTL.
+type ExpiredKey struct {
+	Key       string
+	CreatedOn time.Time
+}
+
+// ExpiredIdempotencyKeys lists up to limit keys whose TTL has passed.
+// Keys without a TTL are only removed with their partition.
+func (s *LedgerStore) ExpiredIdempotencyKeys(ctx context.Context, limit int) ([]ExpiredKey, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT key, created_on FROM idempotency_keys WHERE expires_at < now() ORDER BY expires_at LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[ExpiredKey])
+}
+
+// DeleteExpiredIdempotencyKey removes k if it is still expired.
+func (s *LedgerStore) DeleteExpiredIdempotencyKey(ctx context.Context, k ExpiredKey) error {
+	_, err := s.db.Exec(ctx,
+		"DELETE FROM idempotency_keys WHERE key = $1 AND created_on = $2 AND expires_at < now()", k.Key, k.CreatedOn)
+	return err
+}
@@ -0,0 +1,101 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestIdempotencyPartitionDrop(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 0)
+
+	old := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.EnsureIdempotencyPartitions(ctx, old, 1); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.DropIdempotencyPartition(context.Background(), "idempotency_keys_p20000102") })
+	if _, err := s.db.Exec(ctx, "INSERT INTO idempotency_keys (key, request_hash, status, created_on) VALUES ('old', 'h', 'completed', '2000-01-01')"); err != nil {
+		t.Fatal(err)
+	}
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}
+	if _, err := s.ExecTransfer(ctx, req, "today", "h"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only a partition whose whole day ended by the cutoff is expired.
+	expired, err := s.ExpiredIdempotencyPartitions(ctx, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(expired, []string{"idempotency_keys_p20000101"}) {
+		t.Fatalf("expired = %v, want only idempotency_keys_p20000101", expired)
+	}
+	if err := s.DropIdempotencyPartition(ctx, expired[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM idempotency_keys WHERE key = 'old'").Scan(&n); err != nil || n != 0 {
+		t.Errorf("old key: %d rows, err %v; want it dropped with its partition", n, err)
+	}
+	// Today's key, in a live partition, still replays through the parent.
+	if _, err := s.ExecTransfer(ctx, req, "today", "h"); err != nil {
+		t.Fatal(err)
+	}
+	AssertBalance(t, s, a, 90)
+
+	if err := s.DropIdempotencyPartition(ctx, "transfers"); err == nil {
+		t.Error("dropped a table that is not an idempotency partition")
+	}
+}
+
+// A key reserved just before UTC midnight sits in yesterday's partition,
+// where today's insert can't collide with it. A second first request must
+// still wait for it rather than reserving the key again.
+func TestReserveSerializesKeyAcrossPartitions(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+
+	first, err := s.db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Rollback(ctx)
+	if _, err := s.idem.Reserve(ctx, first, OpTransfer, "midnight", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Exec(ctx, "UPDATE idempotency_keys SET created_on = created_on - 1 WHERE key = 'midnight'"); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		second, err := s.db.Begin(ctx)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer second.Rollback(ctx)
+		_, err = s.idem.Reserve(ctx, second, OpTransfer, "midnight", "h")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("second reservation returned %v while the first was open", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := first.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, ErrConflict) {
+		t.Errorf("second reservation: err = %v, want ErrConflict", err)
+	}
+}
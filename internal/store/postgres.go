@@ -5,26 +5,123 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/punchamoorthee/ledgerops/internal/cache"
 	"github.com/punchamoorthee/ledgerops/internal/domain"
+	"github.com/punchamoorthee/ledgerops/internal/idgen"
+	"github.com/punchamoorthee/ledgerops/internal/validation"
 )
 
 var (
-	ErrAccountNotFound = errors.New("account not found")
-	ErrConflict        = errors.New("conflict: request in progress")
-	ErrKeyMismatch     = errors.New("idempotency key mismatch")
-	ErrFunds           = errors.New("insufficient funds")
+	ErrAccountNotFound  = errors.New("account not found")
+	ErrConflict         = errors.New("conflict: request in progress")
+	ErrKeyMismatch      = errors.New("idempotency key mismatch")
+	ErrFunds            = errors.New("insufficient funds")
+	ErrInvalidAmount    = errors.New("amount must be positive")
+	ErrSelfTransfer     = errors.New("cannot transfer to self")
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrNegativeBalance  = errors.New("initial balance must not be negative")
+	ErrVersionConflict  = errors.New("account was modified concurrently")
+
+	// ErrPreconditionFailed means a conditional transfer's sender changed
+	// since the client read it.
+	ErrPreconditionFailed = errors.New("sender balance version does not match")
+
+	// ErrLockContention is the ErrConflict returned when NOWAIT could not
+	// take an account lock, as opposed to an idempotency key still in flight.
+	ErrLockContention = fmt.Errorf("%w: account lock not available", ErrConflict)
+
+	ErrBelowMinimumBalance  = errors.New("debit would take the balance below the account's minimum")
+	ErrNegativeMinBalance   = errors.New("minimum balance must not be negative")
+	ErrInvalidType          = errors.New("unknown transfer type")
+	ErrInvalidEffectiveDate = errors.New("effective date must be YYYY-MM-DD")
+
+	// ErrInvariantViolation means the check_ledger_invariant trigger fired:
+	// a transfer's entries did not sum to zero. It always indicates a bug.
+	ErrInvariantViolation = errors.New("ledger invariant violated")
+	// ErrUnbalancedEntries is the application-side version of the same
+	// check, caught before the entries are written.
+	ErrUnbalancedEntries = errors.New("ledger entries do not balance")
 )
 
+// querier is satisfied by both the pool and a transaction, so read helpers
+// can run standalone or inside a caller's snapshot.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type LedgerStore struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	ids      idgen.IDGenerator                 // nil means transfers use the serial sequence
+	accounts *cache.LRU[int64, domain.Account] // nil disables read caching
+
+	idem          IdempotencyStore
+	maxStoredBody int // for the default PostgresIdempotency; see WithIdempotencyBodyLimit
+
+	sweepValidators []validation.TransferValidator
+
+	isoLevel pgx.TxIsoLevel // write transactions
+}
+
+// Option customizes a LedgerStore.
+type Option func(*LedgerStore)
+
+// WithIDGenerator assigns transfer IDs from g instead of the database sequence.
+func WithIDGenerator(g idgen.IDGenerator) Option {
+	return func(s *LedgerStore) { s.ids = g }
+}
+
+// WithAccountCache serves GetAccount through c. Every balance-changing
+// operation invalidates the accounts it touched once it commits.
+func WithAccountCache(c *cache.LRU[int64, domain.Account]) Option {
+	return func(s *LedgerStore) { s.accounts = c }
+}
+
+// WithIdempotencyBodyLimit stops storing idempotency responses larger than
+// n bytes. Oversized transfer and chain responses are rebuilt from the
+// transfer records on replay instead.
+func WithIdempotencyBodyLimit(n int) Option {
+	return func(s *LedgerStore) { s.maxStoredBody = n }
+}
+
+// WithIdempotencyStore replaces the default PostgresIdempotency. The store
+// must honor the transaction passed to Reserve and Complete.
+func WithIdempotencyStore(is IdempotencyStore) Option {
+	return func(s *LedgerStore) { s.idem = is }
+}
+
+// WithSweepValidators re-runs vs against a sweep once its amount is known.
+// The handler validates a sweep before the amount is resolved, so limits
+// such as validation.MaxAmount only take effect here.
+func WithSweepValidators(vs ...validation.TransferValidator) Option {
+	return func(s *LedgerStore) { s.sweepValidators = vs }
 }
 
-func NewLedgerStore(db *pgxpool.Pool) *LedgerStore {
-	return &LedgerStore{db: db}
+// WithIsolation runs write transactions at level instead of Repeatable
+// Read. Serialization failures (40001), which Serializable raises more
+// often, are retried; see retrySerialization.
+func WithIsolation(level pgx.TxIsoLevel) Option {
+	return func(s *LedgerStore) { s.isoLevel = level }
+}
+
+func NewLedgerStore(db *pgxpool.Pool, opts ...Option) *LedgerStore {
+	s := &LedgerStore{db: db, isoLevel: pgx.RepeatableRead}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.idem == nil {
+		s.idem = NewPostgresIdempotency(db, s.maxStoredBody)
+	}
+	return s
 }
 
 // ExecTransfer executes a double-entry transfer with strong consistency guarantees.
@@ -32,140 +129,901 @@ func NewLedgerStore(db *pgxpool.Pool) *LedgerStore {
 // 2. Uses Deterministic Locking (Deadlock Prevention)
 // 3. Enforces DB Invariants (Constraint Triggers)
 func (s *LedgerStore) ExecTransfer(ctx context.Context, req domain.TransferRequest, idempotencyKey, reqHash string) (*domain.TransferResponse, error) {
-	// Start Tx with Repeatable Read isolation to ensure consistent snapshots
-	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	return retrySerialization(ctx, func() (*domain.TransferResponse, error) {
+		return s.execTransfer(ctx, req, idempotencyKey, reqHash)
+	})
+}
+
+func (s *LedgerStore) execTransfer(ctx context.Context, req domain.TransferRequest, idempotencyKey, reqHash string) (*domain.TransferResponse, error) {
+	// Defensive checks: the handler validates these too, but the store must
+	// hold the line for any caller that bypasses HTTP.
+	if req.Amount <= 0 && !req.Sweep {
+		return nil, ErrInvalidAmount
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return nil, ErrSelfTransfer
+	}
+
+	// Start Tx at Repeatable Read (or Serializable) to ensure consistent snapshots
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: s.isoLevel})
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
+	defer rollback(ctx, tx)
 
 	// --- 1. IDEMPOTENCY CHECK ---
-	var storedStatus string
-	var storedBody json.RawMessage
-	var storedHash string
+	cached, err := s.idem.Reserve(ctx, tx, OpTransfer, idempotencyKey, reqHash)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		// Return cached response; commit is not needed for a read-only return
+		return replayTransfer(ctx, tx, idempotencyKey, cached)
+	}
 
-	err = tx.QueryRow(ctx,
-		"SELECT status, response_body, request_hash FROM idempotency_keys WHERE key = $1",
-		idempotencyKey).Scan(&storedStatus, &storedBody, &storedHash)
+	// --- 2. DETERMINISTIC LOCKING ---
+	accounts, err := lockAccounts(ctx, tx, req.FromAccountID, req.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
 
-	if err == nil {
-		// Key exists
-		if storedHash != reqHash {
-			return nil, ErrKeyMismatch
+	// --- 3. BUSINESS LOGIC & EXECUTION ---
+	if req.IfSenderVersion > 0 && accounts[req.FromAccountID].version != req.IfSenderVersion {
+		return nil, ErrPreconditionFailed
+	}
+	if req.Sweep {
+		// Resolved under the lock, so no concurrent transfer can change it.
+		// A replay returns this amount, not a fresh evaluation. The reserve
+		// is never swept.
+		req.Amount = accounts[req.FromAccountID].spendable()
+		if req.Amount <= 0 {
+			return nil, ErrFunds
 		}
-		if storedStatus == "in_progress" {
-			return nil, ErrConflict
+		for _, v := range s.sweepValidators {
+			if err := v.Validate(ctx, req); err != nil {
+				return nil, err
+			}
 		}
-		// Return cached response
-		var resp domain.TransferResponse
-		if err := json.Unmarshal(storedBody, &resp); err != nil {
+	}
+	if err := accounts[req.FromAccountID].checkDebit(req.Amount); err != nil {
+		return nil, err
+	}
+	resp, err := s.moveFunds(ctx, tx, req.FromAccountID, req.ToAccountID, req.Amount, req.Type, req.EffectiveDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- 4. FINALIZE ---
+	if err := s.idem.Complete(ctx, tx, idempotencyKey, 201, resp, resp.Transfer.ID); err != nil {
+		return nil, err
+	}
+
+	if err := commit(ctx, tx); err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(req.FromAccountID, req.ToAccountID)
+	return resp, nil
+}
+
+// lockedAccount is an account row held under FOR UPDATE.
+type lockedAccount struct {
+	balance    int64
+	minBalance int64 // reserve a debit may not dip into
+	version    int64 // balance_version, what If-Match is checked against
+}
+
+// spendable is what a debit can take without breaching the reserve.
+func (a *lockedAccount) spendable() int64 {
+	return a.balance - a.minBalance
+}
+
+// checkDebit reports why amount can't be debited, if it can't.
+func (a *lockedAccount) checkDebit(amount int64) error {
+	if a.balance < amount {
+		return ErrFunds
+	}
+	if a.spendable() < amount {
+		return ErrBelowMinimumBalance
+	}
+	return nil
+}
+
+// lockAccounts takes FOR UPDATE locks on the given accounts and returns their
+// balances and reserves. IDs are de-duplicated and locked in ascending order, so any two
+// callers contend in the same order and cannot deadlock. NOWAIT fails fast
+// during extreme contention scenarios (Hot-Spot).
+func lockAccounts(ctx context.Context, tx pgx.Tx, ids ...int64) (map[int64]*lockedAccount, error) {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+
+	// One round trip; ORDER BY id keeps the acquisition order ascending and
+	// the locked rows give us the balances.
+	rows, err := tx.Query(ctx,
+		"SELECT id, balance, min_balance, balance_version FROM accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE NOWAIT",
+		unique)
+	if err != nil {
+		return nil, lockError(err)
+	}
+	accounts := make(map[int64]*lockedAccount, len(unique))
+	for rows.Next() {
+		var id int64
+		var a lockedAccount
+		if err := rows.Scan(&id, &a.balance, &a.minBalance, &a.version); err != nil {
+			rows.Close()
 			return nil, err
 		}
-		return &resp, nil // Commit is not needed for read-only return
-	} else if err != pgx.ErrNoRows {
-		return nil, err
+		accounts[id] = &a
 	}
+	if err := rows.Err(); err != nil {
+		return nil, lockError(err)
+	}
+	// Only a clean scan that came back short means an account is missing;
+	// any other failure surfaces as-is (a 500), never as a 404.
+	if len(accounts) != len(unique) {
+		return nil, ErrAccountNotFound
+	}
+	return accounts, nil
+}
 
-	// Insert "in_progress" marker
-	_, err = tx.Exec(ctx,
-		"INSERT INTO idempotency_keys (key, request_hash, status) VALUES ($1, $2, 'in_progress')",
-		idempotencyKey, reqHash)
+// lockError maps a NOWAIT lock failure (55P03) to ErrLockContention and
+// passes every other error through. The server may report it when the
+// query is sent or while rows are read, so both paths use this.
+func lockError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "55P03" { // Lock not available
+		return ErrLockContention
+	}
+	return err
+}
+
+// moveFunds records a completed transfer with its two ledger legs and
+// applies it to the balances. Callers must already hold the account locks
+// and have checked funds. An empty effective date means today.
+func (s *LedgerStore) moveFunds(ctx context.Context, tx pgx.Tx, from, to, amount int64, typ, effective string) (*domain.TransferResponse, error) {
+	t, err := s.insertTransfer(ctx, tx, from, to, amount, typ, effective, "completed")
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
-			return nil, ErrConflict
+		return nil, err
+	}
+	entries, err := applyEntries(ctx, tx, t.ID, from, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.TransferResponse{Transfer: *t, Entries: entries}, nil
+}
+
+// insertTransfer creates the transfer record only; no money moves.
+func (s *LedgerStore) insertTransfer(ctx context.Context, tx pgx.Tx, from, to, amount int64, typ, effective, status string) (*domain.Transfer, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if from == to {
+		return nil, ErrSelfTransfer
+	}
+	if !domain.ValidTransferType(typ) {
+		return nil, ErrInvalidType
+	}
+	if typ == "" {
+		typ = domain.TransferTypeTransfer
+	}
+	if effective != "" {
+		if _, err := time.Parse(domain.EffectiveDateLayout, effective); err != nil {
+			return nil, ErrInvalidEffectiveDate
 		}
+	}
+
+	// A nil ID falls back to the serial sequence; created_at comes back from
+	// the DB so replays carry the original time.
+	var id *int64
+	if s.ids != nil {
+		next, err := s.ids.NextID()
+		if err != nil {
+			return nil, err
+		}
+		id = &next
+	}
+	t := domain.Transfer{FromAccountID: from, ToAccountID: to, Amount: amount, Type: typ, Status: status}
+	err := tx.QueryRow(ctx, `
+		INSERT INTO transfers (id, from_account_id, to_account_id, amount, type, status, effective_date)
+		VALUES (COALESCE($1, nextval('transfers_id_seq')), $2, $3, $4, $5, $6, COALESCE(NULLIF($7, '')::date, (now() AT TIME ZONE 'UTC')::date))
+		RETURNING id, public_id::text, to_char(effective_date, 'YYYY-MM-DD'), created_at`,
+		id, from, to, amount, typ, status, effective).Scan(&t.ID, &t.PublicID, &t.EffectiveDate, &t.CreatedAt)
+	if err != nil {
 		return nil, err
 	}
+	return &t, nil
+}
 
-	// --- 2. DETERMINISTIC LOCKING ---
-	// Sort IDs to prevent circular wait conditions
-	first, second := req.FromAccountID, req.ToAccountID
-	if first > second {
-		first, second = second, first
-	}
-
-	// Acquire locks in ascending order
-	// Use NOWAIT to fail fast during extreme contention scenarios (Hot-Spot)
-	for _, id := range []int64{first, second} {
-		var b int64
-		if err := tx.QueryRow(ctx, "SELECT balance FROM accounts WHERE id = $1 FOR UPDATE NOWAIT", id).Scan(&b); err != nil {
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) && pgErr.Code == "55P03" { // Lock not available
-				return nil, ErrConflict
-			}
+// leg is one side of a transfer before it is written as a ledger entry.
+type leg struct {
+	accountID int64
+	delta     int64
+}
+
+// checkBalanced fails fast on legs that don't sum to zero, before anything
+// reaches the database. check_ledger_invariant remains the backstop at
+// commit, for writers that bypass this path.
+func checkBalanced(legs []leg) error {
+	var sum int64
+	for _, l := range legs {
+		sum += l.delta
+	}
+	if len(legs) < 2 || sum != 0 {
+		return fmt.Errorf("%w: %d legs sum to %d", ErrUnbalancedEntries, len(legs), sum)
+	}
+	return nil
+}
+
+// applyEntries writes the debit and credit legs for a transfer, updates
+// both balances and returns the legs with each account's balance after them.
+func applyEntries(ctx context.Context, tx pgx.Tx, transferID, from, to, amount int64) ([]domain.LedgerEntry, error) {
+	return writeLegs(ctx, tx, transferID, []leg{{from, -amount}, {to, amount}})
+}
+
+func writeLegs(ctx context.Context, tx pgx.Tx, transferID int64, legs []leg) ([]domain.LedgerEntry, error) {
+	if err := checkBalanced(legs); err != nil {
+		return nil, err
+	}
+	accountIDs := make([]int64, len(legs))
+	deltas := make([]int64, len(legs))
+	for i, l := range legs {
+		accountIDs[i], deltas[i] = l.accountID, l.delta
+	}
+
+	// Create Double-Entry Ledger Records (Debit and Credit)
+	// The DB trigger `check_ledger_invariant` will verify SUM(delta) == 0 at COMMIT time.
+	rows, err := tx.Query(ctx, `
+		INSERT INTO ledger_entries (transfer_id, account_id, delta)
+		SELECT $1, a, d FROM unnest($2::bigint[], $3::bigint[]) AS l(a, d)
+		RETURNING id, transfer_id, account_id, delta, created_at`,
+		transferID, accountIDs, deltas)
+	if err != nil {
+		return nil, invariantError(err)
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.LedgerEntry, error) {
+		var e domain.LedgerEntry
+		err := row.Scan(&e.ID, &e.TransferID, &e.AccountID, &e.Delta, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, invariantError(err)
+	}
+
+	// Update Balances; we hold the row locks, so the returned balance is
+	// exactly the balance after this transfer's leg. The balance version
+	// moves too, so an account's ETag changes whenever its balance does.
+	after := make(map[int64]int64, len(legs))
+	for _, l := range legs {
+		var balance int64
+		err := tx.QueryRow(ctx, "UPDATE accounts SET balance = balance + $1, balance_version = balance_version + 1 WHERE id = $2 RETURNING balance",
+			l.delta, l.accountID).Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		after[l.accountID] = balance
+	}
+	for i := range entries {
+		entries[i].BalanceAfter = after[entries[i].AccountID]
+	}
+	// RETURNING order is unspecified; match the ID order loadTransfer reads
+	// back so a created response and a later GET are identical.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// RaiseAccountIDFloor makes new accounts get IDs of at least floor. It only
+// ever moves the sequence forward, so it is safe to run on every start.
+func (s *LedgerStore) RaiseAccountIDFloor(ctx context.Context, floor int64) error {
+	_, err := s.db.Exec(ctx,
+		"SELECT setval('accounts_id_seq', $1, false) FROM accounts_id_seq WHERE last_value < $1", floor)
+	return err
+}
+
+func (s *LedgerStore) CreateAccount(ctx context.Context, req domain.CreateAccountRequest) (int64, error) {
+	if req.InitialBalance < 0 {
+		return 0, ErrNegativeBalance
+	}
+	if req.MinBalance < 0 {
+		return 0, ErrNegativeMinBalance
+	}
+	var id int64
+	err := s.db.QueryRow(ctx,
+		"INSERT INTO accounts (balance, initial_balance, min_balance, name, metadata, tags) VALUES ($1, $1, $2, NULLIF($3, ''), $4, COALESCE($5, '{}')) RETURNING id",
+		req.InitialBalance, req.MinBalance, req.Name, nilIfEmpty(req.Metadata), req.Tags).Scan(&id)
+	return id, err
+}
+
+// UpdateAccount applies upd if the account is still at upd.Version and
+// returns the account at its new version.
+func (s *LedgerStore) UpdateAccount(ctx context.Context, id int64, upd domain.AccountUpdate) (*domain.Account, error) {
+	var acc domain.Account
+	err := s.db.QueryRow(ctx, `
+		UPDATE accounts SET
+			name = CASE WHEN $2::text IS NULL THEN name ELSE NULLIF($2, '') END,
+			metadata = CASE WHEN $3::jsonb IS NULL THEN metadata ELSE NULLIF($3, '{}'::jsonb) END,
+			tags = COALESCE($5::text[], tags),
+			version = version + 1
+		WHERE id = $1 AND version = $4
+		RETURNING `+accountCols, id, upd.Name, upd.Metadata, upd.Version, upd.Tags,
+	).Scan(accountDest(&acc)...)
+	if err == pgx.ErrNoRows {
+		// Either the account is missing or someone else updated it first.
+		var exists bool
+		if err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM accounts WHERE id = $1)", id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
 			return nil, ErrAccountNotFound
 		}
+		return nil, ErrVersionConflict
 	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateAccounts(id)
+	return &acc, nil
+}
 
-	// --- 3. BUSINESS LOGIC & EXECUTION ---
-	var fromBalance int64
-	if err := tx.QueryRow(ctx, "SELECT balance FROM accounts WHERE id = $1", req.FromAccountID).Scan(&fromBalance); err != nil {
+const accountCols = "id, balance, min_balance, COALESCE(name, ''), metadata, tags, version, balance_version, created_at"
+
+func accountDest(a *domain.Account) []any {
+	return []any{&a.ID, &a.Balance, &a.MinBalance, &a.Name, &a.Metadata, &a.Tags, &a.Version, &a.BalanceVersion, &a.CreatedAt}
+}
+
+func nilIfEmpty(m map[string]any) map[string]any {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (s *LedgerStore) GetAccount(ctx context.Context, id int64) (*domain.Account, error) {
+	var gen uint64
+	if s.accounts != nil {
+		if acc, ok := s.accounts.Get(id); ok {
+			return &acc, nil
+		}
+		gen = s.accounts.Generation()
+	}
+
+	accs, err := readAccounts(ctx, s.db, readPlain, id)
+	if err != nil {
 		return nil, err
 	}
-	if fromBalance < req.Amount {
-		return nil, ErrFunds
+	if len(accs) == 0 {
+		return nil, ErrAccountNotFound
 	}
+	acc := accs[0]
+	if s.accounts != nil {
+		s.accounts.Add(id, acc, gen)
+	}
+	return &acc, nil
+}
 
-	// Create Transfer Record
-	var transferID int64
-	err = tx.QueryRow(ctx,
-		"INSERT INTO transfers (from_account_id, to_account_id, amount, status) VALUES ($1, $2, $3, 'completed') RETURNING id",
-		req.FromAccountID, req.ToAccountID, req.Amount).Scan(&transferID)
+// ListAccounts returns up to limit accounts past after (from the start when
+// nil) that carry every one of tags, or all accounts when tags is empty, in
+// ID order.
+func (s *LedgerStore) ListAccounts(ctx context.Context, tags []string, after *Cursor, limit int) (*domain.AccountPage, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	var afterID int64
+	if after != nil {
+		afterID = after.ID
+	}
+	// Fetch one extra row to learn whether another page exists.
+	rows, err := s.db.Query(ctx,
+		"SELECT "+accountCols+" FROM accounts WHERE tags @> $1 AND id > $2 ORDER BY id LIMIT $3",
+		tags, afterID, limit+1)
 	if err != nil {
 		return nil, err
 	}
+	accs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Account, error) {
+		var a domain.Account
+		err := row.Scan(accountDest(&a)...)
+		return a, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.AccountPage{Accounts: accs}
+	if len(accs) > limit {
+		page.Accounts = accs[:limit]
+		last := accs[limit-1]
+		page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if page.Accounts == nil {
+		page.Accounts = []domain.Account{}
+	}
+	return page, nil
+}
 
-	// Create Double-Entry Ledger Records (Debit and Credit)
-	// The DB trigger `check_ledger_invariant` will verify SUM(delta) == 0 at COMMIT time.
-	_, err = tx.Exec(ctx,
-		"INSERT INTO ledger_entries (transfer_id, account_id, delta) VALUES ($1, $2, $3), ($1, $4, $5)",
-		transferID, req.FromAccountID, -req.Amount, req.ToAccountID, req.Amount)
+// readMode selects how readAccounts locks the rows it reads.
+type readMode int
+
+const (
+	// readPlain takes no lock: the normal MVCC read every GET uses.
+	readPlain readMode = iota
+	// readShared takes FOR SHARE locks, held until the caller's transaction
+	// ends. The balances can't change under a report reading them, but every
+	// transfer touching those accounts fails its FOR UPDATE NOWAIT with lock
+	// contention in the meantime, so keep such transactions short.
+	readShared
+)
+
+// readAccounts reads the given accounts in ID order; missing IDs are simply
+// absent. readShared is only meaningful with q a transaction.
+func readAccounts(ctx context.Context, q querier, mode readMode, ids ...int64) ([]domain.Account, error) {
+	query := "SELECT " + accountCols + " FROM accounts WHERE id = ANY($1) ORDER BY id"
+	if mode == readShared {
+		query += " FOR SHARE"
+	}
+	rows, err := q.Query(ctx, query, ids)
 	if err != nil {
-		return nil, fmt.Errorf("invariant violation: %v", err)
+		return nil, err
 	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Account, error) {
+		var a domain.Account
+		err := row.Scan(accountDest(&a)...)
+		return a, err
+	})
+}
+
+// invalidateAccounts evicts cached reads for accounts whose balance changed.
+// Call it only after the change has committed.
+func (s *LedgerStore) invalidateAccounts(ids ...int64) {
+	if s.accounts != nil {
+		s.accounts.Invalidate(ids...)
+	}
+}
 
-	// Update Balances
-	_, err = tx.Exec(ctx, "UPDATE accounts SET balance = balance - $1 WHERE id = $2", req.Amount, req.FromAccountID)
+func (s *LedgerStore) ListBlockedPairs(ctx context.Context) ([]domain.BlockedPair, error) {
+	rows, err := s.db.Query(ctx, "SELECT from_id, to_id FROM blocked_pairs")
 	if err != nil {
 		return nil, err
 	}
-	_, err = tx.Exec(ctx, "UPDATE accounts SET balance = balance + $1 WHERE id = $2", req.Amount, req.ToAccountID)
+	defer rows.Close()
+
+	var pairs []domain.BlockedPair
+	for rows.Next() {
+		var p domain.BlockedPair
+		if err := rows.Scan(&p.FromAccountID, &p.ToAccountID); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// BalanceAt reconstructs an account's balance as of t, i.e. before any entry
+// recorded at or after t. Initial balances are not journaled, so we walk back
+// from the current balance rather than forward from zero.
+func (s *LedgerStore) BalanceAt(ctx context.Context, id int64, t time.Time) (int64, error) {
+	return balanceAt(ctx, s.db, id, t)
+}
+
+func balanceAt(ctx context.Context, q querier, id int64, t time.Time) (int64, error) {
+	var balance int64
+	err := q.QueryRow(ctx, `
+		SELECT a.balance - COALESCE((SELECT SUM(delta) FROM ledger_entries WHERE account_id = a.id AND created_at >= $2), 0)
+		FROM accounts a WHERE a.id = $1`, id, t).Scan(&balance)
+	if err == pgx.ErrNoRows {
+		return 0, ErrAccountNotFound
+	}
+	return balance, err
+}
+
+// GetStatement returns the account's entries whose transfer is effective in
+// [from, to), with a running balance in effective-date order. An effective
+// date counts as midnight UTC, so a back-dated transfer lands in the period
+// it was dated for rather than the one it was recorded in. Everything is
+// read from one snapshot so the opening balance and the entries agree.
+func (s *LedgerStore) GetStatement(ctx context.Context, id int64, from, to time.Time) (*domain.Statement, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
 	if err != nil {
 		return nil, err
 	}
+	defer rollback(ctx, tx)
 
-	// --- 4. FINALIZE ---
-	resp := domain.TransferResponse{
-		Transfer: domain.Transfer{ID: transferID, FromAccountID: req.FromAccountID, ToAccountID: req.ToAccountID, Amount: req.Amount, Status: "completed"},
-		Entries: []domain.LedgerEntry{
-			{AccountID: req.FromAccountID, Delta: -req.Amount},
-			{AccountID: req.ToAccountID, Delta: req.Amount},
-		},
+	// Walk back from the current balance over everything effective at or
+	// after from, as balanceAt does over created_at.
+	var opening int64
+	err = tx.QueryRow(ctx, `
+		SELECT a.balance - COALESCE((
+			SELECT SUM(e.delta) FROM ledger_entries e JOIN transfers t ON t.id = e.transfer_id
+			WHERE e.account_id = a.id AND (t.effective_date::timestamp AT TIME ZONE 'UTC') >= $2), 0)
+		FROM accounts a WHERE a.id = $1`, id, from).Scan(&opening)
+	if err == pgx.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	respBytes, _ := json.Marshal(resp)
-	_, err = tx.Exec(ctx,
-		"UPDATE idempotency_keys SET status = 'completed', transfer_id = $1, response_status = 201, response_body = $2 WHERE key = $3",
-		transferID, respBytes, idempotencyKey)
+	rows, err := tx.Query(ctx, `
+		SELECT e.id, e.transfer_id, e.account_id, e.delta, e.created_at, t.type, to_char(t.effective_date, 'YYYY-MM-DD')
+		FROM ledger_entries e JOIN transfers t ON t.id = e.transfer_id
+		WHERE e.account_id = $1
+		  AND (t.effective_date::timestamp AT TIME ZONE 'UTC') >= $2
+		  AND (t.effective_date::timestamp AT TIME ZONE 'UTC') < $3
+		ORDER BY t.effective_date, e.id`, id, from, to)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &resp, tx.Commit(ctx)
+	st := &domain.Statement{AccountID: id, From: from, To: to, OpeningBalance: opening, Entries: []domain.LedgerEntry{}}
+	running := opening
+	for rows.Next() {
+		var line domain.LedgerEntry
+		if err := rows.Scan(&line.ID, &line.TransferID, &line.AccountID, &line.Delta, &line.CreatedAt, &line.TransferType, &line.EffectiveDate); err != nil {
+			return nil, err
+		}
+		running += line.Delta
+		line.BalanceAfter = running
+		st.Entries = append(st.Entries, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	st.ClosingBalance = running
+	return st, nil
 }
 
-func (s *LedgerStore) CreateAccount(ctx context.Context, initialBalance int64) (int64, error) {
-	var id int64
-	err := s.db.QueryRow(ctx, "INSERT INTO accounts (balance) VALUES ($1) RETURNING id", initialBalance).Scan(&id)
-	return id, err
+// GetEntries returns up to limit of the account's ledger entries past after
+// (from the first when nil), in ID order, with a running balance computed
+// from the opening balance plus a window sum over entry IDs. Each entry
+// carries its transfer's type. There is deliberately no unbounded variant: a
+// hot account's full ledger would not fit in memory.
+func (s *LedgerStore) GetEntries(ctx context.Context, id int64, after *Cursor, limit int) (*domain.AccountEntries, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	var opening int64
+	err = tx.QueryRow(ctx, "SELECT initial_balance FROM accounts WHERE id = $1", id).Scan(&opening)
+	if err == pgx.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var afterID int64
+	if after != nil {
+		afterID = after.ID
+	}
+	// The page is cut first and the running balance computed over it alone,
+	// seeded with everything before the cursor. One extra row tells us
+	// whether another page exists.
+	rows, err := tx.Query(ctx, `
+		SELECT p.id, p.transfer_id, p.account_id, p.delta, p.created_at,
+			$2 + prior.total + SUM(p.delta) OVER (ORDER BY p.id), p.type
+		FROM (
+			SELECT e.id, e.transfer_id, e.account_id, e.delta, e.created_at, t.type
+			FROM ledger_entries e JOIN transfers t ON t.id = e.transfer_id
+			WHERE e.account_id = $1 AND e.id > $3
+			ORDER BY e.id
+			LIMIT $4
+		) p, (
+			SELECT COALESCE(SUM(delta), 0) AS total FROM ledger_entries WHERE account_id = $1 AND id <= $3
+		) prior
+		ORDER BY p.id`, id, opening, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.LedgerEntry, error) {
+		var e domain.LedgerEntry
+		err := row.Scan(&e.ID, &e.TransferID, &e.AccountID, &e.Delta, &e.CreatedAt, &e.BalanceAfter, &e.TransferType)
+		return e, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.AccountEntries{AccountID: id, Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		last := entries[limit-1]
+		page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if page.Entries == nil {
+		page.Entries = []domain.LedgerEntry{}
+	}
+	return page, nil
 }
 
-func (s *LedgerStore) GetAccount(ctx context.Context, id int64) (*domain.Account, error) {
-	var acc domain.Account
-	err := s.db.QueryRow(ctx, "SELECT id, balance, created_at FROM accounts WHERE id = $1", id).Scan(&acc.ID, &acc.Balance, &acc.CreatedAt)
+// GetAccountSummary computes the account's summary in one query. A non-nil
+// since limits the activity totals to entries at or after it.
+func (s *LedgerStore) GetAccountSummary(ctx context.Context, id int64, since *time.Time) (*domain.AccountSummary, error) {
+	sum := domain.AccountSummary{AccountID: id}
+	err := s.db.QueryRow(ctx, `
+		SELECT a.balance, a.min_balance,
+		       act.out_count, act.out_amount, act.in_count, act.in_amount, act.last_at
+		FROM accounts a
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) FILTER (WHERE e.delta < 0) AS out_count,
+			       COALESCE(-SUM(e.delta) FILTER (WHERE e.delta < 0), 0) AS out_amount,
+			       COUNT(*) FILTER (WHERE e.delta > 0) AS in_count,
+			       COALESCE(SUM(e.delta) FILTER (WHERE e.delta > 0), 0) AS in_amount,
+			       MAX(e.created_at) AS last_at
+			FROM ledger_entries e
+			WHERE e.account_id = a.id AND ($2::timestamptz IS NULL OR e.created_at >= $2)
+		) act
+		WHERE a.id = $1`, id, since,
+	).Scan(&sum.Balance, &sum.MinBalance, &sum.OutboundCount, &sum.OutboundAmount, &sum.InboundCount, &sum.InboundAmount, &sum.LastActivityAt)
 	if err == pgx.ErrNoRows {
 		return nil, ErrAccountNotFound
 	}
-	return &acc, err
+	if err != nil {
+		return nil, err
+	}
+	sum.Available = sum.Balance - sum.Held
+	return &sum, nil
+}
+
+// GetTransfer loads a transfer and its entries by internal ID or public UUID.
+func (s *LedgerStore) GetTransfer(ctx context.Context, ref string) (*domain.TransferResponse, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return loadTransfer(ctx, s.db, "id = $1", id)
+	}
+	if isUUID(ref) {
+		return loadTransfer(ctx, s.db, "public_id = $1::uuid", ref)
+	}
+	return nil, ErrTransferNotFound
+}
+
+// GetTransferByKey returns the transfer created under an idempotency key,
+// for clients that kept the key but lost the response. A key that is
+// unknown, expired, or finished without a transfer gives ErrKeyNotFound;
+// one whose request hasn't finished gives ErrKeyInProgress. Keys live in
+// idempotency_keys, so this assumes the default PostgresIdempotency.
+func (s *LedgerStore) GetTransferByKey(ctx context.Context, key string) (*domain.TransferResponse, error) {
+	var status string
+	var transferID *int64
+	err := s.db.QueryRow(ctx, `
+		SELECT status, transfer_id FROM idempotency_keys
+		WHERE key = $1 AND operation = $2 AND (expires_at IS NULL OR expires_at >= now())
+		ORDER BY created_on DESC LIMIT 1`, key, OpTransfer).Scan(&status, &transferID)
+	if err == pgx.ErrNoRows {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "in_progress" {
+		return nil, ErrKeyInProgress
+	}
+	if transferID == nil {
+		return nil, ErrKeyNotFound
+	}
+	return loadTransfer(ctx, s.db, "id = $1", *transferID)
+}
+
+// Replayable reports whether key already holds a completed response to this
+// request under op, so running it again would only replay that response. A
+// key that is unknown, in flight, or reused for a different request is not
+// replayable.
+func (s *LedgerStore) Replayable(ctx context.Context, op, key, reqHash string) (bool, error) {
+	resp, err := s.idem.Lookup(ctx, op, key, reqHash)
+	if errors.Is(err, ErrKeyMismatch) || errors.Is(err, ErrIdempotencyOperationMismatch) {
+		return false, nil
+	}
+	return resp != nil, err
+}
+
+// GetTransfers loads many transfers by internal ID in two queries, one for
+// the transfers and one for all their entries. Results follow the order of
+// ids with duplicates dropped; IDs that match nothing come back in notFound.
+func (s *LedgerStore) GetTransfers(ctx context.Context, ids []int64) (*domain.TransferBatch, error) {
+	// One snapshot, so an entry can't appear without its transfer.
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	rows, err := tx.Query(ctx, "SELECT id, public_id::text, from_account_id, to_account_id, amount, type, status, COALESCE(failure_reason, ''), COALESCE(reason, ''), to_char(effective_date, 'YYYY-MM-DD'), created_at FROM transfers WHERE id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int64]*domain.TransferResponse, len(ids))
+	for rows.Next() {
+		var resp domain.TransferResponse
+		t := &resp.Transfer
+		if err := rows.Scan(&t.ID, &t.PublicID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.Type, &t.Status, &t.FailureReason, &t.Reason, &t.EffectiveDate, &t.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		resp.Entries = []domain.LedgerEntry{}
+		found[t.ID] = &resp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT e.id, e.transfer_id, e.account_id, e.delta, e.created_at,
+		       a.initial_balance + (SELECT SUM(x.delta) FROM ledger_entries x WHERE x.account_id = e.account_id AND x.id <= e.id)
+		FROM ledger_entries e JOIN accounts a ON a.id = e.account_id
+		WHERE e.transfer_id = ANY($1) ORDER BY e.id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[domain.LedgerEntry])
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if t, ok := found[e.TransferID]; ok {
+			t.Entries = append(t.Entries, e)
+		}
+	}
+
+	batch := &domain.TransferBatch{Transfers: []domain.TransferResponse{}, NotFound: []int64{}}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if t, ok := found[id]; ok {
+			batch.Transfers = append(batch.Transfers, *t)
+		} else {
+			batch.NotFound = append(batch.NotFound, id)
+		}
+	}
+	return batch, tx.Commit(ctx)
+}
+
+// replayTransfer decodes a stored transfer response for replay. A body
+// that predates a field the handler needs (the ID, or the public ID that
+// Location is built from) is reloaded from the transfer record instead;
+// without an ID in the body, the key's transfer_id column says which
+// transfer it was.
+func replayTransfer(ctx context.Context, tx pgx.Tx, key string, cached json.RawMessage) (*domain.TransferResponse, error) {
+	var resp domain.TransferResponse
+	if err := json.Unmarshal(cached, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Transfer.ID == 0 || resp.Transfer.PublicID == "" {
+		id := resp.Transfer.ID
+		if id == 0 {
+			err := tx.QueryRow(ctx,
+				"SELECT COALESCE(transfer_id, 0) FROM idempotency_keys WHERE key = $1 ORDER BY created_on LIMIT 1", key).Scan(&id)
+			if err != nil && err != pgx.ErrNoRows {
+				return nil, err
+			}
+		}
+		if id != 0 {
+			reloaded, err := loadTransfer(ctx, tx, "id = $1", id)
+			if err != nil {
+				return nil, err
+			}
+			resp = *reloaded
+		}
+	}
+	resp.Replayed = true
+	return &resp, nil
+}
+
+// loadTransfer reads the transfer matching cond (with its single argument)
+// and its entries.
+func loadTransfer(ctx context.Context, q querier, cond string, arg any) (*domain.TransferResponse, error) {
+	row := q.QueryRow(ctx, "SELECT id, public_id::text, from_account_id, to_account_id, amount, type, status, COALESCE(failure_reason, ''), COALESCE(reason, ''), to_char(effective_date, 'YYYY-MM-DD'), created_at FROM transfers WHERE "+cond, arg)
+
+	var resp domain.TransferResponse
+	t := &resp.Transfer
+	err := row.Scan(&t.ID, &t.PublicID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.Type, &t.Status, &t.FailureReason, &t.Reason, &t.EffectiveDate, &t.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Each account's entries are written under its row lock, so entry ID
+	// order is that account's ledger order.
+	rows, err := q.Query(ctx, `
+		SELECT e.id, e.transfer_id, e.account_id, e.delta, e.created_at,
+		       a.initial_balance + (SELECT SUM(x.delta) FROM ledger_entries x WHERE x.account_id = e.account_id AND x.id <= e.id)
+		FROM ledger_entries e JOIN accounts a ON a.id = e.account_id
+		WHERE e.transfer_id = $1 ORDER BY e.id`, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Entries, err = pgx.CollectRows(rows, pgx.RowToStructByPos[domain.LedgerEntry])
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// serializationAttempts bounds retrySerialization.
+const serializationAttempts = 3
+
+// retrySerialization runs a whole write transaction again when Postgres
+// aborts it with a serialization failure (40001). At Serializable, two
+// requests racing on one idempotency key can fail this way instead of with
+// a unique violation; the retry sees the winner's committed key and
+// replays it (or gets ErrConflict), so the race resolves the same way at
+// either isolation level.
+func retrySerialization[T any](ctx context.Context, run func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := run()
+		var pgErr *pgconn.PgError
+		if attempt == serializationAttempts || !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(time.Duration(attempt) * 5 * time.Millisecond):
+		}
+	}
+}
+
+// rollback is the deferred cleanup for every write transaction; after a
+// successful commit it is a no-op. It deliberately ignores ctx's
+// cancellation: when a request is canceled mid-transaction the rollback
+// must still reach Postgres, so the idempotency marker and any balance
+// changes are discarded together and the connection returns to the pool
+// clean instead of being torn down.
+func rollback(ctx context.Context, tx pgx.Tx) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	tx.Rollback(ctx)
+}
+
+// commit commits tx, surfacing a deferred invariant trigger failure as
+// ErrInvariantViolation.
+func commit(ctx context.Context, tx pgx.Tx) error {
+	return invariantError(tx.Commit(ctx))
+}
+
+// invariantError wraps err in ErrInvariantViolation when it was raised by
+// check_ledger_invariant, keeping the trigger's message (which names the
+// transfer) for the logs. Other errors pass through unchanged.
+func invariantError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "P0001" && strings.HasPrefix(pgErr.Message, "Ledger invariant violated") {
+		return fmt.Errorf("%w: %s", ErrInvariantViolation, pgErr.Message)
+	}
+	return err
+}
+
+// IsTimeout reports whether err came from a canceled request deadline or
+// from Postgres canceling the statement (57014, e.g. statement_timeout).
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+// isUUID reports whether s has the canonical 8-4-4-4-12 hex layout.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
 }
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// The store rejects bad input itself, before touching the database, for
+// callers that never went through the handler's validation.
+func TestExecTransferRejectsInvalidInput(t *testing.T) {
+	s := NewLedgerStore(nil)
+	cases := []struct {
+		name string
+		req  domain.TransferRequest
+		want error
+	}{
+		{"zero amount", domain.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 0}, ErrInvalidAmount},
+		{"negative amount", domain.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: -5}, ErrInvalidAmount},
+		{"self-transfer", domain.TransferRequest{FromAccountID: 3, ToAccountID: 3, Amount: 10}, ErrSelfTransfer},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.ExecTransfer(context.Background(), tc.req, "k", "h"); !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCreateAccountRejectsNegativeBalances(t *testing.T) {
+	s := NewLedgerStore(nil)
+	if _, err := s.CreateAccount(context.Background(), domain.CreateAccountRequest{InitialBalance: -1}); !errors.Is(err, ErrNegativeBalance) {
+		t.Errorf("negative initial balance: err = %v", err)
+	}
+	if _, err := s.CreateAccount(context.Background(), domain.CreateAccountRequest{MinBalance: -1}); !errors.Is(err, ErrNegativeMinBalance) {
+		t.Errorf("negative minimum balance: err = %v", err)
+	}
+}
+
+func TestCheckBalanced(t *testing.T) {
+	cases := []struct {
+		name string
+		legs []leg
+		ok   bool
+	}{
+		{"debit and credit", []leg{{1, -10}, {2, 10}}, true},
+		{"split credit", []leg{{1, -10}, {2, 4}, {3, 6}}, true},
+		{"creates money", []leg{{1, -10}, {2, 11}}, false},
+		{"destroys money", []leg{{1, -10}, {2, 9}}, false},
+		{"single leg", []leg{{1, 0}}, false},
+		{"no legs", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkBalanced(tc.legs)
+			if tc.ok && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrUnbalancedEntries) {
+				t.Fatalf("err = %v, want ErrUnbalancedEntries", err)
+			}
+		})
+	}
+
+	// writeLegs checks before it touches the transaction.
+	if _, err := writeLegs(context.Background(), nil, 1, []leg{{1, -10}, {2, 9}}); !errors.Is(err, ErrUnbalancedEntries) {
+		t.Errorf("writeLegs: err = %v, want ErrUnbalancedEntries", err)
+	}
+}
+
+func TestCheckDebit(t *testing.T) {
+	acct := lockedAccount{balance: 1000, minBalance: 200}
+	cases := []struct {
+		amount int64
+		want   error
+	}{
+		{500, nil},
+		{800, nil}, // lands exactly on the reserve
+		{801, ErrBelowMinimumBalance},
+		{1000, ErrBelowMinimumBalance},
+		{1001, ErrFunds}, // short of the balance itself, reserve or not
+	}
+	for _, tc := range cases {
+		if err := acct.checkDebit(tc.amount); !errors.Is(err, tc.want) {
+			t.Errorf("debit %d: err = %v, want %v", tc.amount, err, tc.want)
+		}
+	}
+	if got := acct.spendable(); got != 800 {
+		t.Errorf("spendable = %d, want 800", got)
+	}
+}
+
+func TestRetrySerialization(t *testing.T) {
+	serialization := &pgconn.PgError{Code: "40001"}
+	cases := []struct {
+		name  string
+		errs  []error // returned by successive attempts
+		calls int
+		want  error
+	}{
+		{name: "first try", errs: []error{nil}, calls: 1},
+		{name: "retried to success", errs: []error{serialization, serialization, nil}, calls: 3},
+		{name: "gives up", errs: []error{serialization, serialization, serialization, nil}, calls: serializationAttempts, want: serialization},
+		{name: "unique violation is not retried", errs: []error{ErrConflict, nil}, calls: 1, want: ErrConflict},
+		{name: "other pg errors are not retried", errs: []error{&pgconn.PgError{Code: "55P03"}, nil}, calls: 1, want: &pgconn.PgError{Code: "55P03"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			_, err := retrySerialization(context.Background(), func() (int, error) {
+				calls++
+				return calls, tc.errs[calls-1]
+			})
+			if calls != tc.calls {
+				t.Errorf("ran %d times, want %d", calls, tc.calls)
+			}
+			var pgErr, wantPg *pgconn.PgError
+			switch {
+			case errors.As(tc.want, &wantPg):
+				if !errors.As(err, &pgErr) || pgErr.Code != wantPg.Code {
+					t.Errorf("err = %v, want pg error %s", err, wantPg.Code)
+				}
+			case !errors.Is(err, tc.want):
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// Every hop of a chain gets the same self-transfer check as a single
+// transfer, comparing the internal account IDs the locks would be taken on.
+func TestExecChainRejectsSelfHop(t *testing.T) {
+	s := NewLedgerStore(nil)
+	req := domain.ChainRequest{Hops: []domain.TransferRequest{
+		{FromAccountID: 1, ToAccountID: 2, Amount: 10},
+		{FromAccountID: 2, ToAccountID: 2, Amount: 10},
+	}}
+	_, err := s.ExecChain(context.Background(), req, "k", "h")
+	var hopErr *HopError
+	if !errors.Is(err, ErrSelfTransfer) || !errors.As(err, &hopErr) || hopErr.Index != 1 {
+		t.Fatalf("err = %v, want ErrSelfTransfer at hop 1", err)
+	}
+}
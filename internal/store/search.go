@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// TransferFilter narrows SearchTransfers. Zero values mean "no filter".
+type TransferFilter struct {
+	MinAmount *int64
+	MaxAmount *int64
+	From      *time.Time // inclusive
+	To        *time.Time // exclusive
+	Status    string
+	Type      string
+	AccountID int64 // either side of the transfer
+
+	EffectiveFrom *time.Time // inclusive, date only
+	EffectiveTo   *time.Time // exclusive, date only
+	Limit         int
+	Cursor        *Cursor // position after the previous page
+}
+
+// SearchTransfers lists transfers newest first, paging by (created_at, id)
+// so deep pages cost the same as the first one.
+func (s *LedgerStore) SearchTransfers(ctx context.Context, f TransferFilter) (*domain.TransferPage, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.MinAmount != nil {
+		add("amount >= $%d", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("amount <= $%d", *f.MaxAmount)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	if f.EffectiveFrom != nil {
+		add("effective_date >= $%d::date", f.EffectiveFrom.Format(domain.EffectiveDateLayout))
+	}
+	if f.EffectiveTo != nil {
+		add("effective_date < $%d::date", f.EffectiveTo.Format(domain.EffectiveDateLayout))
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.Type != "" {
+		add("type = $%d", f.Type)
+	}
+	if f.AccountID != 0 {
+		add("(from_account_id = $%[1]d OR to_account_id = $%[1]d)", f.AccountID)
+	}
+	if f.Cursor != nil {
+		cond, cursorArgs := f.Cursor.Predicate(len(args) + 1)
+		args = append(args, cursorArgs...)
+		where = append(where, cond)
+	}
+
+	query := "SELECT id, public_id::text, from_account_id, to_account_id, amount, type, status, to_char(effective_date, 'YYYY-MM-DD'), created_at FROM transfers"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	// Fetch one extra row to learn whether another page exists.
+	args = append(args, f.Limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &domain.TransferPage{Transfers: []domain.Transfer{}}
+	for rows.Next() {
+		var t domain.Transfer
+		if err := rows.Scan(&t.ID, &t.PublicID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.Type, &t.Status, &t.EffectiveDate, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		page.Transfers = append(page.Transfers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Transfers) > f.Limit {
+		page.Transfers = page.Transfers[:f.Limit]
+		last := page.Transfers[f.Limit-1]
+		page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
@@ -0,0 +1,118 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestSearchTransfersFilters(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 10_000), SeedAccount(t, s, 10_000)
+	before := time.Now().Add(-time.Minute)
+	for i, amount := range []int64{100, 250, 500, 1000} {
+		from, to := a, b
+		if i%2 == 1 {
+			from, to = b, a
+		}
+		typ := domain.TransferTypePayment
+		if amount >= 500 {
+			typ = domain.TransferTypeFee
+		}
+		req := domain.TransferRequest{FromAccountID: from, ToAccountID: to, Amount: amount, Type: typ}
+		if _, err := s.ExecTransfer(ctx, req, fmt.Sprintf("search-%d", i), "h"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := time.Now().Add(time.Minute)
+
+	amounts := func(f TransferFilter) []int64 {
+		t.Helper()
+		f.Limit = 100
+		page, err := s.SearchTransfers(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, tr := range page.Transfers {
+			got = append(got, tr.Amount)
+		}
+		return got
+	}
+	cases := []struct {
+		name   string
+		filter TransferFilter
+		want   []int64 // newest first
+	}{
+		{"no filter", TransferFilter{}, []int64{1000, 500, 250, 100}},
+		{"min amount", TransferFilter{MinAmount: ptr[int64](250)}, []int64{1000, 500, 250}},
+		{"max amount", TransferFilter{MaxAmount: ptr[int64](250)}, []int64{250, 100}},
+		{"created window", TransferFilter{From: &before, To: &after}, []int64{1000, 500, 250, 100}},
+		{"created before everything", TransferFilter{To: &before}, nil},
+		{"type", TransferFilter{Type: domain.TransferTypeFee}, []int64{1000, 500}},
+		{"status", TransferFilter{Status: "completed"}, []int64{1000, 500, 250, 100}},
+		{"combined", TransferFilter{MinAmount: ptr[int64](200), MaxAmount: ptr[int64](600), Type: domain.TransferTypePayment, From: &before}, []int64{250}},
+		{"empty result", TransferFilter{MinAmount: ptr[int64](5000)}, nil},
+	}
+	for _, tc := range cases {
+		if got := amounts(tc.filter); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Hops of one chain share a created_at; id must break the tie so paging
+// neither skips nor repeats them.
+func TestSearchTransfersPagesThroughTies(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 10_000), SeedAccount(t, s, 10_000)
+	var want []int64
+	for c := 0; c < 3; c++ {
+		var hops []domain.TransferRequest
+		for i := 0; i < 4; i++ {
+			hops = append(hops, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10})
+		}
+		resp, err := s.ExecChain(ctx, domain.ChainRequest{Hops: hops}, fmt.Sprintf("ties-%d", c), "h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, resp.TransferIDs...)
+	}
+	// Newest first: later chains have later timestamps and higher IDs.
+	slices.Sort(want)
+	slices.Reverse(want)
+
+	var got []int64
+	f := TransferFilter{Limit: 5}
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("paging did not terminate")
+		}
+		page, err := s.SearchTransfers(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range page.Transfers {
+			got = append(got, tr.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if f.Cursor, err = DecodeCursor(page.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged IDs %v, want %v", got, want)
+	}
+}
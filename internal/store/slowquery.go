@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SlowQueryTracer logs and counts queries that run longer than a threshold.
+// Set it as the pool's ConnConfig.Tracer and it sees every Query, QueryRow
+// and Exec, including those inside transactions. Only the operation name
+// derived from the SQL is reported, never the arguments, so amounts and
+// keys stay out of the logs.
+type SlowQueryTracer struct {
+	threshold time.Duration
+	slow      *prometheus.CounterVec
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	op    string
+	start time.Time
+}
+
+func NewSlowQueryTracer(reg prometheus.Registerer, namespace string, threshold time.Duration) *SlowQueryTracer {
+	return &SlowQueryTracer{
+		threshold: threshold,
+		slow: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "slow_queries_total",
+			Help:      "Queries that exceeded SLOW_QUERY_THRESHOLD, by operation",
+		}, []string{"operation"}),
+	}
+}
+
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{op: queryOperation(data.SQL), start: time.Now()})
+}
+
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	if elapsed := time.Since(q.start); elapsed >= t.threshold {
+		t.slow.WithLabelValues(q.op).Inc()
+		log.Printf("SLOW QUERY %s took %s", q.op, elapsed.Round(time.Millisecond))
+	}
+}
+
+// queryOperation names a statement by its verb and the first table it
+// reads or writes, e.g. "update accounts" or "insert ledger_entries". That
+// is enough to tell the steps of a transfer apart while keeping the label
+// set small.
+func queryOperation(sql string) string {
+	words := strings.Fields(strings.ToLower(sql))
+	if len(words) == 0 {
+		return "unknown"
+	}
+	verb := words[0]
+	after := map[string]string{"select": "from", "with": "from", "insert": "into", "delete": "from", "update": "update"}[verb]
+	if after == "" {
+		return verb
+	}
+	for i, w := range words[:len(words)-1] {
+		if w == after {
+			table, _, _ := strings.Cut(words[i+1], "(")
+			table = strings.Trim(table, `"),;`)
+			if table != "" {
+				return verb + " " + table
+			}
+		}
+	}
+	return verb
+}
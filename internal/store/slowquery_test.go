@@ -0,0 +1,73 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSlowQueryTracer(t *testing.T) {
+	var logs bytes.Buffer
+	prev, flags := log.Writer(), log.Flags()
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(prev)
+		log.SetFlags(flags)
+	}()
+
+	tr := NewSlowQueryTracer(prometheus.NewRegistry(), "ledger", 20*time.Millisecond)
+	run := func(sql string, took time.Duration) {
+		ctx := tr.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+			SQL:  sql,
+			Args: []any{int64(424242), "secret-key"},
+		})
+		time.Sleep(took)
+		tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	}
+
+	run("UPDATE accounts SET balance = balance + $1 WHERE id = $2", 30*time.Millisecond)
+	run("INSERT INTO ledger_entries (transfer_id) VALUES ($1)", 0)
+
+	if got := testutil.ToFloat64(tr.slow.WithLabelValues("update accounts")); got != 1 {
+		t.Errorf("slow update accounts = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(tr.slow.WithLabelValues("insert ledger_entries")); got != 0 {
+		t.Errorf("fast insert counted as slow %v times", got)
+	}
+	out := logs.String()
+	if !strings.HasPrefix(out, "SLOW QUERY update accounts took ") || strings.Count(out, "\n") != 1 {
+		t.Errorf("log = %q, want one SLOW QUERY line for update accounts", out)
+	}
+	for _, arg := range []string{"424242", "secret-key"} {
+		if strings.Contains(out, arg) {
+			t.Errorf("log leaks argument %q: %q", arg, out)
+		}
+	}
+}
+
+func TestQueryOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT id, balance FROM accounts WHERE id = ANY($1) FOR UPDATE NOWAIT": "select accounts",
+		"INSERT INTO idempotency_keys(key, request_hash) VALUES ($1, $2)":       "insert idempotency_keys",
+		"update accounts set balance = $1":                                      "update accounts",
+		"DELETE FROM idempotency_keys WHERE expires_at < now()":                 "delete idempotency_keys",
+		"WITH moved AS (SELECT 1) SELECT * FROM moved":                          "with moved",
+		`SELECT count(*) FROM "transfers"`:                                      "select transfers",
+		"SELECT 1":                                                              "select",
+		"BEGIN":                                                                 "begin",
+		"   ":                                                                   "unknown",
+	}
+	for sql, want := range tests {
+		if got := queryOperation(sql); got != want {
+			t.Errorf("queryOperation(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
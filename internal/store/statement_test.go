@@ -0,0 +1,69 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// A transfer recorded today but dated 40 days ago belongs to the earlier
+// period, in statements and in effective-date filters alike.
+func TestStatementBucketsByEffectiveDate(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	backDated := today.AddDate(0, 0, -40)
+	current, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}, "current", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	late, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 30, EffectiveDate: backDated.Format(domain.EffectiveDateLayout)}, "late", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if late.Transfer.EffectiveDate != backDated.Format(domain.EffectiveDateLayout) || !late.Transfer.CreatedAt.After(current.Transfer.CreatedAt) {
+		t.Fatalf("late transfer effective %s created %s; want %s, recorded after %s",
+			late.Transfer.EffectiveDate, late.Transfer.CreatedAt, backDated.Format(domain.EffectiveDateLayout), current.Transfer.CreatedAt)
+	}
+	if current.Transfer.EffectiveDate != today.Format(domain.EffectiveDateLayout) {
+		t.Errorf("default effective date %s, want today %s", current.Transfer.EffectiveDate, today.Format(domain.EffectiveDateLayout))
+	}
+
+	split := today.AddDate(0, 0, -20)
+	earlier, err := s.GetStatement(ctx, a, today.AddDate(0, 0, -60), split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(earlier.Entries) != 1 || earlier.Entries[0].TransferID != late.Transfer.ID {
+		t.Fatalf("earlier period entries %+v, want only transfer %d", earlier.Entries, late.Transfer.ID)
+	}
+	if earlier.OpeningBalance != 1000 || earlier.ClosingBalance != 970 {
+		t.Errorf("earlier period %d -> %d, want 1000 -> 970", earlier.OpeningBalance, earlier.ClosingBalance)
+	}
+
+	later, err := s.GetStatement(ctx, a, split, today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(later.Entries) != 1 || later.Entries[0].TransferID != current.Transfer.ID {
+		t.Fatalf("later period entries %+v, want only transfer %d", later.Entries, current.Transfer.ID)
+	}
+	if later.OpeningBalance != earlier.ClosingBalance || later.ClosingBalance != 870 {
+		t.Errorf("later period %d -> %d, want %d -> 870", later.OpeningBalance, later.ClosingBalance, earlier.ClosingBalance)
+	}
+
+	from, to := today.AddDate(0, 0, -60), split
+	page, err := s.SearchTransfers(ctx, TransferFilter{EffectiveFrom: &from, EffectiveTo: &to, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Transfers) != 1 || page.Transfers[0].ID != late.Transfer.ID {
+		t.Errorf("effective filter returned %+v, want only transfer %d", page.Transfers, late.Transfer.ID)
+	}
+}
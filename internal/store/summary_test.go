@@ -0,0 +1,64 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestAccountSummaryMixedHistory(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, err := s.CreateAccount(ctx, domain.CreateAccountRequest{InitialBalance: 1000, MinBalance: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, c := SeedAccount(t, s, 500), SeedAccount(t, s, 0)
+
+	for i, m := range []struct{ from, to, amount int64 }{{a, b, 100}, {a, c, 30}, {b, a, 40}} {
+		if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: m.from, ToAccountID: m.to, Amount: m.amount}, fmt.Sprintf("mix-%d", i), "h"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A queued transfer is neither a hold nor activity yet.
+	if _, err := s.EnqueueTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 200}, "mix-pending", "h"); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := s.GetAccountSummary(ctx, a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := domain.AccountSummary{
+		AccountID: a, Balance: 910, MinBalance: 50, Held: 0, Available: 910,
+		OutboundCount: 2, OutboundAmount: 130, InboundCount: 1, InboundAmount: 40,
+	}
+	got := *sum
+	got.LastActivityAt = nil
+	if got != want {
+		t.Errorf("summary = %+v\nwant      %+v", got, want)
+	}
+	if sum.LastActivityAt == nil {
+		t.Error("no last activity")
+	}
+
+	// A window after all the activity keeps the balances but counts nothing.
+	future := time.Now().Add(time.Hour)
+	sum, err = s.GetAccountSummary(ctx, a, &future)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Balance != 910 || sum.Available != 910 || sum.OutboundCount != 0 || sum.InboundCount != 0 || sum.LastActivityAt != nil {
+		t.Errorf("windowed summary = %+v", sum)
+	}
+
+	if _, err := s.GetAccountSummary(ctx, c+1, nil); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("unknown account: err = %v, want ErrAccountNotFound", err)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// A sweep races ordinary debits of the same account. Whatever order the
+// locks are granted in, the sweep moves exactly what was left when it ran,
+// and a replay returns that amount rather than the balance now.
+func TestSweepRacesConcurrentDebits(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b, sink, funder := SeedAccount(t, s, 1000), SeedAccount(t, s, 0), SeedAccount(t, s, 0), SeedAccount(t, s, 50)
+	sweep := domain.TransferRequest{FromAccountID: a, ToAccountID: sink, Sweep: true}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		debited int64
+		swept   *domain.TransferResponse
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := execWithRetry(ctx, s, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 100}, fmt.Sprintf("debit-%d", i))
+			switch {
+			case err == nil:
+				mu.Lock()
+				debited += 100
+				mu.Unlock()
+			case !errors.Is(err, ErrFunds) && !errors.Is(err, ErrLockContention):
+				t.Errorf("debit %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for attempt := 1; ; attempt++ {
+			resp, err := s.ExecTransfer(ctx, sweep, "sweep", "h")
+			if errors.Is(err, ErrLockContention) && attempt < 100 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			if err != nil {
+				t.Errorf("sweep: %v", err)
+			}
+			swept = resp
+			return
+		}
+	}()
+	wg.Wait()
+	if swept == nil {
+		t.FailNow()
+	}
+
+	if swept.Transfer.Amount != 1000-debited {
+		t.Errorf("swept %d after %d was debited, want %d", swept.Transfer.Amount, debited, 1000-debited)
+	}
+	AssertBalance(t, s, a, 0)
+	AssertBalance(t, s, sink, swept.Transfer.Amount)
+
+	// New money arrives; replaying the sweep must not take it.
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: funder, ToAccountID: a, Amount: 50}, "refill", "h"); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := s.ExecTransfer(ctx, sweep, "sweep", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Transfer.Amount != swept.Transfer.Amount {
+		t.Errorf("replay reports %d, original swept %d", replay.Transfer.Amount, swept.Transfer.Amount)
+	}
+	AssertBalance(t, s, a, 50)
+	AssertBalance(t, s, sink, swept.Transfer.Amount)
+	AssertInvariants(t, s)
+}
+
+// execWithRetry retries lock contention a few times, as a client would.
+// The key is reused: a failed attempt leaves no idempotency record.
+func execWithRetry(ctx context.Context, s *LedgerStore, req domain.TransferRequest, key string) error {
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		if _, err = s.ExecTransfer(ctx, req, key, key); !errors.Is(err, ErrLockContention) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt+1) * time.Millisecond)
+	}
+	return err
+}
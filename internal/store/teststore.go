@@ -0,0 +1,158 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/punchamoorthee/ledgerops/db"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// Store-level integration tests run with `go test -tags integration` against
+// the throwaway database in TEST_DB_SOURCE. The helpers below are compiled
+// only under that tag so production builds never carry them.
+
+var (
+	schemaOnce sync.Once
+	schemaErr  error
+)
+
+// NewTestStore returns a LedgerStore on a freshly truncated copy of the
+// schema. The schema is rebuilt from the embedded migrations once per test
+// binary; each call then empties every table and resets its sequences. The
+// test is skipped when TEST_DB_SOURCE is unset. TEST_DB_SOURCE must point at
+// a database the tests own: its public schema is dropped.
+func NewTestStore(t testing.TB, opts ...Option) *LedgerStore {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_SOURCE")
+	if dsn == "" {
+		t.Skip("TEST_DB_SOURCE not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	schemaOnce.Do(func() { schemaErr = applySchema(ctx, pool) })
+	if schemaErr != nil {
+		t.Fatalf("build test schema: %v", schemaErr)
+	}
+	if err := truncateAll(ctx, pool); err != nil {
+		t.Fatalf("truncate test database: %v", err)
+	}
+	return NewLedgerStore(pool, opts...)
+}
+
+// applySchema drops the public schema and replays every up migration in
+// version order. Each file runs as one simple-protocol Exec, so files with
+// several statements work as they do under migrate.
+func applySchema(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, "DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		return err
+	}
+	files, err := fs.Glob(db.Migrations, "migrations/*.up.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files) // zero-padded versions sort lexically
+	for _, f := range files {
+		sql, err := fs.ReadFile(db.Migrations, f)
+		if err != nil {
+			return err
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			return &migrationError{file: f, err: err}
+		}
+	}
+	return nil
+}
+
+type migrationError struct {
+	file string
+	err  error
+}
+
+func (e *migrationError) Error() string { return e.file + ": " + e.err.Error() }
+func (e *migrationError) Unwrap() error { return e.err }
+
+// truncateAll empties every table in one statement; partitions go with
+// their parents.
+func truncateAll(ctx context.Context, pool *pgxpool.Pool) error {
+	rows, err := pool.Query(ctx, `
+		SELECT c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p') AND NOT c.relispartition`)
+	if err != nil {
+		return err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = pgx.Identifier{n}.Sanitize()
+	}
+	_, err = pool.Exec(ctx, "TRUNCATE "+strings.Join(quoted, ", ")+" RESTART IDENTITY CASCADE")
+	return err
+}
+
+// SeedAccount creates an account opening with balance and returns its ID.
+func SeedAccount(t testing.TB, s *LedgerStore, balance int64) int64 {
+	t.Helper()
+	id, err := s.CreateAccount(context.Background(), domain.CreateAccountRequest{InitialBalance: balance})
+	if err != nil {
+		t.Fatalf("seed account: %v", err)
+	}
+	return id
+}
+
+// AssertBalance fails the test unless account id holds want.
+func AssertBalance(t testing.TB, s *LedgerStore, id, want int64) {
+	t.Helper()
+	var got int64
+	if err := s.db.QueryRow(context.Background(), "SELECT balance FROM accounts WHERE id = $1", id).Scan(&got); err != nil {
+		t.Fatalf("read balance of account %d: %v", id, err)
+	}
+	if got != want {
+		t.Errorf("account %d balance = %d, want %d", id, got, want)
+	}
+}
+
+// AssertInvariants fails the test if the ledger as a whole is unbalanced or
+// any account's stored balance has drifted from its entries.
+func AssertInvariants(t testing.TB, s *LedgerStore) {
+	t.Helper()
+	ctx := context.Background()
+	var sum int64
+	if err := s.db.QueryRow(ctx, "SELECT COALESCE(SUM(delta), 0) FROM ledger_entries").Scan(&sum); err != nil {
+		t.Fatalf("sum ledger entries: %v", err)
+	}
+	if sum != 0 {
+		t.Errorf("ledger entries sum to %d, want 0", sum)
+	}
+	var after int64
+	for {
+		diff, err := s.ReconcileDiff(ctx, after, 200)
+		if err != nil {
+			t.Fatalf("reconcile diff: %v", err)
+		}
+		for _, v := range diff.Accounts {
+			t.Errorf("account %d: stored balance %d, entries imply %d", v.AccountID, v.StoredBalance, v.ComputedBalance)
+		}
+		if diff.NextAfter == 0 {
+			return
+		}
+		after = diff.NextAfter
+	}
+}
@@ -0,0 +1,28 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// Each NewTestStore starts from empty tables with reset sequences, whatever
+// the previous test left behind.
+func TestNewTestStoreStartsEmpty(t *testing.T) {
+	for round := 0; round < 2; round++ {
+		s := NewTestStore(t)
+		a, b := SeedAccount(t, s, 500), SeedAccount(t, s, 0)
+		if a != 1 || b != 2 {
+			t.Fatalf("round %d: seeded accounts %d and %d, want 1 and 2", round, a, b)
+		}
+		if _, err := s.ExecTransfer(context.Background(), domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 200}, "smoke", "smoke"); err != nil {
+			t.Fatalf("round %d: transfer: %v", round, err)
+		}
+		AssertBalance(t, s, a, 300)
+		AssertBalance(t, s, b, 200)
+		AssertInvariants(t, s)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// TraceTransfer assembles everything recorded about one transfer, by ID or
+// public UUID, from a single snapshot. It is a support tool: the key lookup
+// scans idempotency_keys, which has no index on transfer IDs.
+func (s *LedgerStore) TraceTransfer(ctx context.Context, ref string) (*domain.TransferTrace, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	var resp *domain.TransferResponse
+	if id, perr := strconv.ParseInt(ref, 10, 64); perr == nil {
+		resp, err = loadTransfer(ctx, tx, "id = $1", id)
+	} else if isUUID(ref) {
+		resp, err = loadTransfer(ctx, tx, "public_id = $1::uuid", ref)
+	} else {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	trace := &domain.TransferTrace{Transfer: resp.Transfer, Entries: resp.Entries}
+	id := resp.Transfer.ID
+
+	var k domain.IdempotencyRecord
+	err = tx.QueryRow(ctx, `
+		SELECT key, operation, status, request_hash, response_status, created_at, expires_at
+		FROM idempotency_keys WHERE transfer_id = $1 OR $1 = ANY(transfer_ids)
+		ORDER BY created_at LIMIT 1`, id,
+	).Scan(&k.Key, &k.Operation, &k.Status, &k.RequestHash, &k.ResponseStatus, &k.CreatedAt, &k.ExpiresAt)
+	switch {
+	case err == nil:
+		trace.IdempotencyKey = &k
+	case err != pgx.ErrNoRows:
+		return nil, err
+	}
+
+	var e domain.Escrow
+	err = tx.QueryRow(ctx, `
+		SELECT id, buyer_account_id, seller_account_id, escrow_account_id, amount, state, hold_transfer_id, settle_transfer_id, created_at, updated_at
+		FROM escrows WHERE hold_transfer_id = $1 OR settle_transfer_id = $1`, id,
+	).Scan(&e.ID, &e.BuyerAccountID, &e.SellerAccountID, &e.EscrowAccountID, &e.Amount, &e.State, &e.HoldTransferID, &e.SettleTransferID, &e.CreatedAt, &e.UpdatedAt)
+	switch {
+	case err == nil:
+		trace.Escrow = &e
+	case err != pgx.ErrNoRows:
+		return nil, err
+	}
+	return trace, nil
+}
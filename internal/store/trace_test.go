@@ -0,0 +1,51 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestTraceTransfer(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+
+	resp, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 250}, "trace-key", "hash")
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	for _, ref := range []string{resp.Transfer.PublicID, "1"} {
+		trace, err := s.TraceTransfer(ctx, ref)
+		if err != nil {
+			t.Fatalf("trace %s: %v", ref, err)
+		}
+		if trace.Transfer.ID != resp.Transfer.ID {
+			t.Errorf("trace %s: transfer %d, want %d", ref, trace.Transfer.ID, resp.Transfer.ID)
+		}
+		if len(trace.Entries) != 2 {
+			t.Errorf("trace %s: %d entries, want 2", ref, len(trace.Entries))
+		}
+		if trace.IdempotencyKey == nil || trace.IdempotencyKey.Key != "trace-key" {
+			t.Errorf("trace %s: idempotency key = %+v, want trace-key", ref, trace.IdempotencyKey)
+		}
+		if trace.Escrow != nil {
+			t.Errorf("trace %s: unexpected escrow %+v", ref, trace.Escrow)
+		}
+	}
+}
+
+// An unknown reference is a 404, not a nil dereference.
+func TestTraceTransferNotFound(t *testing.T) {
+	s := NewTestStore(t)
+	for _, ref := range []string{"999", "5f0c6f0e-8d9b-4c1e-9a57-3c2b1d0e4f6a", "not-a-ref"} {
+		if _, err := s.TraceTransfer(context.Background(), ref); !errors.Is(err, ErrTransferNotFound) {
+			t.Errorf("trace %s: err = %v, want ErrTransferNotFound", ref, err)
+		}
+	}
+}
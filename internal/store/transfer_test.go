@@ -0,0 +1,109 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestTransferCreatedAtStableAcrossReplays(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 100), SeedAccount(t, s, 0)
+	req := domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}
+
+	first, err := s.ExecTransfer(ctx, req, "created-at", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Transfer.CreatedAt.IsZero() {
+		t.Fatal("created_at is zero")
+	}
+	if d := time.Since(first.Transfer.CreatedAt); d < 0 || d > time.Minute {
+		t.Errorf("created_at %s is not now", first.Transfer.CreatedAt)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	replay, err := s.ExecTransfer(ctx, req, "created-at", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replay.Replayed || !replay.Transfer.CreatedAt.Equal(first.Transfer.CreatedAt) {
+		t.Errorf("replay created_at = %s (replayed %v), want %s", replay.Transfer.CreatedAt, replay.Replayed, first.Transfer.CreatedAt)
+	}
+}
+
+func TestGetTransfersMixedIDs(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+	var ids []int64
+	for i, amount := range []int64{100, 200, 300} {
+		resp, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: amount}, fmt.Sprintf("batch-%d", i), "h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, resp.Transfer.ID)
+	}
+
+	// Request order, a duplicate, and two IDs that never existed.
+	batch, err := s.GetTransfers(ctx, []int64{ids[2], 9999, ids[0], ids[2], -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, tr := range batch.Transfers {
+		got = append(got, tr.Transfer.ID)
+		want, err := s.GetTransfer(ctx, fmt.Sprint(tr.Transfer.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.Transfer.Amount != want.Transfer.Amount || len(tr.Entries) != 2 {
+			t.Errorf("transfer %d: amount %d with %d entries, want %d with 2", tr.Transfer.ID, tr.Transfer.Amount, len(tr.Entries), want.Transfer.Amount)
+		}
+		for i, e := range tr.Entries {
+			w := want.Entries[i]
+			if e.ID != w.ID || e.AccountID != w.AccountID || e.Delta != w.Delta || e.BalanceAfter != w.BalanceAfter {
+				t.Errorf("transfer %d entry %d = %+v, want %+v", tr.Transfer.ID, i, e, w)
+			}
+		}
+	}
+	if !slices.Equal(got, []int64{ids[2], ids[0]}) {
+		t.Errorf("found %v, want %v", got, []int64{ids[2], ids[0]})
+	}
+	if !slices.Equal(batch.NotFound, []int64{9999, -1}) {
+		t.Errorf("not_found %v, want [9999 -1]", batch.NotFound)
+	}
+
+	none, err := s.GetTransfers(ctx, []int64{12345})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none.Transfers) != 0 || none.Transfers == nil || !slices.Equal(none.NotFound, []int64{12345}) {
+		t.Errorf("all missing: %+v", none)
+	}
+}
+
+// A self-transfer is refused before anything is reserved or locked, so the
+// key stays free for the client's corrected request.
+func TestSelfTransferReservesNothing(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	a, b := SeedAccount(t, s, 1000), SeedAccount(t, s, 0)
+
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: a, Amount: 10}, "self", "h1"); !errors.Is(err, ErrSelfTransfer) {
+		t.Fatalf("err = %v, want ErrSelfTransfer", err)
+	}
+	if _, err := s.ExecTransfer(ctx, domain.TransferRequest{FromAccountID: a, ToAccountID: b, Amount: 10}, "self", "h2"); err != nil {
+		t.Fatalf("corrected request with the same key: %v", err)
+	}
+	AssertBalance(t, s, a, 990)
+	AssertInvariants(t, s)
+}
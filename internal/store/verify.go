@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// Each statement reads accounts and entries from a single snapshot, so an
+// in-flight transfer can't make a healthy account look drifted.
+const verifySelect = `
+	SELECT a.id, a.balance,
+		a.initial_balance + COALESCE((SELECT SUM(delta) FROM ledger_entries e WHERE e.account_id = a.id), 0)
+	FROM accounts a`
+
+// VerifyAccount checks one account's balance against its ledger entries.
+// The account is read under a shared lock, so a transfer in flight on it
+// finishes first and the reported balance is settled; transfers on the
+// account are rejected as contended for the few milliseconds this takes.
+func (s *LedgerStore) VerifyAccount(ctx context.Context, id int64) (*domain.AccountVerification, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, tx)
+
+	accs, err := readAccounts(ctx, tx, readShared, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(accs) == 0 {
+		return nil, ErrAccountNotFound
+	}
+	v, err := scanVerification(tx.QueryRow(ctx, verifySelect+" WHERE a.id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	return v, tx.Commit(ctx)
+}
+
+// VerifyAccounts checks up to limit accounts with IDs greater than afterID,
+// in ID order, so callers can walk the table in bounded chunks.
+func (s *LedgerStore) VerifyAccounts(ctx context.Context, afterID int64, limit int) ([]domain.AccountVerification, error) {
+	rows, err := s.db.Query(ctx, verifySelect+" WHERE a.id > $1 ORDER BY a.id LIMIT $2", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.AccountVerification
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+func scanVerification(row pgx.Row) (*domain.AccountVerification, error) {
+	var v domain.AccountVerification
+	if err := row.Scan(&v.AccountID, &v.StoredBalance, &v.ComputedBalance); err != nil {
+		return nil, err
+	}
+	v.Drift = v.StoredBalance - v.ComputedBalance
+	v.Consistent = v.Drift == 0
+	return &v, nil
+}
+
+// ReconcileDiff lists up to limit accounts with IDs greater than afterID
+// whose stored balance disagrees with their entries, without changing
+// anything. Healthy accounts are filtered out in the query, so a page only
+// comes back short at the end of the table.
+func (s *LedgerStore) ReconcileDiff(ctx context.Context, afterID int64, limit int) (*domain.ReconcileDiff, error) {
+	// Fetch one extra row to learn whether another page exists.
+	rows, err := s.db.Query(ctx, `
+		SELECT a.id, a.balance, a.initial_balance + COALESCE(e.total, 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT SUM(delta) AS total FROM ledger_entries WHERE account_id = a.id
+		) e ON true
+		WHERE a.id > $1 AND a.balance <> a.initial_balance + COALESCE(e.total, 0)
+		ORDER BY a.id
+		LIMIT $2`, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diff := &domain.ReconcileDiff{Accounts: []domain.AccountVerification{}}
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, err
+		}
+		diff.Accounts = append(diff.Accounts, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(diff.Accounts) > limit {
+		diff.Accounts = diff.Accounts[:limit]
+		diff.NextAfter = diff.Accounts[limit-1].AccountID
+	}
+	return diff, nil
+}
+
+// RecomputeBalances rewrites the stored balance of up to limit accounts
+// with IDs greater than afterID from their ledger entries, in ID order.
+// NextAfter is the last ID visited, or 0 once the table is exhausted. Each
+// account is fixed in its own short transaction, so live transfers contend
+// with at most one locked row at a time.
+func (s *LedgerStore) RecomputeBalances(ctx context.Context, afterID int64, limit int) (*domain.RecomputeResult, error) {
+	rows, err := s.db.Query(ctx, "SELECT id FROM accounts WHERE id > $1 ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	res := &domain.RecomputeResult{}
+	for _, id := range ids {
+		fixed, err := s.recomputeBalance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		res.Processed++
+		if fixed {
+			res.Corrected++
+		}
+		res.NextAfter = id
+	}
+	return res, nil
+}
+
+// recomputeBalance sets one account's balance to initial_balance plus its
+// entries and reports whether that changed it. Entries are written under
+// the account's row lock, so once we hold it the sum is settled.
+func (s *LedgerStore) recomputeBalance(ctx context.Context, id int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return false, err
+	}
+	defer rollback(ctx, tx)
+
+	var stored, initial int64
+	if err := tx.QueryRow(ctx, "SELECT balance, initial_balance FROM accounts WHERE id = $1 FOR UPDATE", id).Scan(&stored, &initial); err != nil {
+		return false, err
+	}
+	var sum int64
+	if err := tx.QueryRow(ctx, "SELECT COALESCE(SUM(delta), 0) FROM ledger_entries WHERE account_id = $1", id).Scan(&sum); err != nil {
+		return false, err
+	}
+	if stored == initial+sum {
+		return false, tx.Commit(ctx)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE accounts SET balance = $1, balance_version = balance_version + 1 WHERE id = $2", initial+sum, id); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	s.invalidateAccounts(id)
+	log.Printf("Recomputed account %d balance: %d -> %d", id, stored, initial+sum)
+	return true, nil
+}
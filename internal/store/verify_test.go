@@ -0,0 +1,131 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// seedDrift creates five accounts with some history, then corrupts the
+// stored balance of the second (+7) and fourth (-3) behind the ledger's back.
+func seedDrift(t *testing.T, s *LedgerStore) []int64 {
+	t.Helper()
+	ctx := context.Background()
+	ids := make([]int64, 5)
+	for i := range ids {
+		ids[i] = SeedAccount(t, s, 1000)
+	}
+	for i, id := range ids[:4] {
+		req := domain.TransferRequest{FromAccountID: id, ToAccountID: ids[i+1], Amount: int64(10 * (i + 1))}
+		if _, err := s.ExecTransfer(ctx, req, fmt.Sprintf("drift-%d", i), "h"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, by := range map[int64]int64{ids[1]: 7, ids[3]: -3} {
+		if _, err := s.db.Exec(ctx, "UPDATE accounts SET balance = balance + $2 WHERE id = $1", id, by); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func TestReconcileDiffListsOnlyDrifted(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	ids := seedDrift(t, s)
+
+	diff, err := s.ReconcileDiff(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.AccountVerification{
+		// Account 2 received 10 and sent 20; account 4 received 30 and sent 40.
+		{AccountID: ids[1], StoredBalance: 997, ComputedBalance: 990, Drift: 7},
+		{AccountID: ids[3], StoredBalance: 987, ComputedBalance: 990, Drift: -3},
+	}
+	if len(diff.Accounts) != len(want) || diff.NextAfter != 0 {
+		t.Fatalf("diff %+v, want exactly %+v", diff, want)
+	}
+	for i, w := range want {
+		if diff.Accounts[i] != w {
+			t.Errorf("diff[%d] = %+v, want %+v", i, diff.Accounts[i], w)
+		}
+	}
+
+	// One drifted account per page: healthy accounts between them never
+	// produce an empty page.
+	first, err := s.ReconcileDiff(ctx, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Accounts) != 1 || first.Accounts[0].AccountID != ids[1] || first.NextAfter != ids[1] {
+		t.Fatalf("first page %+v, want account %d then a cursor", first, ids[1])
+	}
+	second, err := s.ReconcileDiff(ctx, first.NextAfter, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Accounts) != 1 || second.Accounts[0].AccountID != ids[3] || second.NextAfter != 0 {
+		t.Fatalf("second page %+v, want only account %d", second, ids[3])
+	}
+
+	// The diff is a dry run.
+	AssertBalance(t, s, ids[1], 997)
+	AssertBalance(t, s, ids[3], 987)
+}
+
+// A chunked walk over many accounts fixes every corrupted one, resuming
+// from each chunk's cursor, and a second walk finds nothing left to fix.
+func TestRecomputeBalancesFixesAll(t *testing.T) {
+	s := NewTestStore(t)
+	ctx := context.Background()
+	ids := seedDrift(t, s)
+	for i := 0; i < 45; i++ {
+		ids = append(ids, SeedAccount(t, s, int64(100*i)))
+	}
+	corrupt := []int64{ids[7], ids[20], ids[21], ids[49]}
+	for _, id := range corrupt {
+		if _, err := s.db.Exec(ctx, "UPDATE accounts SET balance = balance + 11 WHERE id = $1", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	walk := func() (processed, corrected int64) {
+		var after int64
+		for chunks := 0; ; chunks++ {
+			if chunks > len(ids) {
+				t.Fatal("walk did not terminate")
+			}
+			res, err := s.RecomputeBalances(ctx, after, 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Processed > 7 {
+				t.Fatalf("chunk processed %d accounts, limit 7", res.Processed)
+			}
+			processed += res.Processed
+			corrected += res.Corrected
+			if res.NextAfter == 0 {
+				return processed, corrected
+			}
+			after = res.NextAfter
+		}
+	}
+
+	processed, corrected := walk()
+	if processed != int64(len(ids)) || corrected != int64(2+len(corrupt)) {
+		t.Errorf("processed %d, corrected %d; want %d and %d", processed, corrected, len(ids), 2+len(corrupt))
+	}
+	AssertInvariants(t, s)
+	AssertBalance(t, s, ids[1], 990)
+	AssertBalance(t, s, ids[3], 990)
+	AssertBalance(t, s, ids[7], 200)
+
+	if processed, corrected := walk(); processed != int64(len(ids)) || corrected != 0 {
+		t.Errorf("second walk processed %d, corrected %d; want %d and 0", processed, corrected, len(ids))
+	}
+}
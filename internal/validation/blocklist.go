@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// ErrBlockedPair is returned for transfers between a blocked pair of accounts.
+var ErrBlockedPair = &Error{Code: "BLOCKED_PAIR", Message: "Transfers between these accounts are blocked"}
+
+// PairLoader fetches the full set of blocked pairs from the source of truth.
+type PairLoader func(ctx context.Context) ([]domain.BlockedPair, error)
+
+// PairBlocklist caches blocked account pairs in memory so the check costs a
+// single map lookup per transfer. The cache is swapped wholesale on reload.
+type PairBlocklist struct {
+	load          PairLoader
+	bidirectional bool
+
+	mu    sync.RWMutex
+	pairs map[[2]int64]struct{}
+}
+
+// NewPairBlocklist returns an empty blocklist; call Reload to populate it.
+// When bidirectional is set, a pair blocks transfers in both directions.
+func NewPairBlocklist(load PairLoader, bidirectional bool) *PairBlocklist {
+	return &PairBlocklist{load: load, bidirectional: bidirectional, pairs: map[[2]int64]struct{}{}}
+}
+
+// Reload replaces the cached pairs and returns how many rules were loaded.
+func (b *PairBlocklist) Reload(ctx context.Context) (int, error) {
+	rules, err := b.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pairs := make(map[[2]int64]struct{}, len(rules)*2)
+	for _, p := range rules {
+		pairs[[2]int64{p.FromAccountID, p.ToAccountID}] = struct{}{}
+		if b.bidirectional {
+			pairs[[2]int64{p.ToAccountID, p.FromAccountID}] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	b.pairs = pairs
+	b.mu.Unlock()
+	return len(rules), nil
+}
+
+// Run reloads the blocklist every interval until ctx is canceled. A failed
+// refresh keeps serving the previous set.
+func (b *PairBlocklist) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.Reload(ctx); err != nil {
+				log.Printf("blocklist refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+func (b *PairBlocklist) Validate(_ context.Context, req domain.TransferRequest) error {
+	b.mu.RLock()
+	_, blocked := b.pairs[[2]int64{req.FromAccountID, req.ToAccountID}]
+	b.mu.RUnlock()
+	if blocked {
+		return ErrBlockedPair
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func TestPairBlocklistDirection(t *testing.T) {
+	rules := []domain.BlockedPair{{FromAccountID: 1, ToAccountID: 2}}
+	load := func(context.Context) ([]domain.BlockedPair, error) { return rules, nil }
+	cases := []struct {
+		bidirectional bool
+		from, to      int64
+		blocked       bool
+	}{
+		{false, 1, 2, true},
+		{false, 2, 1, false},
+		{true, 1, 2, true},
+		{true, 2, 1, true},
+		{true, 1, 3, false},
+	}
+	for _, tc := range cases {
+		b := NewPairBlocklist(load, tc.bidirectional)
+		if _, err := b.Reload(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		err := b.Validate(context.Background(), domain.TransferRequest{FromAccountID: tc.from, ToAccountID: tc.to, Amount: 1})
+		if got := err == ErrBlockedPair; got != tc.blocked {
+			t.Errorf("bidirectional=%v %d -> %d: err = %v, want blocked %v", tc.bidirectional, tc.from, tc.to, err, tc.blocked)
+		}
+	}
+}
+
+func TestPairBlocklistReload(t *testing.T) {
+	rules := []domain.BlockedPair{{FromAccountID: 1, ToAccountID: 2}}
+	fail := false
+	b := NewPairBlocklist(func(context.Context) ([]domain.BlockedPair, error) {
+		if fail {
+			return nil, errors.New("db down")
+		}
+		return rules, nil
+	}, false)
+	req := domain.TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 1}
+
+	if err := b.Validate(context.Background(), req); err != nil {
+		t.Errorf("blocked before the first reload: %v", err)
+	}
+	if n, err := b.Reload(context.Background()); err != nil || n != 1 {
+		t.Fatalf("reload = %d, %v", n, err)
+	}
+	if b.Validate(context.Background(), req) != ErrBlockedPair {
+		t.Error("pair not blocked after reload")
+	}
+
+	// A failed refresh keeps the previous set.
+	fail = true
+	if _, err := b.Reload(context.Background()); err == nil {
+		t.Fatal("reload succeeded with a failing loader")
+	}
+	if b.Validate(context.Background(), req) != ErrBlockedPair {
+		t.Error("failed reload dropped the cached pairs")
+	}
+
+	// A successful one replaces it wholesale.
+	fail, rules = false, nil
+	b.Reload(context.Background())
+	if err := b.Validate(context.Background(), req); err != nil {
+		t.Errorf("unblocked pair still rejected: %v", err)
+	}
+}
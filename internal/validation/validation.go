@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+// TransferValidator is a pre-execution hook for risk/fraud rules. Validators
+// run after the handler's own request checks and before the store is
+// touched, so a rejection never mutates the ledger. A sweep arrives with
+// Amount 0; the store re-runs amount-sensitive validators once it resolves
+// the amount under the account lock.
+type TransferValidator interface {
+	Validate(ctx context.Context, req domain.TransferRequest) error
+}
+
+// Error is a rule rejection. Code is stable and surfaced to clients;
+// Message is for humans.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// MaxAmount rejects any single transfer above Limit.
+type MaxAmount struct {
+	Limit int64
+}
+
+func (v MaxAmount) Validate(_ context.Context, req domain.TransferRequest) error {
+	if req.Amount > v.Limit {
+		return &Error{Code: "AMOUNT_LIMIT_EXCEEDED", Message: fmt.Sprintf("Amount exceeds the per-transfer limit of %d", v.Limit)}
+	}
+	return nil
+}
+
+// AccountBlocklist rejects transfers where either side is a blocked account.
+type AccountBlocklist struct {
+	ids map[int64]struct{}
+}
+
+func NewAccountBlocklist(ids []int64) *AccountBlocklist {
+	m := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		m[id] = struct{}{}
+	}
+	return &AccountBlocklist{ids: m}
+}
+
+func (v *AccountBlocklist) Validate(_ context.Context, req domain.TransferRequest) error {
+	for _, id := range []int64{req.FromAccountID, req.ToAccountID} {
+		if _, blocked := v.ids[id]; blocked {
+			return &Error{Code: "ACCOUNT_BLOCKED", Message: fmt.Sprintf("Account %d is blocked", id)}
+		}
+	}
+	return nil
+}
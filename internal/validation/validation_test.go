@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/punchamoorthee/ledgerops/internal/domain"
+)
+
+func code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+func TestMaxAmount(t *testing.T) {
+	v := MaxAmount{Limit: 100}
+	if err := v.Validate(context.Background(), domain.TransferRequest{Amount: 100}); err != nil {
+		t.Errorf("at the limit: %v", err)
+	}
+	if err := v.Validate(context.Background(), domain.TransferRequest{Amount: 101}); code(err) != "AMOUNT_LIMIT_EXCEEDED" {
+		t.Errorf("over the limit: %v", err)
+	}
+}
+
+func TestAccountBlocklist(t *testing.T) {
+	v := NewAccountBlocklist([]int64{7})
+	cases := []struct {
+		from, to int64
+		want     string
+	}{
+		{7, 1, "ACCOUNT_BLOCKED"},
+		{1, 7, "ACCOUNT_BLOCKED"},
+		{1, 2, ""},
+	}
+	for _, tc := range cases {
+		err := v.Validate(context.Background(), domain.TransferRequest{FromAccountID: tc.from, ToAccountID: tc.to, Amount: 1})
+		if code(err) != tc.want {
+			t.Errorf("%d -> %d: err = %v, want code %q", tc.from, tc.to, err, tc.want)
+		}
+	}
+}
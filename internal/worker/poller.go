@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Poller is a generic batch worker: it periodically fetches up to BatchSize
+// items and fans them out to Concurrency goroutines running Process.
+//
+// Fetch only proposes candidates. Process must claim each item itself
+// (typically SELECT ... FOR UPDATE SKIP LOCKED in its own transaction), so
+// several pollers, or several instances, can share one queue safely.
+type Poller[T any] struct {
+	Name        string
+	Fetch       func(ctx context.Context, limit int) ([]T, error)
+	Process     func(ctx context.Context, item T) error
+	Concurrency int
+	BatchSize   int
+
+	// Interval is the delay between polls while there is work. When a poll
+	// comes back empty the delay doubles, up to MaxInterval, and resets as
+	// soon as work appears. MaxInterval <= Interval disables the backoff.
+	Interval    time.Duration
+	MaxInterval time.Duration
+
+	// Drain re-polls immediately after a full batch instead of waiting.
+	Drain bool
+}
+
+// Run polls until ctx is canceled. On cancellation it stops handing out
+// items but lets those already in flight finish, then returns, so callers
+// can wait on it during graceful shutdown.
+func (p *Poller[T]) Run(ctx context.Context) {
+	delay := p.Interval
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n := p.poll(ctx)
+		switch {
+		case n == 0 && p.MaxInterval > p.Interval:
+			delay = min(delay*2, p.MaxInterval)
+		case n == p.BatchSize && p.Drain:
+			delay = 0
+		default:
+			delay = p.Interval
+		}
+		timer.Reset(delay)
+	}
+}
+
+// poll runs one batch and returns how many items it fetched.
+func (p *Poller[T]) poll(ctx context.Context) int {
+	items, err := p.Fetch(ctx, p.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("%s: fetch failed: %v", p.Name, err)
+		}
+		return 0
+	}
+
+	// In-flight items run to completion even if shutdown begins mid-batch.
+	processCtx := context.WithoutCancel(ctx)
+
+	work := make(chan T)
+	var wg sync.WaitGroup
+	for i := 0; i < max(p.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				if err := p.Process(processCtx, item); err != nil {
+					log.Printf("%s: %v", p.Name, err)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case <-ctx.Done():
+			break feed
+		case work <- item:
+		}
+	}
+	close(work)
+	wg.Wait()
+	return len(items)
+}
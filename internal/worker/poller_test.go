@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// queue hands out ints from a fixed backlog, BatchSize at a time.
+type queue struct {
+	mu      sync.Mutex
+	pending []int
+	fetches []time.Time
+}
+
+func (q *queue) fetch(_ context.Context, limit int) ([]int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.fetches = append(q.fetches, time.Now())
+	n := min(limit, len(q.pending))
+	batch := q.pending[:n]
+	q.pending = q.pending[n:]
+	return batch, nil
+}
+
+func (q *queue) fetchTimes() []time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]time.Time(nil), q.fetches...)
+}
+
+func TestPollerProcessesEveryItem(t *testing.T) {
+	q := &queue{}
+	for i := range 25 {
+		q.pending = append(q.pending, i)
+	}
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	var running, peak atomic.Int32
+	done := make(chan struct{})
+	p := &Poller[int]{
+		Name:  "test",
+		Fetch: q.fetch,
+		Process: func(_ context.Context, item int) error {
+			if n := running.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+			defer running.Add(-1)
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			seen[item]++
+			if len(seen) == 25 {
+				close(done)
+			}
+			return nil
+		},
+		Concurrency: 3,
+		BatchSize:   10,
+		Interval:    5 * time.Millisecond,
+		Drain:       true,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backlog not drained")
+	}
+	cancel()
+	<-stopped
+
+	for item, n := range seen {
+		if n != 1 {
+			t.Errorf("item %d processed %d times", item, n)
+		}
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("%d items ran at once, Concurrency is 3", got)
+	}
+}
+
+func TestPollerBacksOffWhenIdle(t *testing.T) {
+	q := &queue{}
+	p := &Poller[int]{
+		Name:        "test",
+		Fetch:       q.fetch,
+		Process:     func(context.Context, int) error { return nil },
+		BatchSize:   10,
+		Interval:    5 * time.Millisecond,
+		MaxInterval: 40 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	// Without backoff an idle 250ms would see ~50 polls; doubling from 5ms
+	// to a 40ms cap allows only a handful.
+	fetches := q.fetchTimes()
+	if len(fetches) < 3 || len(fetches) > 12 {
+		t.Fatalf("%d polls in 250ms", len(fetches))
+	}
+	first := fetches[1].Sub(fetches[0])
+	last := fetches[len(fetches)-1].Sub(fetches[len(fetches)-2])
+	if last < 2*first || last < 30*time.Millisecond {
+		t.Errorf("gap grew from %s to %s, want it to double toward 40ms", first, last)
+	}
+}
+
+// Cancelling mid-batch lets the items already taken finish with a live
+// context, and Run waits for them before returning.
+func TestPollerShutdownMidBatch(t *testing.T) {
+	q := &queue{pending: []int{1, 2, 3, 4, 5}}
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var finished, processCtxErr atomic.Int32
+	p := &Poller[int]{
+		Name:  "test",
+		Fetch: q.fetch,
+		Process: func(ctx context.Context, item int) error {
+			if item == 1 {
+				close(started)
+				<-unblock
+			}
+			if ctx.Err() != nil {
+				processCtxErr.Add(1)
+			}
+			finished.Add(1)
+			return nil
+		},
+		Concurrency: 1,
+		BatchSize:   5,
+		Interval:    time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(stopped)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-stopped:
+		t.Fatal("Run returned with an item still in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(unblock)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the in-flight item finished")
+	}
+
+	// Once the worker frees up, each further hand-off races the already
+	// closed ctx.Done, so only the in-flight item is guaranteed to finish.
+	if finished.Load() < 1 {
+		t.Error("the in-flight item did not finish")
+	}
+	if processCtxErr.Load() != 0 {
+		t.Error("Process saw a cancelled context")
+	}
+}